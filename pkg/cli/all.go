@@ -26,6 +26,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/router"
 )
 
@@ -68,6 +69,9 @@ var allStatusCmd = &cobra.Command{
 		// retrieve status
 		stats, err := containerHandler.AllStatus()
 		handleError(err)
+		for i := range stats {
+			project.CheckHealth(containerHandler, &stats[i])
+		}
 		// json out
 		if checkFlag(cmd, "json") {
 			out, err := json.Marshal(stats)
@@ -102,12 +106,13 @@ var allStatusCmd = &cobra.Command{
 				fmt.Sprintf("[%s] %s", string(s.ObjectType), s.Name),
 				serviceType,
 				s.State,
+				healthString(s),
 				slot,
 				ipAddrStr,
 			})
 		}
 		drawTable(
-			[]string{"Project ID", "Name", "Type", "State", "Slot", "IP Address"},
+			[]string{"Project ID", "Name", "Type", "State", "Health", "Slot", "IP Address"},
 			data,
 		)
 		drawKeys()
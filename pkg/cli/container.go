@@ -30,6 +30,7 @@ import (
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
 )
 
 var containerCmd = &cobra.Command{
@@ -159,20 +160,15 @@ var containerAppDeleteCommitCmd = &cobra.Command{
 }
 
 var containerLogsCmd = &cobra.Command{
-	Use:   "logs [-f follow]",
+	Use:   "logs [-f follow] [--since 10m] [--grep pattern] [--file app|php|access|error|deploy|cron] [--json]",
 	Short: "Display logs for container.",
 	Run: func(cmd *cobra.Command, args []string) {
 		output.Enable = false
 		proj, err := getProject(true)
 		handleError(err)
-		followFlag := cmd.Flags().Lookup("follow")
-		hasFollow := followFlag != nil && followFlag.Value.String() != "false"
 		d, err := getDefFromCommand(containerCmd, proj)
 		handleError(err)
-		handleError(proj.NewContainer(d).LogStdout(hasFollow))
-		if hasFollow {
-			select {}
-		}
+		streamLogs(cmd, []project.Container{proj.NewContainer(d)})
 	},
 }
 
@@ -257,6 +253,32 @@ var containerCopyCmd = &cobra.Command{
 	},
 }
 
+var containerXdebugCmd = &cobra.Command{
+	Use:   "xdebug on|off",
+	Short: "Toggle xdebug in application container.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+			handleError(fmt.Errorf("expected 'on' or 'off'"))
+		}
+		proj, err := getProject(true)
+		handleError(err)
+		d, err := getDefFromCommand(containerCmd, proj)
+		handleError(err)
+		switch d := d.(type) {
+		case def.App:
+			{
+				if args[0] == "on" {
+					handleError(proj.XdebugEnable(d))
+					return
+				}
+				handleError(proj.XdebugDisable(d))
+				return
+			}
+		}
+		handleError(fmt.Errorf("can only toggle xdebug on applications"))
+	},
+}
+
 var containerExportCmd = &cobra.Command{
 	Use:   "export path",
 	Short: "Export /mnt directory.",
@@ -315,7 +337,7 @@ var containerImportCmd = &cobra.Command{
 
 func init() {
 	containerShellCmd.PersistentFlags().Bool("root", false, "shell as root")
-	containerLogsCmd.Flags().BoolP("follow", "f", false, "follow logs")
+	addLogFlags(containerLogsCmd)
 	containerCmd.PersistentFlags().StringP("service", "s", "", "name of service/application/worker")
 	containerCmd.AddCommand(containerAppDeployCmd)
 	containerCmd.AddCommand(containerAppPostDeployCmd)
@@ -324,6 +346,7 @@ func init() {
 	containerCmd.AddCommand(containerAppDeleteCommitCmd)
 	containerCmd.AddCommand(containerLogsCmd)
 	containerCmd.AddCommand(containerCopyCmd)
+	containerCmd.AddCommand(containerXdebugCmd)
 	containerCmd.AddCommand(containerExportCmd)
 	containerCmd.AddCommand(containerImportCmd)
 	RootCmd.AddCommand(containerCmd)
@@ -18,18 +18,33 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
 )
 
 var databaseCmd = &cobra.Command{
 	Use:     "db [-s service] [-d database]",
-	Aliases: []string{"database", "mysql", "mariadb"},
+	Aliases: []string{"database", "mysql", "mariadb", "postgresql", "redis", "mongodb", "elasticsearch", "opensearch"},
 	Short:   "Manage database.",
 }
 
+// getDatabase returns the database from the command flag or the first database of the service.
+func getDatabase(proj *project.Project, service def.Service) string {
+	database := databaseCmd.PersistentFlags().Lookup("database").Value.String()
+	if database == "" {
+		if databases := proj.GetDatabases(service); len(databases) > 0 {
+			database = databases[0]
+		}
+	}
+	return database
+}
+
 var databaseDumpCmd = &cobra.Command{
 	Use:   "dump",
 	Short: "Make a database dump.",
@@ -38,10 +53,11 @@ var databaseDumpCmd = &cobra.Command{
 		handleError(err)
 		service, err := getService(databaseCmd, proj, project.GetDatabaseTypeNames())
 		handleError(err)
-		database := databaseCmd.PersistentFlags().Lookup("database").Value.String()
+		database := getDatabase(proj, service)
 		if database == "" {
 			handleError(fmt.Errorf("must provide a database to dump"))
 		}
+		project.WarnDatabaseEphemeral(service)
 		c := proj.NewContainer(service)
 		_, err = c.Shell(
 			"root",
@@ -70,10 +86,154 @@ var databaseSQLCmd = &cobra.Command{
 	},
 }
 
+var databaseImportCmd = &cobra.Command{
+	Use:   "import [--drop] file",
+	Short: "Import a database dump (sql, pg custom, redis rdb, mongodb archive or elasticsearch ndjson, optionally gzip or bzip2 compressed).",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			handleError(fmt.Errorf("must provide a file to import"))
+		}
+		proj, err := getProject(true)
+		handleError(err)
+		service, err := getService(databaseCmd, proj, project.GetDatabaseTypeNames())
+		handleError(err)
+		database := getDatabase(proj, service)
+		if database == "" {
+			handleError(fmt.Errorf("must provide a database to import in to"))
+		}
+		handleError(proj.DatabaseImport(service, database, args[0], checkFlag(cmd, "drop")))
+	},
+}
+
+var databaseSnapshotCmd = &cobra.Command{
+	Use:     "snapshot",
+	Aliases: []string{"snapshots", "snap"},
+	Short:   "Manage named database snapshots.",
+}
+
+var databaseSnapshotCreateCmd = &cobra.Command{
+	Use:   "create name",
+	Short: "Create a snapshot of all databases in a service.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			handleError(fmt.Errorf("must provide a snapshot name"))
+		}
+		proj, err := getProject(true)
+		handleError(err)
+		service, err := getService(databaseCmd, proj, project.GetDatabaseTypeNames())
+		handleError(err)
+		_, err = proj.DatabaseSnapshotCreate(service, args[0])
+		handleError(err)
+	},
+}
+
+var databaseSnapshotListCmd = &cobra.Command{
+	Use:   "list [--json]",
+	Short: "List database snapshots.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		snapshots, err := proj.DatabaseSnapshotList(
+			databaseCmd.PersistentFlags().Lookup("service").Value.String(),
+		)
+		handleError(err)
+		if checkFlag(cmd, "json") {
+			out, err := json.MarshalIndent(snapshots, "", "  ")
+			handleError(err)
+			output.WriteStdout(string(out) + "\n")
+			return
+		}
+		data := make([][]string, 0)
+		for _, s := range snapshots {
+			data = append(data, []string{
+				s.Name, s.Service, s.Type, fmt.Sprintf("%.2f MB", float64(s.Size)/1024/1024), s.Created.Format(time.RFC3339),
+			})
+		}
+		drawTable([]string{"Name", "Service", "Type", "Size", "Created"}, data)
+	},
+}
+
+var databaseSnapshotRestoreCmd = &cobra.Command{
+	Use:   "restore name",
+	Short: "Restore a database snapshot, the service type and version must match the snapshot.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			handleError(fmt.Errorf("must provide a snapshot name"))
+		}
+		proj, err := getProject(true)
+		handleError(err)
+		service, err := getService(databaseCmd, proj, project.GetDatabaseTypeNames())
+		handleError(err)
+		handleError(proj.DatabaseSnapshotRestore(service, args[0]))
+	},
+}
+
+var databaseSnapshotDeleteCmd = &cobra.Command{
+	Use:     "delete name",
+	Aliases: []string{"del", "rm"},
+	Short:   "Delete a database snapshot.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			handleError(fmt.Errorf("must provide a snapshot name"))
+		}
+		proj, err := getProject(true)
+		handleError(err)
+		service, err := getService(databaseCmd, proj, project.GetDatabaseTypeNames())
+		handleError(err)
+		handleError(proj.DatabaseSnapshotDelete(service.Name, args[0]))
+	},
+}
+
+var databaseSanitizeCmd = &cobra.Command{
+	Use:   "sanitize [--status]",
+	Short: "Run the sanitize rules against the databases or show which databases are not sanitized.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		serviceName := databaseCmd.PersistentFlags().Lookup("service").Value.String()
+		if checkFlag(cmd, "status") {
+			data := make([][]string, 0)
+			for _, service := range proj.Services {
+				if proj.GetDatabaseDumpFormat(service) == "" || (serviceName != "" && service.Name != serviceName) {
+					continue
+				}
+				for _, database := range proj.GetDatabases(service) {
+					flagged, err := proj.DatabaseSanitizeFlagged(service, database)
+					handleError(err)
+					status := "yes"
+					if flagged {
+						status = "NO"
+					}
+					data = append(data, []string{service.Name, database, status})
+				}
+			}
+			drawTable([]string{"Service", "Database", "Sanitized"}, data)
+			return
+		}
+		if serviceName == "" {
+			handleError(proj.DatabaseSanitizeAll())
+			return
+		}
+		service, err := getService(databaseCmd, proj, project.GetDatabaseTypeNames())
+		handleError(err)
+		handleError(proj.DatabaseSanitize(service, getDatabase(proj, service)))
+	},
+}
+
 func init() {
+	databaseSnapshotListCmd.Flags().Bool("json", false, "JSON output")
+	databaseSnapshotCmd.AddCommand(databaseSnapshotCreateCmd)
+	databaseSnapshotCmd.AddCommand(databaseSnapshotListCmd)
+	databaseSnapshotCmd.AddCommand(databaseSnapshotRestoreCmd)
+	databaseSnapshotCmd.AddCommand(databaseSnapshotDeleteCmd)
+	databaseImportCmd.Flags().Bool("drop", false, "drop and recreate the database before import")
+	databaseSanitizeCmd.Flags().Bool("status", false, "show which databases are flagged as not sanitized")
 	databaseCmd.PersistentFlags().StringP("database", "d", "", "name of database")
 	databaseCmd.PersistentFlags().StringP("service", "s", "", "name of service")
 	databaseCmd.AddCommand(databaseDumpCmd)
 	databaseCmd.AddCommand(databaseSQLCmd)
+	databaseCmd.AddCommand(databaseImportCmd)
+	databaseCmd.AddCommand(databaseSnapshotCmd)
+	databaseCmd.AddCommand(databaseSanitizeCmd)
 	RootCmd.AddCommand(databaseCmd)
 }
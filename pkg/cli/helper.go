@@ -20,7 +20,7 @@ package cli
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/platformsh"
@@ -125,10 +125,17 @@ func getDefFromCommand(cmd *cobra.Command, proj *project.Project) (interface{},
 }
 
 // getService fetches a service definition.
+// Without a service name the first service matching the filter types, which are in order
+// of preference, is returned. Services of the same type are ordered by name.
 func getService(cmd *cobra.Command, proj *project.Project, filterType []string) (def.Service, error) {
 	name := cmd.PersistentFlags().Lookup("service").Value.String()
-	for _, serv := range proj.Services {
-		for _, t := range filterType {
+	services := make([]def.Service, len(proj.Services))
+	copy(services, proj.Services)
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	for _, t := range filterType {
+		for _, serv := range services {
 			if (serv.Name == name || name == "") && t == serv.GetTypeName() {
 				return serv, nil
 			}
@@ -141,35 +148,9 @@ func getService(cmd *cobra.Command, proj *project.Project, filterType []string)
 }
 
 func getPlatformShEnvironment(cmd *cobra.Command, proj *project.Project) (*platformsh.Environment, error) {
-	if proj.PlatformSH == nil {
-		return nil, errors.WithStack(platformsh.ErrProjectNotFound)
-	}
-	if len(proj.PlatformSH.Environments) == 0 {
-		if err := proj.PlatformSH.FetchEnvironments(); err != nil {
-			return nil, errors.WithStack(err)
-		}
-	}
-	if err := proj.PlatformSH.FetchEnvironments(); err != nil {
-		return nil, errors.WithStack(err)
-	}
-	if len(proj.PlatformSH.Environments) == 0 {
-		return nil, errors.WithStack(platformsh.ErrEnvironmentNotFound)
-	}
 	envName := cmd.PersistentFlags().Lookup("environment").Value.String()
-	env := &proj.PlatformSH.Environments[0]
-	for _, e := range proj.PlatformSH.Environments {
-		if e.Name == "master" || e.Name == "main" {
-			env = &e
-			break
-		}
-	}
-	if envName != "" {
-		env = proj.PlatformSH.GetEnvironment(envName)
-	}
-	if env == nil {
-		return nil, errors.WithStack(platformsh.ErrEnvironmentNotFound)
-	}
-	return env, nil
+	env, err := proj.PlatformSHEnvironment(envName)
+	return env, errors.WithStack(err)
 }
 
 // handleError handles an error.
@@ -252,13 +233,27 @@ func drawTable(head []string, data [][]string) {
 func drawKeys() {
 	output.WriteStdout("[a] = application\t\t[s] = service\n")
 	output.WriteStdout("[w] = worker\t\t\t[r] = router\n")
-	output.WriteStdout("[c] = committed\n")
+	output.WriteStdout("[c] = committed\t\t\t[x] = xdebug\n")
+}
+
+// healthString returns the readiness of a container for display.
+func healthString(s container.Status) string {
+	switch {
+	case !s.Running:
+		{
+			return "n/a"
+		}
+	case s.Healthy:
+		{
+			return "ready"
+		}
+	}
+	return "not ready"
 }
 
 // getContainerHandler returns container handler.
 func getContainerHandler() (container.Interface, error) {
-	// TODO configurable
-	return container.NewDocker()
+	return project.NewGlobalContainerHandler()
 }
 
 // checkFlag returns true if given flag is set.
@@ -277,11 +272,14 @@ func projectStart(cmd *cobra.Command, p *project.Project, slot int) {
 		p, err = getProject(true)
 		handleError(err)
 	}
-	// determine volume slot
-	var err error
+	// determine volume slot, use slot persisted with slot switch if not given
 	if slot < 0 {
-		slot, err = strconv.Atoi(cmd.Flags().Lookup("slot").Value.String())
-		handleError(err)
+		slot = p.ActiveSlot()
+		if cmd.Flags().Changed("slot") {
+			var err error
+			slot, err = cmd.Flags().GetInt("slot")
+			handleError(err)
+		}
 	}
 	p.SetSlot(slot)
 	// set no commit
@@ -315,6 +313,7 @@ func projectStart(cmd *cobra.Command, p *project.Project, slot int) {
 	}
 	// start project
 	handleError(p.Start())
+	handleError(p.SlotTouch())
 	// start router
 	if !checkFlag(cmd, "no-router") {
 		handleError(router.Start())
@@ -71,7 +71,7 @@ var platformShSSHCmd = &cobra.Command{
 }
 
 var platformShSyncCmd = &cobra.Command{
-	Use: "sync [-e environment] [--skip-variables] [--skip-mounts] [--skip-databases]",
+	Use: "sync [-e environment] [--skip-variables] [--skip-mounts] [--skip-databases] [--tables t1,t2] [--exclude-tables t1,t2] [--structure-only t1,t2]",
 	Run: func(cmd *cobra.Command, args []string) {
 		proj, err := getProject(true)
 		handleError(err)
@@ -94,7 +94,17 @@ var platformShSyncCmd = &cobra.Command{
 			handleError(proj.PlatformSHSyncMounts(env.Name))
 		}
 		if !checkFlag(cmd, "skip-databases") {
-			handleError(proj.PlatformSHSyncDatabases(env.Name))
+			tables, err := cmd.Flags().GetStringSlice("tables")
+			handleError(err)
+			excludeTables, err := cmd.Flags().GetStringSlice("exclude-tables")
+			handleError(err)
+			structureOnly, err := cmd.Flags().GetStringSlice("structure-only")
+			handleError(err)
+			handleError(proj.PlatformSHSyncDatabases(env.Name, project.DatabaseDumpOptions{
+				Tables:        tables,
+				ExcludeTables: excludeTables,
+				StructureOnly: structureOnly,
+			}))
 		}
 		if !checkFlag(cmd, "skip-mounts") || !checkFlag(cmd, "skip-databases") {
 			handleError(proj.Stop())
@@ -102,16 +112,55 @@ var platformShSyncCmd = &cobra.Command{
 	},
 }
 
+var platformShPushCmd = &cobra.Command{
+	Use:   "push [-e environment] [--mounts] [--databases] [--force-main]",
+	Short: "Push local mounts and databases to a Platform.sh environment, both are pushed if neither is specified.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		// get psh environment
+		env, err := getPlatformShEnvironment(cmd, proj)
+		handleError(err)
+		if env.IsMain && !checkFlag(cmd, "force-main") {
+			handleError(errors.Wrapf(project.ErrPushMainEnvironment, "environment '%s' is the main environment", env.Name))
+		}
+		output.Info(fmt.Sprintf("Push to %s (%s) environment.", env.Name, env.MachineName))
+		pushMounts := checkFlag(cmd, "mounts")
+		pushDatabases := checkFlag(cmd, "databases")
+		if !pushMounts && !pushDatabases {
+			pushMounts = true
+			pushDatabases = true
+		}
+		// perform push tasks
+		handleError(proj.Start())
+		if pushMounts {
+			handleError(proj.PlatformSHPushMounts(env.Name, checkFlag(cmd, "force-main")))
+		}
+		if pushDatabases {
+			handleError(proj.PlatformSHPushDatabases(env.Name, checkFlag(cmd, "force-main")))
+		}
+		handleError(proj.Stop())
+	},
+}
+
 func init() {
 	platformShSSHCmd.PersistentFlags().StringP("service", "s", "", "name of service/application/worker")
-	platformShSSHCmd.PersistentFlags().StringP("environment", "e", "", "name of environment")
+	platformShSSHCmd.PersistentFlags().StringP("environment", "e", "", "name of environment (defaults to environment mapped to current git branch)")
 	platformShSSHCmd.Flags().Bool("pipe", false, "return ssh url instead of creating interactive terminal")
 	platformShCmd.AddCommand(platformShLoginCmd)
 	platformShCmd.AddCommand(platformShSSHCmd)
-	platformShSyncCmd.PersistentFlags().StringP("environment", "e", "", "name of environment")
+	platformShSyncCmd.PersistentFlags().StringP("environment", "e", "", "name of environment (defaults to environment mapped to current git branch)")
 	platformShSyncCmd.Flags().Bool("skip-variables", false, "Skip variable sync.")
 	platformShSyncCmd.Flags().Bool("skip-mounts", false, "Skip mount sync.")
 	platformShSyncCmd.Flags().Bool("skip-databases", false, "Skip database sync.")
+	platformShSyncCmd.Flags().StringSlice("tables", []string{}, "Only sync these database tables.")
+	platformShSyncCmd.Flags().StringSlice("exclude-tables", []string{}, "Do not sync these database tables.")
+	platformShSyncCmd.Flags().StringSlice("structure-only", []string{}, "Only sync the structure of these database tables, * for all tables.")
 	platformShCmd.AddCommand(platformShSyncCmd)
+	platformShPushCmd.PersistentFlags().StringP("environment", "e", "", "name of environment (defaults to environment mapped to current git branch)")
+	platformShPushCmd.Flags().Bool("mounts", false, "Push mounts.")
+	platformShPushCmd.Flags().Bool("databases", false, "Push databases.")
+	platformShPushCmd.Flags().Bool("force-main", false, "Allow pushing to the main environment.")
+	platformShCmd.AddCommand(platformShPushCmd)
 	RootCmd.AddCommand(platformShCmd)
 }
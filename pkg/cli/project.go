@@ -20,10 +20,13 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/router"
 )
 
@@ -165,13 +168,17 @@ var projectStatusCmd = &cobra.Command{
 			}
 			serviceType := s.Type
 			if s.Committed {
-				serviceType = "[c] " + s.Type
+				serviceType = "[c] " + serviceType
+			}
+			if s.Xdebug {
+				serviceType = "[x] " + serviceType
 			}
 
 			data = append(data, []string{
 				fmt.Sprintf("[%s] %s", string(s.ObjectType), s.Name),
 				serviceType,
 				s.State,
+				healthString(s),
 				ipAddrStr,
 			})
 		}
@@ -180,7 +187,7 @@ var projectStatusCmd = &cobra.Command{
 		output.WriteStdout(fmt.Sprintf("SLOT\t\t%s\n", slot))
 		output.WriteStdout(fmt.Sprintf("ACTIVE\t\t%d/%d\n", activeCount, len(status)))
 		drawTable(
-			[]string{"Name", "Type", "Status", "IP Address"},
+			[]string{"Name", "Type", "Status", "Health", "IP Address"},
 			data,
 		)
 		drawKeys()
@@ -188,32 +195,92 @@ var projectStatusCmd = &cobra.Command{
 	},
 }
 
+// logLabelColors are the terminal colors used to label log lines by container.
+var logLabelColors = []int{36, 32, 33, 35, 34, 96, 92, 93, 95, 94}
+
+// logEntryPrinter returns a callback that outputs log entries labeled by container.
+func logEntryPrinter(cmd *cobra.Command, containers []project.Container) func(project.LogEntry) error {
+	// label each container with its own color
+	labels := make(map[string]string)
+	labelWidth := 0
+	for _, c := range containers {
+		if len(c.Config.GetHumanName()) > labelWidth {
+			labelWidth = len(c.Config.GetHumanName())
+		}
+	}
+	for i, c := range containers {
+		name := c.Config.GetHumanName()
+		labels[name] = output.Color(
+			fmt.Sprintf("%-*s |", labelWidth, name),
+			logLabelColors[i%len(logLabelColors)],
+		)
+	}
+	return func(entry project.LogEntry) error {
+		// json out
+		if checkFlag(cmd, "json") {
+			entryJSON, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			output.WriteStdout(string(entryJSON) + "\n")
+			return nil
+		}
+		// line out
+		output.WriteStdout(fmt.Sprintf(
+			"%s %s %s\n",
+			labels[entry.Source],
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Message,
+		))
+		return nil
+	}
+}
+
+// streamLogs outputs the logs of given containers, or the given log file when the file flag is set.
+func streamLogs(cmd *cobra.Command, containers []project.Container) {
+	opts := project.LogOptions{Follow: checkFlag(cmd, "follow")}
+	if grep := cmd.Flags().Lookup("grep").Value.String(); grep != "" {
+		var err error
+		opts.Grep, err = regexp.Compile(grep)
+		handleError(err)
+	}
+	since := cmd.Flags().Lookup("since").Value.String()
+	if file := cmd.Flags().Lookup("file").Value.String(); file != "" {
+		if since != "" {
+			handleError(fmt.Errorf("--since can not be used with --file"))
+		}
+		handleError(project.StreamLogFile(containers, file, opts, logEntryPrinter(cmd, containers)))
+		return
+	}
+	var err error
+	opts.Since, err = project.ParseLogSince(since)
+	handleError(err)
+	handleError(project.StreamLogs(containers, opts, logEntryPrinter(cmd, containers)))
+}
+
+// addLogFlags adds the log filter flags to given command.
+func addLogFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("follow", "f", false, "follow logs")
+	cmd.Flags().String("since", "", "only show logs since duration (i.e. 10m) or timestamp")
+	cmd.Flags().String("grep", "", "only show lines matching regular expression")
+	cmd.Flags().String("file", "", "show log file instead ("+strings.Join(project.LogFileNames(), ", ")+")")
+	cmd.Flags().Bool("json", false, "JSON output")
+}
+
 var projectLogsCmd = &cobra.Command{
-	Use:   "logs [-f follow]",
+	Use:   "logs [-f follow] [--since 10m] [--grep pattern] [--service name] [--file app] [--json]",
 	Short: "Display logs for all project containers.",
 	Run: func(cmd *cobra.Command, args []string) {
 		output.Enable = false
 		proj, err := getProject(true)
 		handleError(err)
-		followFlag := cmd.Flags().Lookup("follow")
-		hasFollow := followFlag != nil && followFlag.Value.String() != "false"
-		for _, app := range proj.Apps {
-			if hasFollow {
-				go proj.NewContainer(app).LogStdout(hasFollow)
-			} else {
-				handleError(proj.NewContainer(app).LogStdout(hasFollow))
-			}
-		}
-		for _, service := range proj.Services {
-			if hasFollow {
-				go proj.NewContainer(service).LogStdout(hasFollow)
-			} else {
-				handleError(proj.NewContainer(service).LogStdout(hasFollow))
-			}
-		}
-		if hasFollow {
-			select {}
+		services, err := cmd.Flags().GetStringSlice("service")
+		handleError(err)
+		containers := proj.LogContainers(services)
+		if len(containers) == 0 {
+			handleError(fmt.Errorf("no containers match the given services"))
 		}
+		streamLogs(cmd, containers)
 	},
 }
 
@@ -266,7 +333,8 @@ func init() {
 	projectStartCmd.Flags().Bool("no-validate", false, "don't validate the project config files")
 	projectStartCmd.Flags().IntP("slot", "s", 0, "set volume slot")
 	projectStatusCmd.Flags().Bool("json", false, "JSON output")
-	projectLogsCmd.Flags().BoolP("follow", "f", false, "follow logs")
+	addLogFlags(projectLogsCmd)
+	projectLogsCmd.Flags().StringSlice("service", []string{}, "only show logs of given apps, workers or services")
 	projectRestartCmd.Flags().Bool("rebuild", false, "force rebuild of app containers")
 	projectRestartCmd.Flags().Bool("no-build", false, "skip building project")
 	projectRestartCmd.Flags().Bool("no-router", false, "skip adding routes to router")
@@ -0,0 +1,142 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+var projectCronCmd = &cobra.Command{
+	Use:     "cron [--app app]",
+	Aliases: []string{"crons"},
+	Short:   "Manage application cron jobs.",
+}
+
+var projectCronListCmd = &cobra.Command{
+	Use:   "list [--json]",
+	Short: "List cron jobs.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		crons := proj.ListCrons()
+		if checkFlag(cmd, "json") {
+			out, err := json.MarshalIndent(crons, "", "  ")
+			handleError(err)
+			output.WriteStdout(string(out) + "\n")
+			return
+		}
+		data := make([][]string, 0)
+		for _, c := range crons {
+			data = append(data, []string{
+				c.App, c.Name, c.Cron.Spec, c.Cron.Command,
+			})
+		}
+		drawTable([]string{"App", "Name", "Spec", "Command"}, data)
+	},
+}
+
+var projectCronRunCmd = &cobra.Command{
+	Use:   "run name",
+	Short: "Run a cron job now.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			handleError(fmt.Errorf("cron name not provided"))
+		}
+		proj, err := getProject(true)
+		handleError(err)
+		job, err := proj.GetCron(args[0], projectCronCmd.PersistentFlags().Lookup("app").Value.String())
+		handleError(err)
+		run, err := proj.RunCron(job, os.Stdout)
+		handleError(err)
+		os.Exit(run.ExitCode)
+	},
+}
+
+var projectCronHistoryCmd = &cobra.Command{
+	Use:   "history name [--json]",
+	Short: "Show run history of a cron job.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			handleError(fmt.Errorf("cron name not provided"))
+		}
+		proj, err := getProject(true)
+		handleError(err)
+		job, err := proj.GetCron(args[0], projectCronCmd.PersistentFlags().Lookup("app").Value.String())
+		handleError(err)
+		history, err := proj.CronHistory(job)
+		handleError(err)
+		if checkFlag(cmd, "json") {
+			out, err := json.MarshalIndent(history, "", "  ")
+			handleError(err)
+			output.WriteStdout(string(out) + "\n")
+			return
+		}
+		data := make([][]string, 0)
+		for _, run := range history {
+			exitCode := strconv.Itoa(run.ExitCode)
+			if run.Skipped {
+				exitCode = "skipped"
+			}
+			data = append(data, []string{
+				run.Start.Format(time.RFC3339),
+				run.Duration.Round(time.Millisecond).String(),
+				exitCode,
+				run.Error,
+			})
+		}
+		drawTable([]string{"Start", "Duration", "Exit Code", "Error"}, data)
+	},
+}
+
+var projectCronStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Run the cron scheduler in the foreground.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		stop := make(chan struct{})
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sig
+			output.Info("Stop cron scheduler, waiting for running crons.")
+			close(stop)
+		}()
+		handleError(proj.CronScheduler(stop))
+	},
+}
+
+func init() {
+	projectCronListCmd.Flags().Bool("json", false, "JSON output")
+	projectCronHistoryCmd.Flags().Bool("json", false, "JSON output")
+	projectCronCmd.PersistentFlags().StringP("app", "a", "", "name of application")
+	projectCronCmd.AddCommand(projectCronListCmd)
+	projectCronCmd.AddCommand(projectCronRunCmd)
+	projectCronCmd.AddCommand(projectCronHistoryCmd)
+	projectCronCmd.AddCommand(projectCronStartCmd)
+	projectCmd.AddCommand(projectCronCmd)
+}
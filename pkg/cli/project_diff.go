@@ -0,0 +1,74 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+var projectDiffCmd = &cobra.Command{
+	Use:   "diff [--rev A..B] [--json]",
+	Short: "Show definition changes made by pcc override files or between two git revisions.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		var changes []def.DiffChange
+		rev := cmd.Flags().Lookup("rev").Value.String()
+		if rev == "" {
+			changes, err = proj.DiffOverrides()
+		} else {
+			revs := strings.SplitN(rev, "..", 2)
+			if len(revs) == 1 {
+				revs = append(revs, "")
+			}
+			changes, err = proj.DiffRevisions(revs[0], revs[1])
+		}
+		handleError(err)
+		if checkFlag(cmd, "json") {
+			out, err := json.MarshalIndent(changes, "", "  ")
+			handleError(err)
+			output.WriteStdout(string(out) + "\n")
+			return
+		}
+		diffValue := func(v interface{}) string {
+			if v == nil {
+				return ""
+			}
+			out, _ := json.Marshal(v)
+			return string(out)
+		}
+		data := make([][]string, 0)
+		for _, c := range changes {
+			data = append(data, []string{
+				c.Name, c.FullPath(), c.Change, diffValue(c.Old), diffValue(c.New),
+			})
+		}
+		drawTable([]string{"Name", "Path", "Change", "Old", "New"}, data)
+	},
+}
+
+func init() {
+	projectDiffCmd.Flags().String("rev", "", "git revisions to compare (A..B), compare revision with working tree if only A is given")
+	projectDiffCmd.Flags().Bool("json", false, "JSON output")
+	projectCmd.AddCommand(projectDiffCmd)
+}
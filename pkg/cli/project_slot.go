@@ -18,8 +18,10 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
@@ -50,6 +52,7 @@ var projectSlotDelete = &cobra.Command{
 			time.Sleep(time.Second * 5)
 		}
 		handleError(proj.PurgeSlot())
+		handleError(proj.SlotForget(slot))
 	},
 }
 
@@ -71,7 +74,87 @@ var projectSlotCopy = &cobra.Command{
 	},
 }
 
+var projectSlotList = &cobra.Command{
+	Use:     "list [--json]",
+	Aliases: []string{"l"},
+	Short:   "List slots with their volumes, size, last used time and label.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(false)
+		handleError(err)
+		slots, err := proj.SlotList()
+		handleError(err)
+		if checkFlag(cmd, "json") {
+			out, err := json.MarshalIndent(slots, "", "  ")
+			handleError(err)
+			output.WriteStdout(string(out) + "\n")
+			return
+		}
+		data := make([][]string, 0)
+		for _, s := range slots {
+			slot := fmt.Sprintf("%d", s.Slot)
+			if s.Active {
+				slot += " *"
+			}
+			size := "n/a"
+			if s.Size >= 0 {
+				size = fmt.Sprintf("%.2f MB", float64(s.Size)/1024/1024)
+			}
+			lastUsed := "never"
+			if !s.LastUsed.IsZero() {
+				lastUsed = s.LastUsed.Format(time.RFC3339)
+			}
+			data = append(data, []string{
+				slot, s.Label, strings.Join(s.Volumes, "\n"), size, lastUsed,
+			})
+		}
+		drawTable([]string{"Slot", "Label", "Volumes", "Size", "Last Used"}, data)
+	},
+}
+
+var projectSlotLabel = &cobra.Command{
+	Use:   "label slot text",
+	Short: "Label slot, an empty text removes the label.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			handleError(fmt.Errorf("slot argument not provided"))
+		}
+		proj, err := getProject(false)
+		handleError(err)
+		slot, err := strconv.Atoi(args[0])
+		handleError(err)
+		handleError(proj.SlotLabel(slot, strings.Join(args[1:], " ")))
+		handleError(proj.Save())
+	},
+}
+
+var projectSlotSwitch = &cobra.Command{
+	Use:   "switch slot|label",
+	Short: "Switch the slot used when starting the project.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			handleError(fmt.Errorf("slot argument not provided"))
+		}
+		proj, err := getProject(false)
+		handleError(err)
+		slot, err := proj.ResolveSlot(args[0])
+		handleError(err)
+		proj.SlotSwitch(slot)
+		handleError(proj.Save())
+		// warn when running containers use another slot
+		for _, s := range proj.Status() {
+			if s.Running && s.Slot != slot {
+				output.Warn("Project is running with another slot, stop and start it to use the new slot.")
+				break
+			}
+		}
+	},
+}
+
 func init() {
+	projectSlotList.Flags().Bool("json", false, "JSON output")
+	projectSlotCmd.AddCommand(projectSlotList)
+	projectSlotCmd.AddCommand(projectSlotLabel)
+	projectSlotCmd.AddCommand(projectSlotSwitch)
 	projectSlotCmd.AddCommand(projectSlotDelete)
 	projectSlotCmd.AddCommand(projectSlotCopy)
 	projectCmd.AddCommand(projectSlotCmd)
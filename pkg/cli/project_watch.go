@@ -0,0 +1,65 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/router"
+)
+
+var projectWatchCmd = &cobra.Command{
+	Use:   "watch [--no-router] [--no-commit]",
+	Short: "Watch project yaml files and restart or rebuild changed definitions.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		// use slot of running project
+		if status := proj.Status(); len(status) > 0 {
+			proj.SetSlot(status[0].Slot)
+		}
+		if proj.HasFlag(project.DisableAutoCommit) || checkFlag(cmd, "no-commit") {
+			proj.SetNoCommit()
+		}
+		stop := make(chan struct{})
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sig
+			output.Info("Stop watching.")
+			close(stop)
+		}()
+		handleError(proj.Watch(stop, func(p *project.Project) error {
+			if checkFlag(cmd, "no-router") {
+				return nil
+			}
+			return router.AddProjectRoutes(p)
+		}))
+	},
+}
+
+func init() {
+	projectWatchCmd.Flags().Bool("no-router", false, "skip updating routes in router")
+	projectWatchCmd.Flags().Bool("no-commit", false, "don't commit the container after being built")
+	projectCmd.AddCommand(projectWatchCmd)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
 )
 
 // RootCmd is the top level command.
@@ -52,4 +53,11 @@ func Execute() error {
 
 func init() {
 	RootCmd.PersistentFlags().BoolP("verbose", "v", false, "show more verbose output")
+	RootCmd.PersistentFlags().String("container-backend", "", "container backend to use (overrides container_backend option)")
+	RootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		backend := RootCmd.PersistentFlags().Lookup("container-backend").Value.String()
+		if backend != "" {
+			handleError(project.SetContainerBackend(backend))
+		}
+	}
 }
@@ -20,7 +20,10 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
@@ -61,6 +64,39 @@ var routerSetPortCmd = &cobra.Command{
 	},
 }
 
+var routerSetModeCmd = &cobra.Command{
+	Use:     "setmode nginx|native",
+	Aliases: []string{"mode"},
+	Short:   "Set router mode, either an nginx container or the in-process native router.",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		gc, err := config.Load()
+		handleError(err)
+		gc.Router.Mode = args[0]
+		for _, err := range gc.Validate() {
+			handleError(err)
+		}
+		output.Info(fmt.Sprintf("Set router mode to '%s.'", args[0]))
+		handleError(config.Save(gc))
+	},
+}
+
+var routerServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the native router in the foreground.",
+	Run: func(cmd *cobra.Command, args []string) {
+		stop := make(chan struct{})
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sig
+			output.Info("Stop native router.")
+			close(stop)
+		}()
+		handleError(router.Serve(stop))
+	},
+}
+
 var routerStartCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start router.",
@@ -108,6 +144,26 @@ var routerDelCmd = &cobra.Command{
 	},
 }
 
+var routerCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage router cache.",
+}
+
+var routerCacheClearCmd = &cobra.Command{
+	Use:     "clear [--project]",
+	Aliases: []string{"purge", "c"},
+	Short:   "Clear cached responses of all projects or of the current project.",
+	Run: func(cmd *cobra.Command, args []string) {
+		pid := ""
+		if checkFlag(cmd, "project") {
+			proj, err := getProject(false)
+			handleError(err)
+			pid = proj.ID
+		}
+		handleError(router.ClearCache(pid))
+	},
+}
+
 var routerListCmd = &cobra.Command{
 	Use:   "list [--json]",
 	Short: "List all active routes.",
@@ -146,12 +202,17 @@ func init() {
 	routerSetPortCmd.Flags().Uint16("http", router.HTTPPort, "Set HTTP port.")
 	routerSetPortCmd.Flags().Uint16("https", router.HTTPSPort, "Set HTTPS port.")
 	routerListCmd.Flags().Bool("json", false, "JSON output")
+	routerCacheClearCmd.Flags().BoolP("project", "p", false, "only clear cache of current project")
+	routerCacheCmd.AddCommand(routerCacheClearCmd)
 	routerCmd.AddCommand(routerSetPortCmd)
+	routerCmd.AddCommand(routerSetModeCmd)
+	routerCmd.AddCommand(routerServeCmd)
 	routerCmd.AddCommand(routerStartCmd)
 	routerCmd.AddCommand(routerStopCmd)
 	routerCmd.AddCommand(routerResetCmd)
 	routerCmd.AddCommand(routerAddCmd)
 	routerCmd.AddCommand(routerDelCmd)
 	routerCmd.AddCommand(routerListCmd)
+	routerCmd.AddCommand(routerCacheCmd)
 	RootCmd.AddCommand(routerCmd)
 }
@@ -18,6 +18,9 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 package cli
 
 import (
+	"fmt"
+	"io/ioutil"
+
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 
 	"github.com/spf13/cobra"
@@ -50,8 +53,38 @@ var routerCertificatesDumpCACmd = &cobra.Command{
 	},
 }
 
+var routerCertificatesTrustBundleCmd = &cobra.Command{
+	Use:     "trust-bundle [--format pem|p12|nss] [--output file]",
+	Aliases: []string{"trust", "bundle"},
+	Short:   "Export the CA certificate for import in to browsers and system trust stores.",
+	Run: func(cmd *cobra.Command, args []string) {
+		format := cmd.Flags().Lookup("format").Value.String()
+		if format == router.TrustBundleFormatNSS {
+			databases, err := router.ImportTrustBundleNSS()
+			handleError(err)
+			for _, db := range databases {
+				output.Info(fmt.Sprintf("Imported CA in to '%s.'", db))
+			}
+			return
+		}
+		bundle, err := router.TrustBundle(format, cmd.Flags().Lookup("password").Value.String())
+		handleError(err)
+		outputPath := cmd.Flags().Lookup("output").Value.String()
+		if outputPath == "" {
+			output.WriteStdout(string(bundle))
+			return
+		}
+		handleError(ioutil.WriteFile(outputPath, bundle, 0644))
+		output.Info(fmt.Sprintf("Wrote CA to '%s.'", outputPath))
+	},
+}
+
 func init() {
+	routerCertificatesTrustBundleCmd.Flags().StringP("format", "f", router.TrustBundleFormatPEM, "bundle format (pem, p12 or nss)")
+	routerCertificatesTrustBundleCmd.Flags().StringP("output", "o", "", "write bundle to file instead of stdout")
+	routerCertificatesTrustBundleCmd.Flags().String("password", "", "p12 bundle password")
 	routerCertificatesCmd.AddCommand(routerCertificatesClearCmd)
 	routerCertificatesCmd.AddCommand(routerCertificatesDumpCACmd)
+	routerCertificatesCmd.AddCommand(routerCertificatesTrustBundleCmd)
 	routerCmd.AddCommand(routerCertificatesCmd)
 }
@@ -0,0 +1,99 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/router"
+)
+
+var routerLogCmd = &cobra.Command{
+	Use:     "log [-f] [--project] [--json]",
+	Aliases: []string{"logs", "access-log"},
+	Short:   "Display router access log.",
+	Run: func(cmd *cobra.Command, args []string) {
+		var filter func(router.AccessLogEntry) bool
+		if checkFlag(cmd, "project") {
+			proj, err := getProject(true)
+			handleError(err)
+			hosts := map[string]bool{}
+			for _, host := range router.ProjectHosts(proj) {
+				hosts[host] = true
+			}
+			filter = func(entry router.AccessLogEntry) bool {
+				return hosts[entry.Host]
+			}
+		}
+		handleError(router.StreamAccessLog(checkFlag(cmd, "follow"), filter, func(entry router.AccessLogEntry) error {
+			// json out
+			if checkFlag(cmd, "json") {
+				entryJSON, err := json.Marshal(entry)
+				if err != nil {
+					return err
+				}
+				output.WriteStdout(string(entryJSON) + "\n")
+				return nil
+			}
+			// line out
+			status := output.Color(fmt.Sprintf("%d", entry.Status), 32)
+			if entry.Status >= 400 {
+				status = output.Color(fmt.Sprintf("%d", entry.Status), 31)
+			} else if entry.Status >= 300 {
+				status = output.Color(fmt.Sprintf("%d", entry.Status), 33)
+			}
+			target := entry.Upstream
+			if target == "" {
+				target = entry.RouteType
+			}
+			route := entry.Route
+			if route == "" {
+				route = "-"
+			}
+			cache := ""
+			if entry.Cache != "" {
+				cache = " " + entry.Cache
+			}
+			output.WriteStdout(fmt.Sprintf(
+				"%s %s %s %s%s -> %s [%s] %.3fs %dB/%dB%s\n",
+				entry.Time.Local().Format("2006-01-02 15:04:05"),
+				status,
+				entry.Method,
+				entry.Host,
+				entry.Path,
+				target,
+				route,
+				entry.Latency,
+				entry.RequestSize,
+				entry.ResponseSize,
+				cache,
+			))
+			return nil
+		}))
+	},
+}
+
+func init() {
+	routerLogCmd.Flags().BoolP("follow", "f", false, "follow log")
+	routerLogCmd.Flags().BoolP("project", "p", false, "only show requests to the current project's hosts")
+	routerLogCmd.Flags().Bool("json", false, "JSON output")
+	routerCmd.AddCommand(routerLogCmd)
+}
@@ -0,0 +1,112 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/router"
+)
+
+var routerShareCmd = &cobra.Command{
+	Use:   "share [--host] [--transport relay|ssh] [--server] [--secret] [--insecure-host-key]",
+	Short: "Share one of the project's routes through a public tunnel.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject(true)
+		handleError(err)
+		transportType := proj.GetOption(project.OptionShareTransport)
+		if v := cmd.Flags().Lookup("transport").Value.String(); v != "" {
+			handleError(project.OptionShareTransport.Validate(v))
+			transportType = v
+		}
+		server := proj.GetOption(project.OptionShareServer)
+		if v := cmd.Flags().Lookup("server").Value.String(); v != "" {
+			server = v
+		}
+		secret := proj.GetOption(project.OptionShareSecret)
+		if v := cmd.Flags().Lookup("secret").Value.String(); v != "" {
+			secret = v
+		}
+		remotePort, err := strconv.Atoi(proj.GetOption(project.OptionShareRemotePort))
+		handleError(err)
+		insecureHostKey := cmd.Flags().Lookup("insecure-host-key").Value.String() == "true"
+		transport, err := router.NewShareTransport(
+			transportType, server, secret, remotePort, proj.GetOption(project.OptionSharePublicURL), insecureHostKey,
+		)
+		handleError(err)
+		share, err := router.ShareProject(proj, cmd.Flags().Lookup("host").Value.String(), transport)
+		handleError(err)
+		output.Info(fmt.Sprintf("Sharing '%s' at %s", share.Host, share.PublicURL))
+		output.WriteStdout(share.PublicURL + "\n")
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-sig:
+			{
+				output.Info("Stop sharing.")
+			}
+		case <-share.Done():
+			{
+				output.Warn("Tunnel closed by server.")
+			}
+		}
+		handleError(share.Close())
+	},
+}
+
+var routerRelayCmd = &cobra.Command{
+	Use:   "relay [--listen] [--public] [--url] [--secret]",
+	Short: "Run a relay server for router:share.",
+	Run: func(cmd *cobra.Command, args []string) {
+		relay := router.NewRelayServer(
+			cmd.Flags().Lookup("listen").Value.String(),
+			cmd.Flags().Lookup("public").Value.String(),
+			cmd.Flags().Lookup("url").Value.String(),
+			cmd.Flags().Lookup("secret").Value.String(),
+		)
+		handleError(relay.Start())
+		output.Info(fmt.Sprintf("Relay accepting clients on %s, public URL %s.", relay.ControlAddr, relay.PublicURL))
+		output.Info(fmt.Sprintf("Clients register with secret '%s' (share_secret option).", relay.Secret))
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		output.Info("Stop relay.")
+		handleError(relay.Close())
+	},
+}
+
+func init() {
+	routerShareCmd.Flags().String("host", "", "host to share, defaults to the first upstream route")
+	routerShareCmd.Flags().String("transport", "", "tunnel transport (relay or ssh), overrides share_transport option")
+	routerShareCmd.Flags().String("server", "", "relay or ssh server, overrides share_server option")
+	routerShareCmd.Flags().String("secret", "", "relay secret, overrides share_secret option")
+	routerShareCmd.Flags().Bool("insecure-host-key", false, "do not verify the ssh server host key")
+	routerRelayCmd.Flags().String("listen", project.OptionShareServer.DefaultValue(), "address to accept share clients on")
+	routerRelayCmd.Flags().String("public", "127.0.0.1:8080", "address to accept public connections on")
+	routerRelayCmd.Flags().String("url", "", "public URL given to clients")
+	routerRelayCmd.Flags().String("secret", "", "secret clients register with, random if empty")
+	routerCmd.AddCommand(routerShareCmd)
+	routerCmd.AddCommand(routerRelayCmd)
+}
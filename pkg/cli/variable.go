@@ -23,11 +23,13 @@ import (
 	"io/ioutil"
 	"os"
 	"sort"
+	"strings"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -38,7 +40,7 @@ var varCmd = &cobra.Command{
 }
 
 var varSetCmd = &cobra.Command{
-	Use:     "create name value",
+	Use:     "create name value [--sensitive]",
 	Aliases: []string{"set", "update"},
 	Short:   "Create/set a variable.",
 	Run: func(cmd *cobra.Command, args []string) {
@@ -64,6 +66,10 @@ var varSetCmd = &cobra.Command{
 				// delete empty var
 				gc.Variables.Delete(args[0])
 			} else {
+				if checkFlag(cmd, "sensitive") {
+					value, err = config.EncryptVariable(strings.TrimSpace(value))
+					handleError(err)
+				}
 				handleError(gc.Variables.Set(args[0], value))
 			}
 			output.Info(fmt.Sprintf("Set global variable '%s.'", args[0]))
@@ -76,6 +82,8 @@ var varSetCmd = &cobra.Command{
 		if value == "" {
 			// delete empty var
 			handleError(proj.VarDelete(args[0]))
+		} else if checkFlag(cmd, "sensitive") {
+			handleError(proj.VarSetSensitive(args[0], value))
 		} else {
 			handleError(proj.VarSet(args[0], value))
 		}
@@ -96,7 +104,7 @@ var varGetCmd = &cobra.Command{
 		if checkFlag(varCmd, "global") {
 			gc, err := config.Load()
 			handleError(err)
-			output.WriteStdout(gc.Variables.GetString(args[0]) + "\n")
+			output.WriteStdout(gc.Variables.Masked().GetString(args[0]) + "\n")
 			return
 		}
 		// use project variable (global if project var not set)
@@ -107,7 +115,7 @@ var varGetCmd = &cobra.Command{
 		if out == "" {
 			gc, err := config.Load()
 			handleError(err)
-			out = gc.Variables.GetString(args[0])
+			out = gc.Variables.Masked().GetString(args[0])
 		}
 		output.WriteStdout(out + "\n")
 	},
@@ -155,18 +163,20 @@ var varListCmd = &cobra.Command{
 			varList := make(def.Variables)
 			varList.Merge(gc.Variables)
 			varList.Merge(proj.Variables)
-			out, err := json.MarshalIndent(varList, "", "  ")
+			out, err := json.MarshalIndent(varList.Masked(), "", "  ")
 			handleError(err)
 			output.WriteStdout(string(out) + "\n")
 			return
 		}
 		varSources := make(map[string][]string)
 		varKeys := make([]string, 0)
-		for _, k := range gc.Variables.Keys() {
-			varSources[k] = []string{"global", gc.Variables.GetString(k)}
+		globalVars := gc.Variables.Masked()
+		for _, k := range globalVars.Keys() {
+			varSources[k] = []string{"global", globalVars.GetString(k)}
 		}
-		for _, k := range proj.Variables.Keys() {
-			varSources[k] = []string{"project", proj.Variables.GetString(k)}
+		projVars := proj.Variables.Masked()
+		for _, k := range projVars.Keys() {
+			varSources[k] = []string{"project", projVars.GetString(k)}
 		}
 		for k := range varSources {
 			varKeys = append(varKeys, k)
@@ -184,12 +194,116 @@ var varListCmd = &cobra.Command{
 	},
 }
 
+var varImportCmd = &cobra.Command{
+	Use:   "import file [--prefix env:] [--format env|json|yaml] [--sensitive]",
+	Short: "Import variables from a dotenv, JSON or YAML file.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			handleError(fmt.Errorf("missing file path"))
+		}
+		data, err := ioutil.ReadFile(args[0])
+		handleError(err)
+		format := cmd.Flags().Lookup("format").Value.String()
+		if format == "" {
+			format = def.VariablesFormatFromPath(args[0])
+		}
+		prefix := cmd.Flags().Lookup("prefix").Value.String()
+		vars, err := def.ParseVariablesFile(data, format, prefix)
+		handleError(err)
+		sensitive := checkFlag(cmd, "sensitive")
+		// use global variables
+		if checkFlag(varCmd, "global") {
+			gc, err := config.Load()
+			handleError(err)
+			for _, k := range vars.Keys() {
+				value := vars.Get(k)
+				if sensitive {
+					value, err = config.EncryptVariable(def.InterfaceToString(value))
+					handleError(err)
+				}
+				handleError(gc.Variables.Set(k, value))
+			}
+			output.Info(fmt.Sprintf("Imported %d global variable(s).", len(vars)))
+			handleError(config.Save(gc))
+			return
+		}
+		// use project variables
+		proj, err := getProject(false)
+		handleError(err)
+		handleError(proj.VarImport(vars, sensitive))
+		handleError(proj.Save())
+	},
+}
+
+var varExportCmd = &cobra.Command{
+	Use:   "export [--format env|json|yaml] [--prefix env:] [--reveal]",
+	Short: "Export variables as dotenv, JSON or YAML.",
+	Run: func(cmd *cobra.Command, args []string) {
+		output.Enable = false
+		var vars def.Variables
+		if checkFlag(varCmd, "global") {
+			gc, err := config.Load()
+			handleError(err)
+			vars = gc.Variables
+		} else {
+			proj, err := getProject(false)
+			handleError(err)
+			vars = proj.Variables
+		}
+		if checkFlag(cmd, "reveal") {
+			var err error
+			vars, err = revealVariables(vars)
+			handleError(err)
+		} else {
+			var omitted []string
+			vars, omitted = vars.WithoutSensitive()
+			if len(omitted) > 0 {
+				output.WriteStderr(fmt.Sprintf(
+					"WARNING: sensitive variable(s) %s not exported, use --reveal to export them decrypted.\n",
+					strings.Join(omitted, ", "),
+				))
+			}
+		}
+		out, err := vars.Export(
+			cmd.Flags().Lookup("format").Value.String(),
+			cmd.Flags().Lookup("prefix").Value.String(),
+		)
+		handleError(err)
+		output.WriteStdout(string(out))
+	},
+}
+
+// revealVariables returns a copy of given variables with sensitive values decrypted.
+func revealVariables(vars def.Variables) (def.Variables, error) {
+	out := make(def.Variables)
+	for k, v := range vars {
+		if vars.IsSensitive(k) {
+			value, err := config.DecryptVariable(v.(string))
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			v = value
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
 func init() {
 	varCmd.PersistentFlags().BoolP("global", "g", false, "Use global variable.")
+	varSetCmd.Flags().Bool("sensitive", false, "Store value encrypted and mask it in output.")
 	varListCmd.Flags().Bool("json", false, "JSON output")
+	varImportCmd.Flags().String("prefix", def.VariablesEnvPrefix, "Prefix for variable names without one.")
+	varImportCmd.Flags().String("format", "", "File format (env, json, yaml), detected from the file extension if not set.")
+	varImportCmd.Flags().Bool("sensitive", false, "Store imported values encrypted and mask them in output.")
+	varExportCmd.Flags().String("prefix", def.VariablesEnvPrefix, "Prefix of variables to export as dotenv.")
+	varExportCmd.Flags().String("format", def.VariablesFormatEnv, "Output format (env, json, yaml).")
+	varExportCmd.Flags().Bool("reveal", false, "Export sensitive variables decrypted instead of leaving them out.")
 	varCmd.AddCommand(varSetCmd)
 	varCmd.AddCommand(varGetCmd)
 	varCmd.AddCommand(varDelCmd)
 	varCmd.AddCommand(varListCmd)
+	varCmd.AddCommand(varImportCmd)
+	varCmd.AddCommand(varExportCmd)
 	RootCmd.AddCommand(varCmd)
 }
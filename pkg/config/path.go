@@ -28,6 +28,9 @@ import (
 const configPerm = 0766
 const userConfigPath = "~/.config/platformcc"
 
+// pathOverride replaces the user config path when set.
+var pathOverride = ""
+
 func expandPath(path string) string {
 	if len(path) == 0 || path[0] != '~' {
 		return path
@@ -53,5 +56,13 @@ func pathTo(name string) string {
 
 // Path returns the path to the config directory.
 func Path() string {
+	if pathOverride != "" {
+		return pathOverride
+	}
 	return expandPath(userConfigPath)
 }
+
+// SetPath overrides the path to the config directory, mainly for tests. An empty path restores the default.
+func SetPath(path string) {
+	pathOverride = path
+}
@@ -0,0 +1,114 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const variablesKeyPath = "variables.key"
+const variablesKeySize = 32
+
+// generateVariablesKey generates the key used to encrypt sensitive variables and stores it to config path.
+func generateVariablesKey() ([]byte, error) {
+	done := output.Duration("Generate sensitive variables key.")
+	key := make([]byte, variablesKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// init config directory
+	if err := initConfig(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(pathTo(variablesKeyPath), key, 0600); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	done()
+	return key, nil
+}
+
+// variablesCipher returns the cipher used to encrypt sensitive variables.
+func variablesCipher() (cipher.AEAD, error) {
+	key, err := ioutil.ReadFile(pathTo(variablesKeyPath))
+	if err != nil {
+		// generate if not exist
+		if !os.IsNotExist(err) {
+			return nil, errors.WithStack(err)
+		}
+		if key, err = generateVariablesKey(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if len(key) != variablesKeySize {
+		return nil, errors.Wrapf(ErrInvalidVariablesKey, "key at '%s' must be %d bytes", pathTo(variablesKeyPath), variablesKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	return gcm, errors.WithStack(err)
+}
+
+// EncryptVariable encrypts given variable value and returns it marked as sensitive.
+func EncryptVariable(value string) (string, error) {
+	gcm, err := variablesCipher()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return def.SensitiveVariablePrefix + base64.StdEncoding.EncodeToString(
+		gcm.Seal(nonce, nonce, []byte(value), nil),
+	), nil
+}
+
+// DecryptVariable decrypts given sensitive variable value, other values are returned as is.
+func DecryptVariable(value string) (string, error) {
+	if !def.IsSensitiveVariable(value) {
+		return value, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, def.SensitiveVariablePrefix))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	gcm, err := variablesCipher()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.Wrap(ErrInvalidVariablesKey, "sensitive variable value is too short")
+	}
+	out, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.Wrap(ErrInvalidVariablesKey, "could not decrypt sensitive variable")
+	}
+	return string(out), nil
+}
@@ -34,6 +34,7 @@ type Config struct {
 	Ports        []string
 	WorkingDir   string
 	EnableOSXNFS bool
+	Quiet        bool // when true only write output to the log file
 }
 
 // GetContainerName return the name of the Docker container.
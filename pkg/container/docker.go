@@ -28,9 +28,13 @@ import (
 	"github.com/pkg/errors"
 )
 
+const dockerClientTimeout = time.Second * 900 // 15 minutes
+
 // Docker defines the Docker container handler.
 type Docker struct {
-	client *client.Client
+	client     *client.Client
+	privileged bool     // start containers in privileged mode
+	capAdd     []string // kernel capabilities to add to containers
 }
 
 // NewDocker creates a new Docker container handler.
@@ -44,12 +48,20 @@ func NewDocker() (Docker, error) {
 		return Docker{}, errors.WithStack(convertDockerError(err))
 	}
 	return Docker{
-		client: dockerClient,
+		client:     dockerClient,
+		privileged: true,
+		capAdd:     []string{"SYS_ADMIN"},
 	}, nil
 }
 
+func init() {
+	RegisterHandler(HandlerDocker, func() (Interface, error) {
+		return NewDocker()
+	})
+}
+
 func getDockerOpts() ([]client.Opt, error) {
-	timeout := client.WithTimeout(time.Second * 900) // 15 minutes
+	timeout := client.WithTimeout(dockerClientTimeout)
 	// standard docker environment
 	host := os.Getenv("DOCKER_HOST")
 	if !strings.HasPrefix(host, "ssh://") {
@@ -41,6 +41,32 @@ import (
 const dockerCommitTagPrefix = "pcc.local/build:"
 const containerStopTimeout = 10
 
+// commandLogMaxSize is the maximum amount of command output kept for the debug log.
+const commandLogMaxSize = 64 * 1024
+
+// cappedBuffer is a buffer that discards everything written past max bytes.
+type cappedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+// Write implements io.Writer.
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// String returns the buffered output.
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
 // ContainerStart starts a Docker container.
 func (d Docker) ContainerStart(c Config) error {
 	// ensure not already running
@@ -65,13 +91,15 @@ func (d Docker) ContainerStart(c Config) error {
 		}
 	}
 	// log start
-	done := output.Duration(
-		fmt.Sprintf(
-			"Start Docker container for %s '%s.'",
-			c.ObjectType.TypeName(),
-			c.ObjectName,
-		),
+	msg := fmt.Sprintf(
+		"Start Docker container for %s '%s.'",
+		c.ObjectType.TypeName(),
+		c.ObjectName,
 	)
+	done := output.LogDuration(msg)
+	if !c.Quiet {
+		done = output.Duration(msg)
+	}
 	// get mounts
 	mounts := make([]mount.Mount, 0)
 	mounts = append(mounts, mount.Mount{
@@ -153,8 +181,8 @@ func (d Docker) ContainerStart(c Config) error {
 	}
 	cHostConfig := &container.HostConfig{
 		AutoRemove:   false,
-		Privileged:   true,
-		CapAdd:       []string{"SYS_ADMIN"},
+		Privileged:   d.privileged,
+		CapAdd:       d.capAdd,
 		Tmpfs:        map[string]string{"/tmp": "exec,mode=777", "/run": "exec,mode=777"},
 		Mounts:       mounts,
 		PortBindings: portBinding,
@@ -241,12 +269,12 @@ func (d Docker) ContainerCommand(id string, user string, cmd []string, out io.Wr
 	if err != nil {
 		return -1, errors.WithStack(convertDockerError(err))
 	}
-	// get command stdout
-	var buf bytes.Buffer
+	// get command stdout, only the start of long running or followed output is kept for the debug log
+	buf := &cappedBuffer{max: commandLogMaxSize}
 	var mWriter io.Writer
-	mWriter = &buf
+	mWriter = buf
 	if out != nil {
-		mWriter = io.MultiWriter(&buf, out)
+		mWriter = io.MultiWriter(buf, out)
 	}
 	if _, err := io.Copy(mWriter, hresp.Reader); err != nil {
 		return -1, errors.WithStack(err)
@@ -272,6 +300,10 @@ func (d Docker) ContainerStatus(id string) (Status, error) {
 			break
 		}
 	}
+	// no default bridge network (podman), use pcc network
+	if ipAddress == "" && data.NetworkSettings.Networks[dockerNetworkName] != nil {
+		ipAddress = data.NetworkSettings.Networks[dockerNetworkName].IPAddress
+	}
 	slot := 1
 	for _, m := range data.Mounts {
 		if m.Type == mount.TypeVolume {
@@ -299,6 +331,23 @@ func (d Docker) ContainerStatus(id string) (Status, error) {
 	}, nil
 }
 
+// ContainerStop stops and deletes a single Docker container.
+func (d Docker) ContainerStop(id string) error {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("name", fmt.Sprintf("^/%s$", id))
+	containers, err := d.client.ContainerList(
+		context.Background(),
+		types.ContainerListOptions{
+			Filters: filterArgs,
+			All:     true,
+		},
+	)
+	if err != nil {
+		return errors.WithStack(convertDockerError(err))
+	}
+	return errors.WithStack(d.deleteContainers(containers))
+}
+
 // ContainerUpload uploads one or more files to a Docker container from a tarball reader.
 func (d Docker) ContainerUpload(id string, path string, r io.Reader) error {
 	output.LogDebug(
@@ -343,18 +392,30 @@ func (d Docker) ContainerDownload(id string, path string, w io.Writer) error {
 }
 
 // ContainerLog returns a reader containing log data for a Docker container.
-func (d Docker) ContainerLog(id string, follow bool) (io.ReadCloser, error) {
+func (d Docker) ContainerLog(id string, opts LogOptions) (io.ReadCloser, error) {
 	output.LogDebug(fmt.Sprintf("Read logs for container '%s.'", id), nil)
+	data, err := d.client.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return nil, errors.WithStack(convertDockerError(err))
+	}
 	rc, err := d.client.ContainerLogs(
 		context.Background(),
 		id,
 		types.ContainerLogsOptions{
 			ShowStdout: true,
 			ShowStderr: true,
-			Follow:     follow,
+			Follow:     opts.Follow,
+			Since:      opts.sinceString(),
+			Timestamps: opts.Timestamps,
 		},
 	)
-	return rc, errors.WithStack(convertDockerError(err))
+	if err != nil {
+		return nil, errors.WithStack(convertDockerError(err))
+	}
+	if data.Config != nil && data.Config.Tty {
+		return rc, nil
+	}
+	return demuxLog(rc), nil
 }
 
 // ContainerCommit stores a Docker container's state as an image.
@@ -370,12 +431,6 @@ func (d Docker) ContainerCommit(id string) error {
 	if !data.State.Running {
 		return errors.Wrapf(ErrContainerNotRunning, "container %s is not running", id)
 	}
-	done := output.Duration(
-		fmt.Sprintf(
-			"Commit container '%s.'",
-			id,
-		),
-	)
 	// commit image
 	idResp, err := d.client.ContainerCommit(
 		context.Background(),
@@ -396,7 +451,6 @@ func (d Docker) ContainerCommit(id string) error {
 		)
 		return errors.WithStack(convertDockerError(err))
 	}
-	done()
 	return nil
 }
 
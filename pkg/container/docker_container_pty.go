@@ -154,8 +154,12 @@ func (d Docker) ContainerShell(id string, user string, cmd []string, stdin io.Re
 			for {
 				n, err = io.Copy(hresp.Conn, stdin)
 				if err == nil {
-					code, err := d.checkCommandExec(resp.ID)
-					exit <- exitResult{code, errors.WithStack(err)}
+					// signal end of input to the command and wait for its output to finish
+					if err := hresp.CloseWrite(); err != nil {
+						exit <- exitResult{-1, errors.WithStack(err)}
+						return
+					}
+					exit <- exitResult{0, nil}
 					return
 				}
 				if strings.Contains(err.Error(), "broken pipe") {
@@ -185,7 +189,11 @@ func (d Docker) ContainerShell(id string, user string, cmd []string, stdin io.Re
 			output.WriteStdout(string(t) + "\n")
 		}
 		res := <-exit
-		return res.code, errors.WithStack(res.error)
+		if res.error != nil {
+			return res.code, errors.WithStack(res.error)
+		}
+		code, err := d.checkCommandExec(resp.ID)
+		return code, errors.WithStack(err)
 	}
 	// create interactive shell
 	// handle resizing
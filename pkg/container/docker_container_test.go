@@ -0,0 +1,38 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package container
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+func TestCappedBuffer(t *testing.T) {
+	buf := &cappedBuffer{max: 10}
+	n, err := io.Copy(buf, strings.NewReader(strings.Repeat("a", 25)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(n, int64(25), "expected all output to be accepted", t)
+	def.AssertEqual(len(buf.String()), 10, "expected buffer to be capped", t)
+	buf.Write([]byte("b"))
+	def.AssertEqual(buf.String(), strings.Repeat("a", 10), "expected writes past the cap to be discarded", t)
+}
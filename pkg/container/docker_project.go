@@ -126,7 +126,7 @@ func (d Docker) ProjectCopySlot(pid string, sourceSlot int, destSlot int) error
 	}
 	// create container
 	cConfig := &container.Config{
-		Image:        "busybox",
+		Image:        "docker.io/library/busybox",
 		Cmd:          []string{"sh", "-c", "cp -rv /mnt/src/* /mnt/dest/"},
 		AttachStdout: true,
 	}
@@ -196,3 +196,36 @@ func (d Docker) ProjectCopySlot(pid string, sourceSlot int, destSlot int) error
 	done()
 	return nil
 }
+
+// ProjectVolumes returns the volumes of all slots of given project along with their size.
+func (d Docker) ProjectVolumes(pid string) ([]Volume, error) {
+	prefix := fmt.Sprintf(containerNamingPrefix, pid)
+	out := make([]Volume, 0)
+	// disk usage contains volume sizes but can fail while another disk usage operation is running
+	du, err := d.client.DiskUsage(context.Background())
+	if err == nil {
+		for _, v := range du.Volumes {
+			if !strings.HasPrefix(v.Name, prefix) || volumeIsGlobal(v.Name) {
+				continue
+			}
+			size := int64(-1)
+			if v.UsageData != nil {
+				size = v.UsageData.Size
+			}
+			out = append(out, Volume{Name: v.Name, Slot: volumeGetSlot(v.Name), Size: size})
+		}
+		return out, nil
+	}
+	output.LogDebug("Disk usage not available.", err)
+	list, err := d.listProjectVolumes(pid)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, v := range list.Volumes {
+		if volumeIsGlobal(v.Name) {
+			continue
+		}
+		out = append(out, Volume{Name: v.Name, Slot: volumeGetSlot(v.Name), Size: -1})
+	}
+	return out, nil
+}
@@ -24,10 +24,14 @@ import (
 	"io/ioutil"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+// dummyLogTime is the timestamp of dummy log lines.
+var dummyLogTime = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // DummyContainer is a dummy container.
 type DummyContainer struct {
 	ID             string
@@ -80,6 +84,16 @@ func NewDummy() Dummy {
 	}
 }
 
+// sharedDummy is the dummy handler returned by the handler registry so
+// that all parts of a dry run share the same tracked environment.
+var sharedDummy = NewDummy()
+
+func init() {
+	RegisterHandler(HandlerDummy, func() (Interface, error) {
+		return sharedDummy, nil
+	})
+}
+
 // GetContainer returns the dummy container for given ID.
 func (d Dummy) GetContainer(id string) *DummyContainer {
 	d.Tracker.Sync.Lock()
@@ -166,6 +180,20 @@ func (d Dummy) ContainerStatus(id string) (Status, error) {
 	}, nil
 }
 
+// ContainerStop stops dummy container.
+func (d Dummy) ContainerStop(id string) error {
+	d.Tracker.Sync.Lock()
+	defer d.Tracker.Sync.Unlock()
+	containers := make([]*DummyContainer, 0)
+	for _, c := range d.Tracker.Containers {
+		if c.ID != id {
+			containers = append(containers, c)
+		}
+	}
+	d.Tracker.Containers = containers
+	return nil
+}
+
 // ContainerUpload uploads to dummy container.
 func (d Dummy) ContainerUpload(id string, path string, r io.Reader) error {
 	c := d.GetContainer(id)
@@ -196,10 +224,13 @@ func (d Dummy) ContainerDownload(id string, path string, w io.Writer) error {
 }
 
 // ContainerLog returns dummy logs.
-func (d Dummy) ContainerLog(id string, follow bool) (io.ReadCloser, error) {
+func (d Dummy) ContainerLog(id string, opts LogOptions) (io.ReadCloser, error) {
 	if d.GetContainer(id) == nil {
 		return nil, fmt.Errorf("container %s not running", id)
 	}
+	if opts.Timestamps {
+		return ioutil.NopCloser(bytes.NewReader([]byte(dummyLogTime.Format(time.RFC3339Nano) + " hello world\n"))), nil
+	}
 	return ioutil.NopCloser(bytes.NewReader([]byte("hello world"))), nil
 }
 
@@ -213,8 +244,8 @@ func (d Dummy) ContainerCommit(id string) error {
 
 // ContainerDeleteCommit deletes dummy commit.
 func (d Dummy) ContainerDeleteCommit(id string) error {
-	if d.GetContainer(id) == nil {
-		return fmt.Errorf("container %s is running", id)
+	if d.GetContainer(id) != nil {
+		return errors.Wrap(ErrCannotDeleteCommit, "cannot delete commit for running container")
 	}
 	return nil
 }
@@ -286,6 +317,19 @@ func (d Dummy) ProjectCopySlot(pid string, sourceSlot int, destSlot int) error {
 	return nil
 }
 
+// ProjectVolumes returns dummy project volumes.
+func (d Dummy) ProjectVolumes(pid string) ([]Volume, error) {
+	d.Tracker.Sync.Lock()
+	defer d.Tracker.Sync.Unlock()
+	out := make([]Volume, 0)
+	for _, c := range d.Tracker.Volumes {
+		if strings.Contains(c, pid) && !volumeIsGlobal(c) {
+			out = append(out, Volume{Name: c, Slot: volumeGetSlot(c), Size: 0})
+		}
+	}
+	return out, nil
+}
+
 // AllStop stops dummy containers.
 func (d Dummy) AllStop() error {
 	d.Tracker.Sync.Lock()
@@ -32,4 +32,6 @@ var (
 	ErrInvalidSlot = errors.New("invalid slot")
 	// ErrCommandExited is an error returned when a container command exits with a non zero exit code.
 	ErrCommandExited = errors.New("command exited with error")
+	// ErrHandlerNotFound is an error returned when a container handler is not registered.
+	ErrHandlerNotFound = errors.New("container handler not found")
 )
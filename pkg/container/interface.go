@@ -27,9 +27,10 @@ type Interface interface {
 	ContainerCommand(id string, user string, cmd []string, out io.Writer) (int, error)
 	ContainerShell(id string, user string, cmd []string, stdin io.Reader) (int, error)
 	ContainerStatus(id string) (Status, error)
+	ContainerStop(id string) error
 	ContainerUpload(id string, path string, r io.Reader) error
 	ContainerDownload(id string, path string, w io.Writer) error
-	ContainerLog(id string, follow bool) (io.ReadCloser, error)
+	ContainerLog(id string, opts LogOptions) (io.ReadCloser, error)
 	ContainerCommit(id string) error
 	ContainerDeleteCommit(id string) error
 	ImagePull(c []Config) error
@@ -37,6 +38,7 @@ type Interface interface {
 	ProjectPurge(pid string) error
 	ProjectPurgeSlot(pid string, slot int) error
 	ProjectCopySlot(pid string, sourceSlot int, destSlot int) error
+	ProjectVolumes(pid string) ([]Volume, error)
 	AllStop() error
 	AllPurge(deleteGlobalVolumes bool) error
 	AllStatus() ([]Status, error)
@@ -0,0 +1,65 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package container
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogOptions defines options for reading container logs.
+type LogOptions struct {
+	Follow     bool
+	Since      time.Time
+	Timestamps bool
+}
+
+// sinceString returns since as a Docker timestamp, empty when not set.
+func (o LogOptions) sinceString() string {
+	if o.Since.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%09d", o.Since.Unix(), o.Since.Nanosecond())
+}
+
+// demuxReader reads the demultiplexed output of a container log stream.
+type demuxReader struct {
+	*io.PipeReader
+	source io.ReadCloser
+}
+
+// Close implements io.Closer.
+func (r *demuxReader) Close() error {
+	err := r.source.Close()
+	r.PipeReader.Close()
+	return err
+}
+
+// demuxLog strips the stream headers Docker adds to the logs of containers without a TTY.
+// Stdout and stderr are merged in to a single stream.
+func demuxLog(rc io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, pw, rc)
+		pw.CloseWithError(err)
+	}()
+	return &demuxReader{PipeReader: pr, source: rc}
+}
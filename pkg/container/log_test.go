@@ -0,0 +1,53 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+func TestDemuxLog(t *testing.T) {
+	// multiplexed stream as returned for containers without a tty
+	stream := &bytes.Buffer{}
+	stdout := stdcopy.NewStdWriter(stream, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(stream, stdcopy.Stderr)
+	stdout.Write([]byte("2021-07-01T10:00:00.000000000Z hello world\n"))
+	// payload length 10 puts a newline byte in the frame header
+	stderr.Write([]byte("warning!!\n"))
+	stdout.Write([]byte("2021-07-01T10:00:01.000000000Z second\n"))
+	rc := demuxLog(ioutil.NopCloser(stream))
+	defer rc.Close()
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(len(lines), 3, "unexpected number of log lines", t)
+	def.AssertEqual(lines[0], "2021-07-01T10:00:00.000000000Z hello world", "unexpected stdout line", t)
+	def.AssertEqual(lines[1], "warning!!", "unexpected stderr line", t)
+	def.AssertEqual(lines[2], "2021-07-01T10:00:01.000000000Z second", "unexpected last line", t)
+}
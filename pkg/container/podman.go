@@ -0,0 +1,79 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+)
+
+// Podman defines the Podman container handler.
+// Podman exposes a Docker compatible API so the Docker handler is reused
+// with a different socket and without privileged containers.
+type Podman struct {
+	Docker
+}
+
+// NewPodman creates a new Podman container handler.
+func NewPodman() (Podman, error) {
+	podmanClient, err := client.NewClientWithOpts(
+		client.WithHost(getPodmanHost()),
+		client.WithAPIVersionNegotiation(),
+		client.WithTimeout(dockerClientTimeout),
+	)
+	if err != nil {
+		return Podman{}, errors.WithStack(convertDockerError(err))
+	}
+	return Podman{
+		Docker: Docker{
+			client: podmanClient,
+			// SYS_ADMIN is still needed to bind mount inside the container
+			privileged: false,
+			capAdd:     []string{"SYS_ADMIN"},
+		},
+	}, nil
+}
+
+func init() {
+	RegisterHandler(HandlerPodman, func() (Interface, error) {
+		return NewPodman()
+	})
+}
+
+// getPodmanHost returns the address of the Podman API socket.
+func getPodmanHost() string {
+	// explicitly configured
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	// rootless socket
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	socketPath := filepath.Join(runtimeDir, "podman", "podman.sock")
+	if _, err := os.Stat(socketPath); err == nil {
+		return "unix://" + socketPath
+	}
+	// rootful socket
+	return "unix:///run/podman/podman.sock"
+}
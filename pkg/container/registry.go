@@ -0,0 +1,84 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package container
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// HandlerDocker is the name of the Docker container handler.
+	HandlerDocker = "docker"
+	// HandlerPodman is the name of the Podman container handler.
+	HandlerPodman = "podman"
+	// HandlerDummy is the name of the dummy container handler.
+	HandlerDummy = "dummy"
+)
+
+// DefaultHandler is the name of the container handler used when none is configured.
+const DefaultHandler = HandlerDocker
+
+// HandlerFactory creates a new container handler.
+type HandlerFactory func() (Interface, error)
+
+var handlers = map[string]HandlerFactory{}
+var handlersLock sync.Mutex
+
+// RegisterHandler makes a container handler available under the given name.
+func RegisterHandler(name string, f HandlerFactory) {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+	handlers[name] = f
+}
+
+// HasHandler returns true if a container handler is registered under the given name.
+func HasHandler(name string) bool {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+	return handlers[name] != nil
+}
+
+// ListHandlers returns the names of all registered container handlers.
+func ListHandlers() []string {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+	out := make([]string, 0, len(handlers))
+	for name := range handlers {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NewHandler creates the container handler registered under the given name.
+func NewHandler(name string) (Interface, error) {
+	if name == "" {
+		name = DefaultHandler
+	}
+	handlersLock.Lock()
+	f := handlers[name]
+	handlersLock.Unlock()
+	if f == nil {
+		return nil, errors.Wrapf(ErrHandlerNotFound, "container handler '%s' not found", name)
+	}
+	h, err := f()
+	return h, errors.WithStack(err)
+}
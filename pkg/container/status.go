@@ -17,6 +17,8 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 
 package container
 
+import "time"
+
 // Status defines container status.
 type Status struct {
 	ID           string              `json:"id"`
@@ -31,4 +33,7 @@ type Status struct {
 	IPAddress    string              `json:"ip_address"`
 	Slot         int                 `json:"slot"`
 	HasContainer bool                `json:"has_container"`
+	Xdebug       bool                `json:"xdebug"`
+	Healthy      bool                `json:"healthy"`
+	LastCheck    time.Time           `json:"last_check"`
 }
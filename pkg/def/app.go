@@ -47,6 +47,7 @@ type App struct {
 	Dependencies  AppDependencies       `yaml:"dependencies"`
 	Runtime       AppRuntime            `yaml:"runtime"`
 	Workers       map[string]*AppWorker `yaml:"workers" json:"workers"`
+	Sanitize      []*AppSanitizeRule    `yaml:"sanitize" json:"sanitize"`
 }
 
 // SetDefaults sets the default values.
@@ -74,6 +75,9 @@ func (d *App) SetDefaults() {
 	}
 	d.Dependencies.SetDefaults()
 	d.Runtime.SetDefaults()
+	for i := range d.Sanitize {
+		d.Sanitize[i].SetDefaults()
+	}
 }
 
 // Validate checks for errors.
@@ -117,6 +121,11 @@ func (d App) Validate() []error {
 	if e := d.Runtime.Validate(&d); len(e) > 0 {
 		o = append(o, e...)
 	}
+	for _, r := range d.Sanitize {
+		if e := r.Validate(&d); len(e) > 0 {
+			o = append(o, e...)
+		}
+	}
 	for _, key := range []string{"env", "php"} {
 		val := d.Variables.GetSubMap(key)
 		if val == nil {
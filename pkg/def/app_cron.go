@@ -17,6 +17,18 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 
 package def
 
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser parses Platform.sh cron specs, five fields or a descriptor such as @hourly.
+var cronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
 // AppCron defines a cron job.
 type AppCron struct {
 	Spec    string `yaml:"spec" json:"spec"`
@@ -30,17 +42,26 @@ func (d *AppCron) SetDefaults() {
 	}
 }
 
+// Schedule parses the cron spec and returns its schedule.
+func (d AppCron) Schedule() (cron.Schedule, error) {
+	s, err := cronParser.Parse(d.Spec)
+	return s, errors.WithStack(err)
+}
+
 // Validate checks for errors.
 func (d AppCron) Validate(root *App) []error {
 	o := make([]error, 0)
-	// TODO fix cron parser
-	/*p := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	if _, e := p.Parse(d.Spec); e != nil {
-		log.Println(d.Spec)
+	if _, e := cronParser.Parse(d.Spec); e != nil {
 		o = append(o, NewValidateError(
-			"app.cron[].spec",
+			fmt.Sprintf("app.%s.crons[].spec", root.Name),
 			e.Error(),
 		))
-	}*/
+	}
+	if d.Command == "" {
+		o = append(o, NewValidateError(
+			fmt.Sprintf("app.%s.crons[].cmd", root.Name),
+			"must not be empty",
+		))
+	}
 	return o
 }
@@ -0,0 +1,134 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package def
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SanitizeActionSQL runs the given SQL statement.
+	SanitizeActionSQL = "sql"
+	// SanitizeActionHash replaces the column values with a hash of the value.
+	SanitizeActionHash = "hash"
+	// SanitizeActionEmail replaces the column values with an email address made from a hash of the value.
+	SanitizeActionEmail = "email"
+	// SanitizeActionNull sets the column values to null.
+	SanitizeActionNull = "null"
+	// SanitizeActionSet sets the column values to the given value.
+	SanitizeActionSet = "set"
+	// SanitizeActionTruncate deletes all rows of the table.
+	SanitizeActionTruncate = "truncate"
+)
+
+// AppSanitizeRule defines a rule that sanitizes database data synced from Platform.sh.
+type AppSanitizeRule struct {
+	Service  string `yaml:"service" json:"service,omitempty"`
+	Database string `yaml:"database" json:"database,omitempty"`
+	SQL      string `yaml:"sql" json:"sql,omitempty"`
+	Table    string `yaml:"table" json:"table,omitempty"`
+	Column   string `yaml:"column" json:"column,omitempty"`
+	Action   string `yaml:"action" json:"action"`
+	Value    string `yaml:"value" json:"value,omitempty"`
+	Where    string `yaml:"where" json:"where,omitempty"`
+}
+
+// SetDefaults sets the default values.
+func (d *AppSanitizeRule) SetDefaults() {
+	if d.Action == "" && d.SQL != "" {
+		d.Action = SanitizeActionSQL
+	}
+}
+
+// String returns a short description of the rule.
+func (d AppSanitizeRule) String() string {
+	switch d.Action {
+	case SanitizeActionSQL:
+		{
+			return d.SQL
+		}
+	case SanitizeActionTruncate:
+		{
+			return fmt.Sprintf("%s %s", d.Action, d.Table)
+		}
+	}
+	return fmt.Sprintf("%s %s.%s", d.Action, d.Table, d.Column)
+}
+
+// Validate checks for errors.
+func (d AppSanitizeRule) Validate(root *App) []error {
+	o := make([]error, 0)
+	key := "sanitize[]"
+	if root != nil {
+		key = fmt.Sprintf("app.%s.sanitize[]", root.Name)
+	}
+	if err := validateMustContainOne(
+		[]string{SanitizeActionSQL, SanitizeActionHash, SanitizeActionEmail, SanitizeActionNull, SanitizeActionSet, SanitizeActionTruncate},
+		d.Action,
+		key+".action",
+	); err != nil {
+		o = append(o, err)
+	}
+	switch d.Action {
+	case SanitizeActionSQL:
+		{
+			if d.SQL == "" {
+				o = append(o, NewValidateError(key+".sql", "must not be empty"))
+			}
+			break
+		}
+	case SanitizeActionTruncate:
+		{
+			if d.Table == "" {
+				o = append(o, NewValidateError(key+".table", "must not be empty"))
+			}
+			break
+		}
+	default:
+		{
+			if d.Table == "" {
+				o = append(o, NewValidateError(key+".table", "must not be empty"))
+			}
+			if d.Column == "" {
+				o = append(o, NewValidateError(key+".column", "must not be empty"))
+			}
+			break
+		}
+	}
+	return o
+}
+
+// ParseSanitizeYaml parses a YAML file containing a sanitize section with a list of rules.
+func ParseSanitizeYaml(d []byte) ([]*AppSanitizeRule, error) {
+	o := struct {
+		Sanitize []*AppSanitizeRule `yaml:"sanitize"`
+	}{}
+	if err := yaml.Unmarshal(d, &o); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, r := range o.Sanitize {
+		r.SetDefaults()
+		if errs := r.Validate(nil); len(errs) > 0 {
+			return nil, errors.WithStack(errs[0])
+		}
+	}
+	return o.Sanitize, nil
+}
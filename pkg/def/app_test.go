@@ -20,6 +20,7 @@ package def
 import (
 	"path"
 	"testing"
+	"time"
 )
 
 func TestParseFile(t *testing.T) {
@@ -46,7 +47,7 @@ func TestParseFile(t *testing.T) {
 }
 
 func TestInvalidCron(t *testing.T) {
-	_, e := ParseAppYamls([][]byte{[]byte(`
+	d, e := ParseAppYamls([][]byte{[]byte(`
 name: test_app_cron
 type: php:7.4
 crons:
@@ -57,10 +58,35 @@ crons:
 	if e != nil {
 		t.Errorf("failed to parse app yaml, %s", e)
 	}
-	/*if e := d.Validate(); len(e) == 0 {
-		// TODO fix cron parser
-		//t.Error("expected cron parse error")
-	}*/
+	if e := d.Validate(); len(e) == 0 {
+		t.Error("expected cron parse error")
+	}
+}
+
+func TestValidCron(t *testing.T) {
+	d, e := ParseAppYamls([][]byte{[]byte(`
+name: test_app_cron
+type: php:7.4
+crons:
+    every5:
+        spec: "*/5 * * * *"
+        cmd: "sleep 5"
+    hourly:
+        spec: "@hourly"
+        cmd: "sleep 5"
+`)}, nil)
+	if e != nil {
+		t.Errorf("failed to parse app yaml, %s", e)
+	}
+	if e := d.Validate(); len(e) > 0 {
+		t.Errorf("unexpected cron validation error, %s", e[0])
+	}
+	s, e := d.Crons["hourly"].Schedule()
+	if e != nil {
+		t.Errorf("failed to parse cron spec, %s", e)
+	}
+	now := time.Date(2021, 6, 1, 10, 15, 0, 0, time.UTC)
+	AssertEqual(s.Next(now), time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC), "unexpected next hourly run", t)
 }
 
 func TestInvalidMount(t *testing.T) {
@@ -234,3 +260,29 @@ workers:
 		t,
 	)
 }
+
+func TestSanitize(t *testing.T) {
+	d, e := ParseAppYamls([][]byte{[]byte(`
+name: test_app_sanitize
+type: php:7.4
+sanitize:
+    - sql: "DELETE FROM sessions"
+    - table: users
+      column: email
+      action: email
+    - table: users
+      action: hash
+`)}, nil)
+	if e != nil {
+		t.Errorf("failed to parse app yaml, %s", e)
+	}
+	AssertEqual(len(d.Sanitize), 3, "unexpected number of sanitize rules", t)
+	AssertEqual(d.Sanitize[0].Action, SanitizeActionSQL, "expected sql action when sql is set", t)
+	AssertEqual(d.Sanitize[1].String(), "email users.email", "unexpected sanitize rule description", t)
+	errs := d.Validate()
+	AssertEqual(len(errs), 1, "expected sanitize rule without column to fail validation", t)
+	AssertEqual(errs[0].(*ValidateError).key, "app.test_app_sanitize.sanitize[].column", "unexpected validation key", t)
+	if _, e := ParseSanitizeYaml([]byte("sanitize:\n    - table: users\n      action: scramble\n")); e == nil {
+		t.Error("expected invalid sanitize action to fail")
+	}
+}
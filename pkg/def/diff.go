@@ -0,0 +1,326 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package def
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// DiffAdded is a value that only exists in the new definitions.
+	DiffAdded = "added"
+	// DiffRemoved is a value that only exists in the old definitions.
+	DiffRemoved = "removed"
+	// DiffChanged is a value that exists in both definitions with a different value.
+	DiffChanged = "changed"
+)
+
+const (
+	// DiffTypeApp is the definition type of application changes.
+	DiffTypeApp = "app"
+	// DiffTypeService is the definition type of service changes.
+	DiffTypeService = "service"
+	// DiffTypeRoute is the definition type of route changes.
+	DiffTypeRoute = "route"
+)
+
+// Definitions is a set of parsed project definitions.
+type Definitions struct {
+	Apps     []App
+	Services []Service
+	Routes   []Route
+}
+
+// DiffChange defines a single change between two definitions.
+type DiffChange struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name"`
+	Path   string      `json:"path"`
+	Change string      `json:"change"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+// FullPath returns the path of the change prefixed with the definition type.
+func (c DiffChange) FullPath() string {
+	if c.Path == "" {
+		return c.Type
+	}
+	return c.Type + "." + c.Path
+}
+
+// Diff compares two sets of definitions and returns all changes. Apps and services are
+// matched by name and routes by their url. Only fields defined in yaml are compared.
+func Diff(old Definitions, new Definitions) []DiffChange {
+	out := make([]DiffChange, 0)
+	oldApps := map[string]App{}
+	for _, app := range old.Apps {
+		oldApps[app.Name] = app
+	}
+	newApps := map[string]App{}
+	for _, app := range new.Apps {
+		newApps[app.Name] = app
+	}
+	out = append(out, diffNamed(DiffTypeApp, reflect.ValueOf(oldApps), reflect.ValueOf(newApps))...)
+	oldServices := map[string]Service{}
+	for _, service := range old.Services {
+		oldServices[service.Name] = service
+	}
+	newServices := map[string]Service{}
+	for _, service := range new.Services {
+		newServices[service.Name] = service
+	}
+	out = append(out, diffNamed(DiffTypeService, reflect.ValueOf(oldServices), reflect.ValueOf(newServices))...)
+	oldRoutes := map[string]Route{}
+	for _, route := range old.Routes {
+		oldRoutes[route.Path] = route
+	}
+	newRoutes := map[string]Route{}
+	for _, route := range new.Routes {
+		newRoutes[route.Path] = route
+	}
+	out = append(out, diffNamed(DiffTypeRoute, reflect.ValueOf(oldRoutes), reflect.ValueOf(newRoutes))...)
+	return out
+}
+
+// DiffValue compares two values of the same type and returns all changes with paths relative to the values.
+func DiffValue(old interface{}, new interface{}) []DiffChange {
+	return diffValue("", reflect.ValueOf(old), reflect.ValueOf(new))
+}
+
+// diffNamed compares two maps of named definitions of given type.
+func diffNamed(defType string, old reflect.Value, new reflect.Value) []DiffChange {
+	out := make([]DiffChange, 0)
+	for _, key := range diffMapKeys(old, new) {
+		name := fmt.Sprint(key.Interface())
+		for _, c := range diffValue("", old.MapIndex(key), new.MapIndex(key)) {
+			c.Type = defType
+			c.Name = name
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func diffValue(path string, old reflect.Value, new reflect.Value) []DiffChange {
+	switch {
+	case !diffValid(old) && !diffValid(new):
+		{
+			return nil
+		}
+	case !diffValid(old):
+		{
+			return []DiffChange{{Path: path, Change: DiffAdded, New: diffInterface(new)}}
+		}
+	case !diffValid(new):
+		{
+			return []DiffChange{{Path: path, Change: DiffRemoved, Old: diffInterface(old)}}
+		}
+	}
+	if old.Kind() == reflect.Interface || old.Kind() == reflect.Ptr {
+		return diffValue(path, old.Elem(), new.Elem())
+	}
+	if old.Type() != new.Type() {
+		return diffChanged(path, old, new)
+	}
+	// values that define their own json encoding are compared as a whole
+	if oldMarshaler, ok := diffMarshaler(old); ok {
+		newMarshaler, _ := diffMarshaler(new)
+		oldJSON, _ := json.Marshal(oldMarshaler)
+		newJSON, _ := json.Marshal(newMarshaler)
+		if string(oldJSON) != string(newJSON) {
+			return diffChanged(path, old, new)
+		}
+		return nil
+	}
+	out := make([]DiffChange, 0)
+	switch old.Kind() {
+	case reflect.Struct:
+		{
+			for i := 0; i < old.NumField(); i++ {
+				field := old.Type().Field(i)
+				name, inline := diffFieldName(field)
+				if name == "" && !inline {
+					continue
+				}
+				fieldPath := diffPath(path, name)
+				if inline {
+					fieldPath = path
+				}
+				out = append(out, diffValue(fieldPath, old.Field(i), new.Field(i))...)
+			}
+			break
+		}
+	case reflect.Map:
+		{
+			for _, key := range diffMapKeys(old, new) {
+				out = append(out, diffValue(
+					diffPath(path, fmt.Sprint(key.Interface())), old.MapIndex(key), new.MapIndex(key),
+				)...)
+			}
+			break
+		}
+	case reflect.Slice, reflect.Array:
+		{
+			for i := 0; i < old.Len() || i < new.Len(); i++ {
+				var oldItem, newItem reflect.Value
+				if i < old.Len() {
+					oldItem = old.Index(i)
+				}
+				if i < new.Len() {
+					newItem = new.Index(i)
+				}
+				out = append(out, diffValue(diffPath(path, fmt.Sprint(i)), oldItem, newItem)...)
+			}
+			break
+		}
+	default:
+		{
+			if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+				return diffChanged(path, old, new)
+			}
+		}
+	}
+	return out
+}
+
+func diffChanged(path string, old reflect.Value, new reflect.Value) []DiffChange {
+	return []DiffChange{{Path: path, Change: DiffChanged, Old: diffInterface(old), New: diffInterface(new)}}
+}
+
+// diffValid returns true if given value holds a non nil value.
+func diffValid(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		{
+			return !v.IsNil()
+		}
+	case reflect.Map, reflect.Slice:
+		{
+			return v.Len() > 0
+		}
+	}
+	return true
+}
+
+// diffFieldName returns the yaml name of a struct field, empty if the field is not defined in yaml.
+func diffFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := field.Tag.Get("yaml")
+	if tag == "" || tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			return "", true
+		}
+	}
+	if parts[0] == "" {
+		return strings.ToLower(field.Name), false
+	}
+	return parts[0], false
+}
+
+// diffMapKeys returns the keys of both maps sorted by their string value.
+func diffMapKeys(old reflect.Value, new reflect.Value) []reflect.Value {
+	keys := map[string]reflect.Value{}
+	for _, m := range []reflect.Value{old, new} {
+		if !m.IsValid() {
+			continue
+		}
+		for _, key := range m.MapKeys() {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]reflect.Value, len(names))
+	for i, name := range names {
+		out[i] = keys[name]
+	}
+	return out
+}
+
+func diffPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// diffMarshaler returns v as a json.Marshaler if its type or a pointer to it defines its own json encoding.
+func diffMarshaler(v reflect.Value) (json.Marshaler, bool) {
+	if m, ok := v.Interface().(json.Marshaler); ok {
+		return m, true
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	m, ok := ptr.Interface().(json.Marshaler)
+	return m, ok
+}
+
+// diffInterface returns the value of v with yaml maps converted so it can be encoded as json.
+func diffInterface(v reflect.Value) interface{} {
+	if m, ok := diffMarshaler(v); ok {
+		return m
+	}
+	return diffJSONValue(v.Interface())
+}
+
+func diffJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		{
+			out := make(map[string]interface{}, len(v))
+			for k, vv := range v {
+				out[fmt.Sprint(k)] = diffJSONValue(vv)
+			}
+			return out
+		}
+	case map[string]interface{}:
+		{
+			out := make(map[string]interface{}, len(v))
+			for k, vv := range v {
+				out[k] = diffJSONValue(vv)
+			}
+			return out
+		}
+	case []interface{}:
+		{
+			out := make([]interface{}, len(v))
+			for i, vv := range v {
+				out[i] = diffJSONValue(vv)
+			}
+			return out
+		}
+	}
+	return v
+}
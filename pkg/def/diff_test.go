@@ -0,0 +1,69 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package def
+
+import (
+	"encoding/json"
+	"path"
+	"testing"
+)
+
+func TestDiffApp(t *testing.T) {
+	p := path.Join("_test_data", "sample1", ".platform.app.yaml")
+	old, err := ParseAppYamlFiles([]string{p}, nil)
+	if err != nil {
+		t.Errorf("failed to parse app yaml, %s", err)
+	}
+	new, err := ParseAppYamlFiles([]string{p}, nil)
+	if err != nil {
+		t.Errorf("failed to parse app yaml, %s", err)
+	}
+	AssertEqual(len(DiffValue(*old, *new)), 0, "expected no changes", t)
+	new.Web.Locations["/"].Passthru = BoolString{}
+	new.Disk = old.Disk + 1024
+	changes := DiffValue(*old, *new)
+	AssertEqual(len(changes), 2, "unexpected number of changes", t)
+	AssertEqual(changes[0].Path, "disk", "unexpected change path", t)
+	AssertEqual(changes[0].Change, DiffChanged, "unexpected change type", t)
+	AssertEqual(changes[1].Path, "web.locations./.passthru", "unexpected change path", t)
+	if _, err := json.Marshal(changes); err != nil {
+		t.Errorf("failed to encode changes, %s", err)
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	p := path.Join("_test_data", "route_override", ".platform")
+	old, err := ParseRoutesYamlFiles([]string{path.Join(p, "routes.yaml")})
+	if err != nil {
+		t.Errorf("failed to parse routes yaml, %s", err)
+	}
+	new, err := ParseRoutesYamlFiles([]string{path.Join(p, "routes.yaml"), path.Join(p, "routes.pcc.yaml")})
+	if err != nil {
+		t.Errorf("failed to parse routes yaml, %s", err)
+	}
+	changes := Diff(Definitions{Routes: old}, Definitions{Routes: new})
+	if len(changes) == 0 {
+		t.Errorf("expected route changes")
+	}
+	for _, c := range changes {
+		AssertEqual(c.Type, DiffTypeRoute, "unexpected definition type", t)
+		if c.Name == "" {
+			t.Errorf("expected route url as change name")
+		}
+	}
+}
@@ -0,0 +1,27 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package def
+
+import "github.com/pkg/errors"
+
+var (
+	// ErrUnsupportedVariablesFormat is an error returned when a variables file format is not supported.
+	ErrUnsupportedVariablesFormat = errors.New("unsupported variables format")
+	// ErrInvalidDotEnv is an error returned when a dotenv file can not be parsed.
+	ErrInvalidDotEnv = errors.New("invalid dotenv")
+)
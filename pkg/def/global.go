@@ -25,6 +25,7 @@ type GlobalConfig struct {
 	Router    struct {
 		PortHTTP  uint16 `yaml:"port_http" json:"port_http"`
 		PortHTTPS uint16 `yaml:"port_https" json:"port_https"`
+		Mode      string `yaml:"mode" json:"mode"`
 	} `yaml:"router" json:"router"`
 }
 
@@ -36,6 +37,9 @@ func (d *GlobalConfig) SetDefaults() {
 	if d.Router.PortHTTPS == 0 {
 		d.Router.PortHTTPS = 443
 	}
+	if d.Router.Mode == "" {
+		d.Router.Mode = "nginx"
+	}
 }
 
 // Validate checks for errors.
@@ -73,5 +77,12 @@ func (d GlobalConfig) Validate() []error {
 			}
 		}
 	}
+	if err := validateMustContainOne(
+		[]string{"nginx", "native"},
+		d.Router.Mode,
+		"global.router.mode",
+	); err != nil {
+		o = append(o, err)
+	}
 	return o
 }
@@ -27,6 +27,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
@@ -56,6 +57,15 @@ func dirToTarGz(pathTo string) (bytes.Buffer, error) {
 			}
 			header.Name = strings.TrimLeft(strings.Replace(path, pathTo, "", 1), "/")
 			header.Name = strings.ReplaceAll(header.Name, "\\", "/")
+			// keep the archive the same for unchanged files, a git checkout or
+			// another user changes the times and owner but not the contents
+			header.ModTime = time.Unix(0, 0)
+			header.AccessTime = time.Time{}
+			header.ChangeTime = time.Time{}
+			header.Uid = 0
+			header.Gid = 0
+			header.Uname = ""
+			header.Gname = ""
 			err = tw.WriteHeader(header)
 			if err != nil {
 				return err
@@ -29,6 +29,12 @@ type RouteCache struct {
 func (d *RouteCache) SetDefaults() {
 	d.Enabled.DefaultValue = false
 	d.Enabled.SetDefaults()
+	if d.Headers == nil {
+		d.Headers = []string{"Accept", "Accept-Language"}
+	}
+	if d.Cookies == nil {
+		d.Cookies = []string{"*"}
+	}
 	if d.DefaultTTL == 0 {
 		d.DefaultTTL = 300
 	}
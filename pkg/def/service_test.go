@@ -18,9 +18,13 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 package def
 
 import (
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestServices(t *testing.T) {
@@ -106,3 +110,30 @@ func TestServices(t *testing.T) {
 
 	}
 }
+
+func TestArchiveUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	if e := os.MkdirAll(filepath.Join(dir, "solr"), 0755); e != nil {
+		t.Fatal(e)
+	}
+	p := filepath.Join(dir, "solr", "file1.txt")
+	if e := ioutil.WriteFile(p, []byte("test"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	defer func(d string) { projectPlatformDir = d }(projectPlatformDir)
+	projectPlatformDir = dir
+	a, e := dirToTarGzB64("solr")
+	if e != nil {
+		t.Fatal(e)
+	}
+	// a checkout changes the file times but not the contents
+	mtime := time.Now().Add(time.Hour)
+	if e := os.Chtimes(p, mtime, mtime); e != nil {
+		t.Fatal(e)
+	}
+	b, e := dirToTarGzB64("solr")
+	if e != nil {
+		t.Fatal(e)
+	}
+	AssertEqual(a, b, "expected archive of unchanged files to be the same", t)
+}
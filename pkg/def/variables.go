@@ -26,6 +26,18 @@ import (
 	"github.com/pkg/errors"
 )
 
+// SensitiveVariablePrefix marks a variable value as sensitive, the rest of the value is encrypted.
+const SensitiveVariablePrefix = "pcc:sensitive:"
+
+// SensitiveVariableMask replaces sensitive variable values in output.
+const SensitiveVariableMask = "********"
+
+// IsSensitiveVariable returns true if given variable value is marked as sensitive.
+func IsSensitiveVariable(value interface{}) bool {
+	v, ok := value.(string)
+	return ok && strings.HasPrefix(v, SensitiveVariablePrefix)
+}
+
 // Variables defines project variables which can be defined in multiple places.
 type Variables map[string]interface{}
 
@@ -68,6 +80,37 @@ func (v Variables) GetStringSubMap(name string) map[string]string {
 	return out
 }
 
+// IsSensitive returns true if given variable is sensitive.
+func (v Variables) IsSensitive(name string) bool {
+	return IsSensitiveVariable(v.Get(name))
+}
+
+// Masked returns a copy of the variables with sensitive values masked.
+func (v Variables) Masked() Variables {
+	out := make(Variables)
+	for k, val := range v {
+		if IsSensitiveVariable(val) {
+			val = SensitiveVariableMask
+		}
+		out[k] = val
+	}
+	return out
+}
+
+// WithoutSensitive returns a copy of the variables without sensitive values along with the left out keys.
+func (v Variables) WithoutSensitive() (Variables, []string) {
+	out := make(Variables)
+	omitted := make([]string, 0)
+	for _, k := range v.Keys() {
+		if IsSensitiveVariable(v[k]) {
+			omitted = append(omitted, k)
+			continue
+		}
+		out[k] = v[k]
+	}
+	return out, omitted
+}
+
 // Merge merges given variables with this one.
 func (v *Variables) Merge(m Variables) {
 	mergeMaps((*v), m)
@@ -0,0 +1,248 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package def
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// VariablesFormatEnv is the dotenv variables file format.
+	VariablesFormatEnv = "env"
+	// VariablesFormatJSON is the JSON variables file format.
+	VariablesFormatJSON = "json"
+	// VariablesFormatYAML is the YAML variables file format.
+	VariablesFormatYAML = "yaml"
+)
+
+// VariablesEnvPrefix is the variable prefix of environment variables.
+const VariablesEnvPrefix = "env:"
+
+var dotEnvNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// VariablesFormatFromPath returns the variables file format for given file path.
+func VariablesFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		{
+			return VariablesFormatJSON
+		}
+	case ".yaml", ".yml":
+		{
+			return VariablesFormatYAML
+		}
+	}
+	return VariablesFormatEnv
+}
+
+// ParseVariablesFile parses variables from given data in given format.
+// Prefix is prepended to every key without one, dotenv keys never have one.
+func ParseVariablesFile(d []byte, format string, prefix string) (Variables, error) {
+	out := make(Variables)
+	switch format {
+	case VariablesFormatJSON:
+		{
+			if err := json.Unmarshal(d, &out); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			break
+		}
+	case VariablesFormatYAML:
+		{
+			if err := yaml.Unmarshal(d, &out); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			break
+		}
+	case VariablesFormatEnv:
+		{
+			vars, err := parseDotEnv(d)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			for k, v := range vars {
+				out[k] = v
+			}
+			break
+		}
+	default:
+		{
+			return nil, errors.Wrapf(ErrUnsupportedVariablesFormat, "format '%s'", format)
+		}
+	}
+	if prefix == "" {
+		return out, nil
+	}
+	prefix = strings.TrimRight(prefix, ":") + ":"
+	prefixed := make(Variables)
+	for k, v := range out {
+		if !strings.Contains(k, ":") {
+			k = prefix + k
+		}
+		if err := prefixed.Set(k, v); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return prefixed, nil
+}
+
+// parseDotEnv parses dotenv lines in to a map.
+func parseDotEnv(d []byte) (map[string]string, error) {
+	out := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(d))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		lineSplit := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(lineSplit[0])
+		if len(lineSplit) != 2 || !dotEnvNameRegex.MatchString(name) {
+			return nil, errors.Wrapf(ErrInvalidDotEnv, "line %d", lineNo)
+		}
+		value, err := parseDotEnvValue(strings.TrimSpace(lineSplit[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNo)
+		}
+		out[name] = value
+	}
+	return out, errors.WithStack(scanner.Err())
+}
+
+// parseDotEnvValue unquotes a dotenv value.
+func parseDotEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	switch value[0] {
+	case '\'':
+		{
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return "", errors.Wrap(ErrInvalidDotEnv, "unterminated single quote")
+			}
+			return value[1 : end+1], nil
+		}
+	case '"':
+		{
+			out := strings.Builder{}
+			for i := 1; i < len(value); i++ {
+				switch value[i] {
+				case '"':
+					{
+						return out.String(), nil
+					}
+				case '\\':
+					{
+						i++
+						if i >= len(value) {
+							break
+						}
+						switch value[i] {
+						case 'n':
+							{
+								out.WriteByte('\n')
+								break
+							}
+						case 't':
+							{
+								out.WriteByte('\t')
+								break
+							}
+						default:
+							{
+								out.WriteByte(value[i])
+								break
+							}
+						}
+						break
+					}
+				default:
+					{
+						out.WriteByte(value[i])
+						break
+					}
+				}
+			}
+			return "", errors.Wrap(ErrInvalidDotEnv, "unterminated double quote")
+		}
+	}
+	// strip inline comment from unquoted value
+	if pos := strings.Index(value, " #"); pos >= 0 {
+		value = strings.TrimSpace(value[:pos])
+	}
+	return value, nil
+}
+
+// formatDotEnvValue quotes a dotenv value when needed.
+func formatDotEnvValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"'#\\$`") {
+		r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t")
+		return "\"" + r.Replace(value) + "\""
+	}
+	return value
+}
+
+// DotEnv returns variables with given prefix as dotenv lines, the prefix is removed from the names.
+func (v Variables) DotEnv(prefix string) string {
+	prefix = strings.TrimRight(prefix, ":") + ":"
+	out := strings.Builder{}
+	for _, k := range v.Keys() {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		out.WriteString(fmt.Sprintf("%s=%s\n", strings.TrimPrefix(k, prefix), formatDotEnvValue(v.GetString(k))))
+	}
+	return out.String()
+}
+
+// Export returns variables in given format.
+func (v Variables) Export(format string, prefix string) ([]byte, error) {
+	switch format {
+	case VariablesFormatEnv:
+		{
+			return []byte(v.DotEnv(prefix)), nil
+		}
+	case VariablesFormatJSON:
+		{
+			out, err := json.MarshalIndent(v, "", "  ")
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return append(out, '\n'), nil
+		}
+	case VariablesFormatYAML:
+		{
+			out, err := yaml.Marshal(map[string]interface{}(v))
+			return out, errors.WithStack(err)
+		}
+	}
+	return nil, errors.Wrapf(ErrUnsupportedVariablesFormat, "format '%s'", format)
+}
@@ -0,0 +1,105 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package def
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestVariablesDotEnv(t *testing.T) {
+	data := []byte(`# local overrides
+APP_ENV=dev
+export DATABASE_URL="mysql://user:p@ss#1@db/main"
+GREETING='hello world' 
+MULTILINE="line1\nline2"
+EMPTY=
+DEBUG=1 # inline comment
+`)
+	vars, err := ParseVariablesFile(data, VariablesFormatEnv, VariablesEnvPrefix)
+	if err != nil {
+		t.Fatalf("failed to parse dotenv, %s", err)
+	}
+	expected := map[string]string{
+		"env:APP_ENV":      "dev",
+		"env:DATABASE_URL": "mysql://user:p@ss#1@db/main",
+		"env:GREETING":     "hello world",
+		"env:MULTILINE":    "line1\nline2",
+		"env:EMPTY":        "",
+		"env:DEBUG":        "1",
+	}
+	AssertEqual(len(vars), len(expected), "unexpected number of variables", t)
+	for k, v := range expected {
+		AssertEqual(vars.GetString(k), v, "unexpected value for "+k, t)
+	}
+	// round trip
+	vars.Set("php:memory_limit", "512M")
+	exported, err := vars.Export(VariablesFormatEnv, VariablesEnvPrefix)
+	if err != nil {
+		t.Fatalf("failed to export dotenv, %s", err)
+	}
+	reimported, err := ParseVariablesFile(exported, VariablesFormatEnv, VariablesEnvPrefix)
+	if err != nil {
+		t.Fatalf("failed to parse exported dotenv, %s", err)
+	}
+	AssertEqual(len(reimported), len(expected), "unexpected number of exported variables", t)
+	for k, v := range expected {
+		AssertEqual(reimported.GetString(k), v, "unexpected exported value for "+k, t)
+	}
+	if _, err := ParseVariablesFile([]byte("NOT VALID"), VariablesFormatEnv, ""); !errors.Is(err, ErrInvalidDotEnv) {
+		t.Errorf("expected invalid dotenv line to fail")
+	}
+	if _, err := ParseVariablesFile([]byte("A='open"), VariablesFormatEnv, ""); !errors.Is(err, ErrInvalidDotEnv) {
+		t.Errorf("expected unterminated quote to fail")
+	}
+	if _, err := ParseVariablesFile([]byte("A=1"), "toml", ""); !errors.Is(err, ErrUnsupportedVariablesFormat) {
+		t.Errorf("expected unsupported format to fail")
+	}
+	// sensitive values are left out
+	vars.Set("env:API_SECRET", SensitiveVariablePrefix+"abc")
+	plain, omitted := vars.WithoutSensitive()
+	AssertEqual(len(omitted), 1, "expected one sensitive variable to be left out", t)
+	AssertEqual(omitted[0], "env:API_SECRET", "unexpected sensitive variable left out", t)
+	AssertEqual(plain.Get("env:API_SECRET"), nil, "expected sensitive variable to be left out", t)
+	AssertEqual(plain.GetString("env:APP_ENV"), "dev", "expected other variables to be kept", t)
+}
+
+func TestVariablesFileTypes(t *testing.T) {
+	for _, format := range []string{VariablesFormatJSON, VariablesFormatYAML} {
+		vars := Variables{
+			"env:APP_ENV":      "dev",
+			"php:memory_limit": "512M",
+			"feature:enabled":  true,
+			"feature:limit":    10,
+		}
+		out, err := vars.Export(format, VariablesEnvPrefix)
+		if err != nil {
+			t.Fatalf("failed to export %s, %s", format, err)
+		}
+		parsed, err := ParseVariablesFile(out, format, VariablesEnvPrefix)
+		if err != nil {
+			t.Fatalf("failed to parse %s, %s", format, err)
+		}
+		AssertEqual(parsed.Get("feature:enabled"), true, "expected bool to be preserved in "+format, t)
+		AssertEqual(fmt.Sprintf("%v", parsed.Get("feature:limit")), "10", "expected number to be preserved in "+format, t)
+		AssertEqual(parsed.GetString("env:APP_ENV"), "dev", "unexpected env:APP_ENV in "+format, t)
+	}
+	AssertEqual(VariablesFormatFromPath("vars.yml"), VariablesFormatYAML, "unexpected format for yml", t)
+	AssertEqual(VariablesFormatFromPath(".env"), VariablesFormatEnv, "unexpected format for .env", t)
+}
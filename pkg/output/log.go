@@ -21,6 +21,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -74,6 +75,21 @@ func LogWarn(msg string) {
 	writeLogFile("[WARN] " + msg)
 }
 
+// logWriter is an io.Writer that writes to the log file.
+type logWriter struct {
+	prefix string
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	writeLogFile("[OUTPUT] " + w.prefix + string(p))
+	return len(p), nil
+}
+
+// LogWriter returns a writer that writes command output to the log file with given prefix.
+func LogWriter(prefix string) io.Writer {
+	return logWriter{prefix: prefix}
+}
+
 // LogError writes error to log file.
 func LogError(err error) {
 	if err == nil {
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/term"
@@ -41,6 +42,9 @@ var Logging = false
 // IndentLevel is the current indentation level.
 var IndentLevel = 0
 
+// termLock serializes writes of terminal messages.
+var termLock sync.Mutex
+
 // IsTTY returns true if running with a TTY.
 func IsTTY() bool {
 	return term.IsTerminal(int(os.Stdin.Fd()))
@@ -57,7 +61,10 @@ func WriteStderr(msg string) {
 }
 
 func levelMsg(msg string) {
-	level := IndentLevel
+	levelMsgAt(IndentLevel, msg)
+}
+
+func levelMsgAt(level int, msg string) {
 	if level < 0 {
 		level = 0
 	}
@@ -73,6 +80,8 @@ func levelMsg(msg string) {
 			break
 		}
 	}
+	termLock.Lock()
+	defer termLock.Unlock()
 	WriteStderr(
 		strings.Repeat(levelSpacer, level) + msg + "\n",
 	)
@@ -93,20 +102,15 @@ func Warn(msg string) {
 	if !Enable {
 		return
 	}
-	IndentLevel++
-	levelMsg(colorWarn(msg))
-	IndentLevel--
+	levelMsgAt(IndentLevel+1, colorWarn(msg))
 }
 
 // Duration prints information and returns channel
 func Duration(msg string) func() {
-	start := time.Now()
 	if !Enable {
-		return func() {
-			dur := time.Since(start)
-			LogInfo(msg + fmt.Sprintf(" (%dms).", dur.Milliseconds()))
-		}
+		return LogDuration(msg)
 	}
+	start := time.Now()
 	levelMsg(msg)
 	IndentLevel++
 	done := func() {
@@ -120,6 +124,15 @@ func Duration(msg string) func() {
 	return done
 }
 
+// LogDuration writes the duration of a task to the log file only and returns function to call when done.
+func LogDuration(msg string) func() {
+	start := time.Now()
+	return func() {
+		dur := time.Since(start)
+		LogInfo(msg + fmt.Sprintf(" (%dms).", dur.Milliseconds()))
+	}
+}
+
 // ContainerLog prints container log line to stdout.
 func ContainerLog(name string, msg string) {
 	WriteStdout(colorSuccess(fmt.Sprintf("[%s] ", name)) + msg + "\n")
@@ -90,7 +90,7 @@ func progressPadWidth(msgs []string, index int) int {
 	return padWidth
 }
 
-func progressPrint(msgs []string, states []ProgressMessageState, cur []*int64, total []*int64, index int) {
+func progressPrint(level int, msgs []string, states []ProgressMessageState, cur []*int64, total []*int64, index int) {
 	msg := msgs[index]
 	state := states[index]
 	padWidth := progressPadWidth(msgs, index)
@@ -102,27 +102,26 @@ func progressPrint(msgs []string, states []ProgressMessageState, cur []*int64, t
 		}
 	}
 	// print
-	levelMsg(
-		msg + strings.Repeat(progressPadChar, padWidth) +
-			state.String() + percentProg,
+	levelMsgAt(
+		level,
+		msg+strings.Repeat(progressPadChar, padWidth)+
+			state.String()+percentProg,
 	)
 }
 
-func progressPrintAll(msgs []string, states []ProgressMessageState, cur []*int64, total []*int64) {
-	if !IsTTY() {
-		return
-	}
+func progressPrintAll(level int, msgs []string, states []ProgressMessageState, cur []*int64, total []*int64) {
 	for i := range msgs {
-		progressPrint(msgs, states, cur, total, i)
+		progressPrint(level, msgs, states, cur, total, i)
 	}
 }
 
-func progressReprint(msgs []string, states []ProgressMessageState, cur []*int64, total []*int64) {
+func progressReprint(level int, msgs []string, states []ProgressMessageState, cur []*int64, total []*int64) {
 	WriteStdout(fmt.Sprintf("\033[%dA", len(msgs)))
-	progressPrintAll(msgs, states, cur, total)
+	progressPrintAll(level, msgs, states, cur, total)
 }
 
 // Progress prints progress messages with state and returns function that updates progress when called.
+// Without a TTY a single line is printed each time a message changes state.
 func Progress(msgs []string) func(i int, s ProgressMessageState, cur *int64, total *int64) {
 	states := make([]ProgressMessageState, len(msgs))
 	for i := range states {
@@ -130,24 +129,28 @@ func Progress(msgs []string) func(i int, s ProgressMessageState, cur *int64, tot
 	}
 	progCur := make([]*int64, len(msgs))
 	progTotal := make([]*int64, len(msgs))
-	startIndent := IndentLevel
-	progressPrintAll(msgs, states, progCur, progTotal)
+	level := IndentLevel
+	tty := IsTTY()
+	if tty {
+		progressPrintAll(level, msgs, states, progCur, progTotal)
+	}
 	wg := sync.Mutex{}
 	return func(i int, s ProgressMessageState, cur *int64, total *int64) {
-		if !IsTTY() {
-			return
-		}
 		wg.Lock()
 		defer wg.Unlock()
 		if i < 0 || i >= len(msgs) {
 			return
 		}
-		currentIndent := IndentLevel
-		IndentLevel = startIndent
+		changed := states[i] != s
 		states[i] = s
 		progCur[i] = cur
 		progTotal[i] = total
-		progressReprint(msgs, states, progCur, progTotal)
-		IndentLevel = currentIndent
+		if tty {
+			progressReprint(level, msgs, states, progCur, progTotal)
+			return
+		}
+		if changed {
+			progressPrint(level, msgs, states, make([]*int64, len(msgs)), make([]*int64, len(msgs)), i)
+		}
 	}
 }
@@ -118,6 +118,9 @@ func (p *Project) FetchEnvironments() error {
 	if err := p.request("/projects/"+p.ID+"/environments", nil, &p.Environments); err != nil {
 		return errors.WithStack(err)
 	}
+	if err := p.saveEnvironmentCache(); err != nil {
+		output.LogError(err)
+	}
 	return nil
 }
 
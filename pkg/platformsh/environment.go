@@ -21,16 +21,24 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
 )
 
+// environmentCacheDir is the directory in the config path holding the last fetched environments of each project.
+const environmentCacheDir = "psh_environments"
+
 // Environment defines a Platform.sh environment.
 type Environment struct {
 	Name         string `json:"name"`
 	Title        string `json:"title"`
 	IsMain       bool   `json:"is_main"`
+	Parent       string `json:"parent"`
 	Status       string `json:"status"`
 	MachineName  string `json:"machine_name"`
 	EdgeHostname string `json:"edge_hostname"`
@@ -57,10 +65,91 @@ func (p *Project) GetEnvironment(name string) *Environment {
 	return nil
 }
 
-// Variables returns list of variables for given platform.sh environment.
-func (p *Project) Variables(env *Environment, service string) (map[string]string, error) {
+// MainEnvironment returns the main (production) environment.
+func (p *Project) MainEnvironment() *Environment {
+	for i, e := range p.Environments {
+		if e.IsMain {
+			return &p.Environments[i]
+		}
+	}
+	for _, name := range []string{"main", "master"} {
+		if env := p.GetEnvironment(name); env != nil {
+			return env
+		}
+	}
+	if len(p.Environments) > 0 {
+		return &p.Environments[0]
+	}
+	return nil
+}
+
+// BranchEnvironment returns the environment matching given Git branch, nil if there is none.
+// Inactive environments are substituted with their closest active parent.
+func (p *Project) BranchEnvironment(branch string) *Environment {
+	env := p.GetEnvironment(branch)
+	visited := map[string]bool{}
+	for env != nil && !env.IsActive() && !visited[env.Name] {
+		visited[env.Name] = true
+		env = p.GetEnvironment(env.Parent)
+	}
+	if env == nil || !env.IsActive() {
+		return nil
+	}
+	return env
+}
+
+// MapBranch returns the environment matching given Git branch.
+// Branches without an environment fall back to the main environment.
+func (p *Project) MapBranch(branch string) *Environment {
+	if env := p.BranchEnvironment(branch); env != nil {
+		return env
+	}
+	return p.MainEnvironment()
+}
+
+// environmentCachePath returns the path to the environment cache of the project.
+func (p *Project) environmentCachePath() string {
+	return filepath.Join(config.Path(), environmentCacheDir, p.ID+".json")
+}
+
+// saveEnvironmentCache stores the fetched environments for use without the API.
+func (p *Project) saveEnvironmentCache() error {
+	out, err := json.Marshal(p.Environments)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.environmentCachePath()), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(ioutil.WriteFile(p.environmentCachePath(), out, 0644))
+}
+
+// CachedEnvironments returns the environments stored by the last API fetch without calling the API.
+// An empty list is returned if the environments were never fetched.
+func (p *Project) CachedEnvironments() ([]Environment, error) {
+	out := make([]Environment, 0)
+	rawData, err := ioutil.ReadFile(p.environmentCachePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(rawData, &out); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+// IsActive returns true if the environment is deployed.
+func (e Environment) IsActive() bool {
+	return e.Status != "inactive" && e.Status != "deleting"
+}
+
+// Variables returns list of variables for given platform.sh environment along with the names of the sensitive ones.
+func (p *Project) Variables(env *Environment, service string) (map[string]string, map[string]bool, error) {
 	if env == nil {
-		return nil, errors.WithStack(ErrInvalidEnvironment)
+		return nil, nil, errors.WithStack(ErrInvalidEnvironment)
 	}
 	// fetch project variables
 	projVarResp := make([]map[string]interface{}, 0)
@@ -76,10 +165,11 @@ func (p *Project) Variables(env *Environment, service string) (map[string]string
 		nil,
 		&envVarResp,
 	); err != nil {
-		return nil, errors.WithStack(err)
+		return nil, nil, errors.WithStack(err)
 	}
 	// compile output
 	out := make(map[string]string)
+	sensitive := make(map[string]bool)
 	for _, v := range append(projVarResp, envVarResp...) {
 		if v["name"] == nil {
 			continue
@@ -95,12 +185,15 @@ func (p *Project) Variables(env *Environment, service string) (map[string]string
 			var err error
 			value, err = p.EnvironmentVariable(env, service, envVarName)
 			if err != nil {
-				return nil, errors.WithStack(err)
+				return nil, nil, errors.WithStack(err)
 			}
 		}
+		if isSensitive, ok := v["is_sensitive"].(bool); ok && isSensitive {
+			sensitive[name] = true
+		}
 		out[name] = value
 	}
-	return out, nil
+	return out, sensitive, nil
 }
 
 // EnvironmentVariable returns the value of the given environment variable.
@@ -19,6 +19,7 @@ package platformsh
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -29,13 +30,67 @@ import (
 )
 
 const platformShGitPattern = `^([a-z0-9]{12,})@git\.(([a-z0-9\-]+\.)?platform\.sh):([a-z0-9]{12,})\.git$`
-const gitConfigPath = ".git/config"
+const gitPath = ".git"
+const gitConfigFile = "config"
+const gitHeadFile = "HEAD"
+const gitCommonDirFile = "commondir"
+const gitDirPrefix = "gitdir: "
+const gitHeadRefPrefix = "ref: refs/heads/"
+
+// gitDir returns the Git directory of the repository at given path.
+// Worktrees and submodules have a .git file pointing at their Git directory.
+func gitDir(path string) (string, error) {
+	dir := filepath.Join(path, gitPath)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if info.IsDir() {
+		return dir, nil
+	}
+	rawPointer, err := ioutil.ReadFile(dir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	pointer := strings.TrimSpace(string(rawPointer))
+	if !strings.HasPrefix(pointer, gitDirPrefix) {
+		return "", errors.Errorf("invalid git directory pointer in %s", dir)
+	}
+	dir = strings.TrimPrefix(pointer, gitDirPrefix)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(path, dir)
+	}
+	return dir, nil
+}
+
+// gitCommonDir returns the Git directory shared by all worktrees of the repository at given path.
+func gitCommonDir(path string) (string, error) {
+	dir, err := gitDir(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	rawCommonDir, err := ioutil.ReadFile(filepath.Join(dir, gitCommonDirFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dir, nil
+		}
+		return "", errors.WithStack(err)
+	}
+	commonDir := strings.TrimSpace(string(rawCommonDir))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(dir, commonDir)
+	}
+	return commonDir, nil
+}
 
 // parseProjectGit returns Platform.sh project id and host name from Git remote.
 func parseProjectGit(path string) (string, string, error) {
-	projGitConfigPath := filepath.Join(path, gitConfigPath)
+	dir, err := gitCommonDir(path)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
 	// load git config file
-	conf, err := ini.LoadFile(projGitConfigPath)
+	conf, err := ini.LoadFile(filepath.Join(dir, gitConfigFile))
 	if err != nil {
 		return "", "", errors.WithStack(err)
 	}
@@ -56,6 +111,24 @@ func parseProjectGit(path string) (string, string, error) {
 	return "", "", fmt.Errorf("platform.sh git remote url not found")
 }
 
+// GitBranch returns the name of the Git branch currently checked out at given path.
+// An empty string is returned when HEAD is detached.
+func GitBranch(path string) (string, error) {
+	dir, err := gitDir(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	rawHead, err := ioutil.ReadFile(filepath.Join(dir, gitHeadFile))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	head := strings.TrimSpace(string(rawHead))
+	if !strings.HasPrefix(head, gitHeadRefPrefix) {
+		return "", nil
+	}
+	return strings.TrimPrefix(head, gitHeadRefPrefix), nil
+}
+
 // FindRoot returns the root directory for the Platform.sh project.
 func FindRoot(path string) (string, error) {
 	path, err := filepath.Abs(path)
@@ -20,6 +20,7 @@ package platformsh
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -42,6 +43,8 @@ import (
 
 const sshAPIURL = "https://ssh.api.platform.sh/"
 const sshCertificateFile = "psh_ssh_cert"
+const sshDownloadChunkSize = 8 << 20
+const sshDownloadRetries = 3
 
 // sshCertificate defines ssh certificate storage for Platform.sh.
 type sshCertificate []byte
@@ -307,6 +310,143 @@ func (p *Project) SSHDownload(env *Environment, service string, path string) (st
 	return outPath, nil
 }
 
+// SSHUpload uploads the contents of given reader to given remote path.
+func (p *Project) SSHUpload(env *Environment, service string, path string, r io.Reader) error {
+	// open ssh connection
+	client, err := p.openSSH(env, service)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer client.Close()
+	// open sftp connection
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer sftpClient.Close()
+	sftpFile, err := sftpClient.Create(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer sftpFile.Close()
+	// upload
+	if _, err := sftpFile.ReadFrom(r); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SSHResumeDownload downloads given remote file of given size over SFTP.
+// The download is appended to the local file so that an interrupted download resumes
+// from the size of the local file. A single connection is used, it is only reopened
+// after an error. Progress is reported with the optional callback.
+func (p *Project) SSHResumeDownload(env *Environment, service string, remotePath string, localPath string, size int64, progress func(cur int64, total int64)) error {
+	var client *ssh.Client
+	var sftpClient *sftp.Client
+	closeClient := func() {
+		if sftpClient != nil {
+			sftpClient.Close()
+			sftpClient = nil
+		}
+		if client != nil {
+			client.Close()
+			client = nil
+		}
+	}
+	defer closeClient()
+	return resumeDownload(func() (io.ReadSeekCloser, error) {
+		closeClient()
+		var err error
+		if client, err = p.openSSH(env, service); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if sftpClient, err = sftp.NewClient(client); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f, err := sftpClient.Open(remotePath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return f, nil
+	}, remotePath, localPath, size, progress)
+}
+
+// resumeDownload appends the remote file returned by open to the local file from the size of the local file.
+// The remote file is opened again after an error, up to sshDownloadRetries times in a row.
+func resumeDownload(open func() (io.ReadSeekCloser, error), remotePath string, localPath string, size int64, progress func(cur int64, total int64)) error {
+	outFile, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer outFile.Close()
+	stat, err := outFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	offset := stat.Size()
+	if offset > size {
+		return errors.Errorf("local file '%s' is larger than remote file", localPath)
+	}
+	if offset > 0 {
+		output.LogInfo(fmt.Sprintf("Resume download of '%s' at %d/%d bytes.", remotePath, offset, size))
+	}
+	var in io.ReadSeekCloser
+	defer func() {
+		if in != nil {
+			in.Close()
+		}
+	}()
+	buf := make([]byte, sshDownloadChunkSize)
+	retries := 0
+	for offset < size {
+		if progress != nil {
+			progress(offset, size)
+		}
+		if in == nil {
+			if in, err = open(); err == nil {
+				if _, err = in.Seek(offset, io.SeekStart); err != nil {
+					in.Close()
+					in = nil
+				}
+			}
+		}
+		n := 0
+		if in != nil {
+			chunk := int64(len(buf))
+			if size-offset < chunk {
+				chunk = size - offset
+			}
+			n, err = in.Read(buf[:chunk])
+		}
+		if n > 0 {
+			if _, err := outFile.Write(buf[:n]); err != nil {
+				return errors.WithStack(err)
+			}
+			offset += int64(n)
+			retries = 0
+		}
+		if err == nil || offset >= size {
+			continue
+		}
+		if err == io.EOF {
+			err = errors.Errorf("no data received for '%s' at %d bytes", remotePath, offset)
+		}
+		retries++
+		if retries > sshDownloadRetries {
+			return errors.WithStack(err)
+		}
+		output.LogDebug(fmt.Sprintf("Retry download of '%s' at %d bytes.", remotePath, offset), err)
+		if in != nil {
+			in.Close()
+			in = nil
+		}
+	}
+	if progress != nil {
+		progress(offset, size)
+	}
+	return nil
+}
+
 // SSHTerminal creates an interactive SSH terminal.
 func (p *Project) SSHTerminal(env *Environment, service string) error {
 	output.Info(fmt.Sprintf("SSH in to Platform.sh environment %s-%s--%s.", p.ID, env.Name, service))
@@ -0,0 +1,103 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package platformsh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+// failingRemoteFile is a remote file whose connection is lost once a read reaches failAt.
+type failingRemoteFile struct {
+	*bytes.Reader
+	failAt int64
+}
+
+func (f *failingRemoteFile) Read(p []byte) (int, error) {
+	pos, _ := f.Seek(0, io.SeekCurrent)
+	if f.failAt > 0 && pos >= f.failAt {
+		return 0, fmt.Errorf("connection lost")
+	}
+	if f.failAt > 0 && int64(len(p)) > f.failAt-pos {
+		p = p[:f.failAt-pos]
+	}
+	return f.Reader.Read(p)
+}
+
+func (f *failingRemoteFile) Close() error {
+	return nil
+}
+
+func TestResumeDownload(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	localPath := filepath.Join(t.TempDir(), "dump.gz")
+	// a previous download was interrupted after 10 bytes
+	if e := ioutil.WriteFile(localPath, data[:10], 0600); e != nil {
+		t.Fatal(e)
+	}
+	offsets := make([]int64, 0)
+	opens := 0
+	open := func() (io.ReadSeekCloser, error) {
+		opens++
+		f := &failingRemoteFile{Reader: bytes.NewReader(data)}
+		// the first connection is lost after 40 bytes
+		if opens == 1 {
+			f.failAt = 40
+		}
+		return &seekRecorder{f, &offsets}, nil
+	}
+	if e := resumeDownload(open, "/tmp/dump.gz", localPath, int64(len(data)), nil); e != nil {
+		t.Fatalf("failed to download, %s", e)
+	}
+	out, e := ioutil.ReadFile(localPath)
+	if e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(string(out), string(data), "unexpected downloaded data", t)
+	def.AssertEqual(opens, 2, "expected the remote file to be opened again after the error only", t)
+	def.AssertEqual(fmt.Sprintf("%v", offsets), "[10 40]", "expected download to resume at the local file size", t)
+	// give up when the connection keeps failing
+	opens = 0
+	failOpen := func() (io.ReadSeekCloser, error) {
+		opens++
+		return nil, fmt.Errorf("connection refused")
+	}
+	if e := resumeDownload(failOpen, "/tmp/dump.gz", filepath.Join(t.TempDir(), "dump.gz"), int64(len(data)), nil); e == nil {
+		t.Errorf("expected download to fail")
+	}
+	def.AssertEqual(opens, sshDownloadRetries+1, "unexpected number of retries", t)
+}
+
+// seekRecorder records the offsets a remote file is opened at.
+type seekRecorder struct {
+	*failingRemoteFile
+	offsets *[]int64
+}
+
+func (s *seekRecorder) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		*s.offsets = append(*s.offsets, offset)
+	}
+	return s.failingRemoteFile.Seek(offset, whence)
+}
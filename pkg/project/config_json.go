@@ -82,9 +82,9 @@ func (p *Project) BuildConfigJSON(d interface{}) ([]byte, error) {
 			"privileged_digest": "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
 			"environment_info": map[string]interface{}{
 				"is_production": false,
-				"machine_name":  "pcc-1",
-				"name":          "pcc",
-				"reference":     "refs/heads/pcc",
+				"machine_name":  p.GetPlatformEnvironment(),
+				"name":          p.GetPlatformBranch(),
+				"reference":     "refs/heads/" + p.GetPlatformBranch(),
 				"is_main":       false,
 			},
 			"project_info": map[string]interface{}{
@@ -163,7 +163,8 @@ func (p *Project) buildConfigAppJSON(d interface{}) map[string]interface{} {
 	case def.App:
 		{
 			name = d.Name
-			if p.HasFlag(EnableCron) {
+			// the container runs the crons unless the cron scheduler does
+			if p.HasFlag(EnableCron) && !p.HasFlag(EnableCronScheduler) {
 				crons = d.Crons
 			}
 			appType = d.Type
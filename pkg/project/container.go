@@ -28,7 +28,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
 
@@ -53,6 +52,8 @@ type Container struct {
 	postBuildPatchCommand string
 	mountStrategy         string
 	postDeployCommand     string
+	stdout                io.Writer
+	quiet                 bool // when true only write output to the log file
 }
 
 // NewContainer creates a new container.
@@ -84,26 +85,53 @@ func (p *Project) NewContainer(d interface{}) Container {
 		postBuildPatchCommand: p.GetDefinitionPostBuildPatch(d),
 		mountStrategy:         p.GetOption(OptionMountStrategy),
 		postDeployCommand:     p.GetDefinitionPostDeployCommand(d),
+		stdout:                os.Stdout,
 	}
 	return o
 }
 
+// duration prints a message and returns function to call when done, quiet containers only write to the log file.
+func (c Container) duration(msg string) func() {
+	if c.quiet {
+		return output.LogDuration(msg)
+	}
+	return output.Duration(msg)
+}
+
+// info prints information, quiet containers only write to the log file.
+func (c Container) info(msg string) {
+	if c.quiet {
+		output.LogInfo(msg)
+		return
+	}
+	output.Info(msg)
+}
+
+// warn prints a warning, quiet containers only write to the log file.
+func (c Container) warn(msg string) {
+	if c.quiet {
+		output.LogWarn(msg)
+		return
+	}
+	output.Warn(msg)
+}
+
 // Start starts the container.
 func (c Container) Start() error {
-	done := output.Duration(
-		fmt.Sprintf("Start %s '%s.'", c.Config.ObjectType.TypeName(), c.Name),
-	)
 	// ensure container isn't already running
 	containerStatus, _ := c.containerHandler.ContainerStatus(c.Config.GetContainerName())
 	if containerStatus.Running {
 		return errors.WithStack(ErrContainerRunning)
 	}
+	done := c.duration(
+		fmt.Sprintf("Start %s '%s.'", c.Config.ObjectType.TypeName(), c.Name),
+	)
 	// start container
 	if err := c.containerHandler.ContainerStart(c.Config); err != nil {
 		return errors.WithStack(err)
 	}
 	// upload config.json
-	d2 := output.Duration("Upload config.json.")
+	d2 := c.duration("Upload config.json.")
 	if err := c.Upload(
 		"/config.json",
 		bytes.NewReader(c.configJSON),
@@ -113,7 +141,7 @@ func (c Container) Start() error {
 	d2()
 	// patch
 	if c.patchCommand != "" {
-		d2 = output.Duration("Patch container.")
+		d2 = c.duration("Patch container.")
 		if _, err := c.containerHandler.ContainerCommand(
 			c.Config.GetContainerName(),
 			"root",
@@ -125,12 +153,12 @@ func (c Container) Start() error {
 		d2()
 	}
 	// run init command
-	d2 = output.Duration("Init container.")
+	d2 = c.duration("Init container.")
 	if _, err := c.containerHandler.ContainerCommand(
 		c.Config.GetContainerName(),
 		"root",
 		[]string{"bash", "--login", "-c", c.initCommand},
-		os.Stdout,
+		c.stdout,
 	); err != nil {
 		return errors.WithStack(err)
 	}
@@ -139,26 +167,38 @@ func (c Container) Start() error {
 	return c.Log()
 }
 
+// Stop stops the container.
+func (c Container) Stop() error {
+	done := c.duration(
+		fmt.Sprintf("Stop %s '%s.'", c.Config.ObjectType.TypeName(), c.Name),
+	)
+	if err := c.containerHandler.ContainerStop(c.Config.GetContainerName()); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
 // Open opens the container and returns the relationships.
 func (c Container) Open() ([]map[string]interface{}, error) {
 	indentLevel := output.IndentLevel
-	done := output.Duration(
+	done := c.duration(
 		fmt.Sprintf("Open %s '%s.'", c.Config.ObjectType.TypeName(), c.Name),
 	)
 	// start service
-	d2 := output.Duration("Start service.")
+	d2 := c.duration("Start service.")
 	if _, err := c.containerHandler.ContainerCommand(
 		c.Config.GetContainerName(),
 		"root",
 		[]string{"bash", "--login", "-c", serviceStartCmd},
-		os.Stdout,
+		c.stdout,
 	); err != nil {
-		output.Warn(err.Error())
+		c.warn(err.Error())
 		//return nil, errors.WithStack(err)
 	}
 	d2()
 	// prepare relationships json
-	d2 = output.Duration("Parse relationships.")
+	d2 = c.duration("Parse relationships.")
 	relJSONData := map[string]interface{}{
 		"relationships": c.Relationships,
 	}
@@ -173,7 +213,7 @@ func (c Container) Open() ([]map[string]interface{}, error) {
 		return nil, errors.WithStack(err)
 	}
 	// open service and retrieve relationships
-	d2 = output.Duration("Open service.")
+	d2 = c.duration("Open service.")
 	var openOutput bytes.Buffer
 	cmd := fmt.Sprintf(serviceOpenCmd, relB64)
 	if _, err := c.containerHandler.ContainerCommand(
@@ -187,7 +227,7 @@ func (c Container) Open() ([]map[string]interface{}, error) {
 
 	d2()
 	// process output relationships
-	d2 = output.Duration("Build relationship.")
+	d2 = c.duration("Build relationship.")
 	openOutlineLines := bytes.Split(openOutput.Bytes(), []byte{'\n'})
 	rlRaw := openOutlineLines[len(openOutlineLines)-1]
 	data := make(map[string]interface{})
@@ -217,7 +257,9 @@ func (c Container) Open() ([]map[string]interface{}, error) {
 	}
 	d2()
 	done()
-	output.IndentLevel = indentLevel
+	if !c.quiet {
+		output.IndentLevel = indentLevel
+	}
 	return out, nil
 }
 
@@ -245,7 +287,7 @@ func (c Container) Build() error {
 		)
 		return nil
 	}
-	done := output.Duration(
+	done := c.duration(
 		fmt.Sprintf("Building %s '%s.'", c.Config.ObjectType.TypeName(), c.Name),
 	)
 	// run command
@@ -253,17 +295,17 @@ func (c Container) Build() error {
 		c.Config.GetContainerName(),
 		"root",
 		[]string{"bash", "--login", "-c", c.buildCommand},
-		os.Stdout,
+		c.stdout,
 	); err != nil {
 		if !errors.Is(err, container.ErrCommandExited) {
 			return errors.WithStack(err)
 		}
-		output.Warn("Build exited with non zero code.")
+		c.warn("Build exited with non zero code.")
 	}
 	done()
 	// post build patch
 	if c.postBuildPatchCommand != "" {
-		d2 := output.Duration("Post build patch.")
+		d2 := c.duration("Post build patch.")
 		if _, err := c.containerHandler.ContainerCommand(
 			c.Config.GetContainerName(),
 			"root",
@@ -282,7 +324,7 @@ func (c Container) SetupMounts() error {
 	if c.mountCommand == "" {
 		return nil
 	}
-	done := output.Duration(
+	done := c.duration(
 		fmt.Sprintf("Set up mounts for %s '%s' using '%s' strategy.", c.Config.ObjectType.TypeName(), c.Name, c.mountStrategy),
 	)
 	// run command
@@ -290,7 +332,7 @@ func (c Container) SetupMounts() error {
 		c.Config.GetContainerName(),
 		"root",
 		[]string{"sh", "-c", c.mountCommand},
-		os.Stdout,
+		c.stdout,
 	); err != nil {
 		return errors.WithStack(err)
 	}
@@ -300,19 +342,19 @@ func (c Container) SetupMounts() error {
 
 // Deploy runs the deploy hooks.
 func (c Container) Deploy() error {
-	done := output.Duration(
+	done := c.duration(
 		fmt.Sprintf("Running deploy hook for %s '%s.'", c.Config.ObjectType.TypeName(), c.Name),
 	)
 	if _, err := c.containerHandler.ContainerCommand(
 		c.Config.GetContainerName(),
 		"root",
 		[]string{"bash", "--login", "-c", appDeployCmd},
-		os.Stdout,
+		c.stdout,
 	); err != nil {
 		if !errors.Is(err, container.ErrCommandExited) {
 			return errors.WithStack(err)
 		}
-		output.Warn("Deploy exited with non zero code.")
+		c.warn("Deploy exited with non zero code.")
 	}
 	done()
 	return nil
@@ -323,19 +365,19 @@ func (c Container) PostDeploy() error {
 	if c.postDeployCommand == "" {
 		return nil
 	}
-	done := output.Duration(
+	done := c.duration(
 		fmt.Sprintf("Running post-deploy hook for %s '%s.'", c.Config.ObjectType.TypeName(), c.Name),
 	)
 	if _, err := c.containerHandler.ContainerCommand(
 		c.Config.GetContainerName(),
 		"root",
 		[]string{"bash", "--login", "-c", c.postDeployCommand},
-		os.Stdout,
+		c.stdout,
 	); err != nil {
 		if !errors.Is(err, container.ErrCommandExited) {
 			return errors.WithStack(err)
 		}
-		output.Warn("Post deploy exited with non zero code.")
+		c.warn("Post deploy exited with non zero code.")
 	}
 	done()
 	return nil
@@ -351,7 +393,7 @@ func (c Container) openEnableAuthentication() error {
 			if !serviceConfig.IsAuthenticationEnabled() {
 				return nil
 			}
-			done := output.Duration("Enable authentication.")
+			done := c.duration("Enable authentication.")
 			// build state json
 			currentState := getDefaultServiceState()
 			currentState.Image = c.Config.Images[0]
@@ -385,7 +427,7 @@ func (c Container) openEnableAuthentication() error {
 
 // Shell accesses the container shell.
 func (c Container) Shell(user string, cmd []string) (int, error) {
-	output.Info(
+	c.info(
 		fmt.Sprintf(
 			"Access shell for %s '%s.'",
 			c.Config.ObjectType.TypeName(),
@@ -408,7 +450,7 @@ func (c Container) Shell(user string, cmd []string) (int, error) {
 func (c Container) Log() error {
 	output.LogInfo(fmt.Sprintf("Read logs for container '%s.'", c.Config.GetContainerName()))
 	go func() {
-		out, err := c.containerHandler.ContainerLog(c.Config.GetContainerName(), true)
+		out, err := c.containerHandler.ContainerLog(c.Config.GetContainerName(), container.LogOptions{Follow: true})
 		if err != nil {
 			output.LogError(err)
 			return
@@ -427,44 +469,16 @@ func (c Container) Log() error {
 	return nil
 }
 
-// LogStdout dumps container log to stdout.
-func (c Container) LogStdout(follow bool) error {
-	output.LogInfo(fmt.Sprintf("Read logs for container '%s.'", c.Config.GetContainerName()))
-	// open logs
-	followOption := ""
-	if follow {
-		followOption = "-f"
-	}
-	errChan := make(chan error)
-	go func(err chan error) {
-		_, e := c.containerHandler.ContainerCommand(
-			c.Config.GetContainerName(), "root",
-			[]string{"sh", "-c", fmt.Sprintf("tail %s /var/log/*.log %s /var/log/*/*.log %s /tmp/*.log", followOption, followOption, followOption)},
-			os.Stdout,
-		)
-		err <- e
-	}(errChan)
-	// follow logs
-	if follow {
-		err := <-errChan
-		return errors.WithStack(err)
-	}
-	// wait a second for buffer to fill
-	select {
-	case err := <-errChan:
-		{
-			return errors.WithStack(err)
-		}
-	case <-time.After(time.Second):
-		{
-			return nil
-		}
-	}
-}
-
 // Commit commits the container.
 func (c Container) Commit() error {
-	return errors.WithStack(c.containerHandler.ContainerCommit(c.Config.GetContainerName()))
+	done := c.duration(
+		fmt.Sprintf("Commit container '%s.'", c.Config.GetContainerName()),
+	)
+	if err := c.containerHandler.ContainerCommit(c.Config.GetContainerName()); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
 }
 
 // DeleteCommit deletes the commit image.
@@ -472,7 +486,7 @@ func (c Container) DeleteCommit() error {
 	err := c.containerHandler.ContainerDeleteCommit(c.Config.GetContainerName())
 	if err != nil {
 		if errors.Is(err, container.ErrImageNotFound) {
-			output.Warn(err.Error())
+			c.warn(err.Error())
 			return nil
 		}
 		return errors.WithStack(err)
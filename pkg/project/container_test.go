@@ -19,10 +19,17 @@ package project
 
 import (
 	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
@@ -405,3 +412,368 @@ func TestUpload(t *testing.T) {
 	// TODO better testing?
 	def.AssertEqual(dc.HasUpload("/tmp"), true, "expected upload", t)
 }
+
+func TestXdebug(t *testing.T) {
+	ch := container.NewDummy()
+	p, e := LoadFromPath(path.Join("_test_data", "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	p.SetContainerHandler(ch)
+	p.Apps[0].Runtime.Xdebug.IdeKey = "TESTKEY"
+	// app must be running
+	if err := p.XdebugEnable(p.Apps[0]); err == nil {
+		t.Errorf("expected error when app container is not running")
+	}
+	p.Start()
+	p.Options[OptionXdebugClient] = "10.0.0.1:9000"
+	if err := p.XdebugEnable(p.Apps[0]); err != nil {
+		t.Errorf("failed to enable xdebug, %s", err)
+	}
+	dc := ch.GetContainer(p.NewContainer(p.Apps[0]).Config.GetContainerName())
+	def.AssertEqual(dc.CommandHistoryIndex("xdebug.idekey=$3") >= 0, true, "expected xdebug idekey argument", t)
+	def.AssertEqual(dc.CommandHistoryIndex(" bash 10.0.0.1 9000 TESTKEY") >= 0, true, "expected xdebug arguments", t)
+	def.AssertEqual(p.Status()[0].Xdebug, true, "expected xdebug status", t)
+	if err := p.XdebugDisable(p.Apps[0]); err != nil {
+		t.Errorf("failed to disable xdebug, %s", err)
+	}
+	def.AssertEqual(p.Status()[0].Xdebug, false, "expected xdebug to be disabled", t)
+}
+
+func TestDatabaseImport(t *testing.T) {
+	ch := container.NewDummy()
+	p, e := LoadFromPath(path.Join("_test_data", "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	p.SetContainerHandler(ch)
+	p.Start()
+	var service def.Service
+	for _, s := range p.Services {
+		if s.Name == "mysqldb" {
+			service = s
+		}
+	}
+	// gzip compressed sql dump
+	f, _ := ioutil.TempFile(os.TempDir(), "pcc-test-*.sql.gz")
+	defer os.Remove(f.Name())
+	gw := gzip.NewWriter(f)
+	gw.Write([]byte("SELECT 1;\n"))
+	gw.Close()
+	f.Close()
+	if err := p.DatabaseImport(service, "main", f.Name(), true); err != nil {
+		t.Errorf("failed to import database, %s", err)
+	}
+	dc := ch.GetContainer(p.NewContainer(service).Config.GetContainerName())
+	recreateIndex := dc.CommandHistoryIndex("DROP DATABASE IF EXISTS `main`")
+	importIndex := dc.CommandHistoryIndex("mysql --password=$(cat /mnt/data/.mysql-password) -Dmain")
+	def.AssertEqual(recreateIndex >= 0, true, "expected database recreate command", t)
+	def.AssertEqual(importIndex > recreateIndex, true, "expected database import command after recreate", t)
+	// pg custom format can't be imported in to mysql
+	pf, _ := ioutil.TempFile(os.TempDir(), "pcc-test-*.dump")
+	defer os.Remove(pf.Name())
+	pf.Write([]byte("PGDMP\x01\x0e"))
+	pf.Close()
+	if err := p.DatabaseImport(service, "main", pf.Name(), false); !errors.Is(err, ErrInvalidDatabaseFormat) {
+		t.Errorf("expected invalid database format error")
+	}
+}
+
+func TestDatabaseSnapshot(t *testing.T) {
+	ch := container.NewDummy()
+	p, e := LoadFromPath(path.Join("_test_data", "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	p.SetContainerHandler(ch)
+	p.Start()
+	var service def.Service
+	for _, s := range p.Services {
+		if s.Name == "mysqldb" {
+			service = s
+		}
+	}
+	if _, err := p.DatabaseSnapshotCreate(service, "../bad"); !errors.Is(err, ErrInvalidSnapshotName) {
+		t.Errorf("expected invalid snapshot name error")
+	}
+	// store snapshot as if it was created from the service
+	snapshot := DatabaseSnapshot{
+		Name:      "test-snapshot",
+		Service:   service.Name,
+		Type:      service.Type,
+		Format:    p.GetDatabaseDumpFormat(service),
+		Databases: []string{"main"},
+		Created:   time.Now(),
+	}
+	if err := p.writeDatabaseSnapshot(snapshot); err != nil {
+		t.Errorf("failed to write snapshot, %s", err)
+	}
+	defer p.DatabaseSnapshotDelete(service.Name, snapshot.Name)
+	f, _ := os.Create(filepath.Join(p.databaseSnapshotPath(service.Name, snapshot.Name), "0.gz"))
+	gw := gzip.NewWriter(f)
+	gw.Write([]byte("SELECT 1;\n"))
+	gw.Close()
+	f.Close()
+	if _, err := p.DatabaseSnapshotCreate(service, snapshot.Name); !errors.Is(err, ErrSnapshotExists) {
+		t.Errorf("expected snapshot exists error")
+	}
+	snapshots, err := p.DatabaseSnapshotList(service.Name)
+	if err != nil {
+		t.Errorf("failed to list snapshots, %s", err)
+	}
+	def.AssertEqual(len(snapshots), 1, "unexpected number of snapshots", t)
+	def.AssertEqual(snapshots[0].Type, service.Type, "unexpected snapshot type", t)
+	// restore in to service
+	if err := p.DatabaseSnapshotRestore(service, snapshot.Name); err != nil {
+		t.Errorf("failed to restore snapshot, %s", err)
+	}
+	dc := ch.GetContainer(p.NewContainer(service).Config.GetContainerName())
+	def.AssertEqual(dc.CommandHistoryIndex("DROP DATABASE IF EXISTS `main`") >= 0, true, "expected database recreate command", t)
+	def.AssertEqual(dc.CommandHistoryIndex("set -o pipefail; zcat /tmp/pcc-snapshot-test-snapshot-0.gz") >= 0, true, "expected import from temp path", t)
+	// restore in to same version with patch level
+	other := service
+	other.Type = "mysql:10.0.1"
+	if err := p.DatabaseSnapshotRestore(other, snapshot.Name); err != nil {
+		t.Errorf("failed to restore snapshot in to same minor version, %s", err)
+	}
+	// refuse restore in to other version or type
+	for _, serviceType := range []string{"mysql:10.1", "mysql:1.0", "mariadb:10.0"} {
+		other.Type = serviceType
+		if err := p.DatabaseSnapshotRestore(other, snapshot.Name); !errors.Is(err, ErrSnapshotMismatch) {
+			t.Errorf("expected snapshot mismatch error for %s", serviceType)
+		}
+	}
+	if err := p.DatabaseSnapshotDelete(service.Name, snapshot.Name); err != nil {
+		t.Errorf("failed to delete snapshot, %s", err)
+	}
+	if _, err := p.DatabaseSnapshotGet(service.Name, snapshot.Name); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected snapshot not found error")
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	ch := container.NewDummy()
+	p, e := LoadFromPath(path.Join("_test_data", "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	p.SetContainerHandler(ch)
+	p.Start()
+	for _, s := range p.Status() {
+		def.AssertEqual(s.Healthy, true, fmt.Sprintf("expected %s to be healthy", s.Name), t)
+		def.AssertEqual(s.LastCheck.IsZero(), false, fmt.Sprintf("expected %s to have last check time", s.Name), t)
+	}
+	for _, s := range p.Services {
+		if s.Name == "mysqldb" {
+			dc := ch.GetContainer(p.NewContainer(s).Config.GetContainerName())
+			def.AssertEqual(dc.CommandHistoryIndex("mysqladmin -h 127.0.0.1 ping") >= 0, true, "expected mysql readiness probe", t)
+		}
+	}
+	def.AssertEqual(healthCheckCommand(container.ObjectContainerWorker, "php:7.4"), "", "expected no probe for worker", t)
+	p.Stop()
+	for _, s := range p.Status() {
+		def.AssertEqual(s.Healthy, false, fmt.Sprintf("expected stopped %s to not be healthy", s.Name), t)
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	projectPath := path.Join("_test_data", "sample2")
+	p, e := LoadFromPath(projectPath, true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	ch := container.NewDummy()
+	p.SetContainerHandler(ch)
+	p.Start()
+	// all containers labeled with their human name
+	containers := p.LogContainers(nil)
+	def.AssertEqual(len(containers), len(p.Apps)+len(p.Services), "wrong number of log containers", t)
+	entries := make([]LogEntry, 0)
+	if err := StreamLogs(containers, LogOptions{}, func(e LogEntry) error {
+		entries = append(entries, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(len(entries), len(containers), "wrong number of log entries", t)
+	for i, e := range entries {
+		def.AssertEqual(e.Message, "hello world", "unexpected log message", t)
+		if i > 0 && e.Time.Before(entries[i-1].Time) {
+			t.Errorf("expected log entries to be ordered by time")
+		}
+	}
+	// filter by service
+	containers = p.LogContainers([]string{"app/" + p.Apps[0].Name})
+	def.AssertEqual(len(containers), 1, "expected a single log container", t)
+	def.AssertEqual(containers[0].Config.GetHumanName(), "app/"+p.Apps[0].Name, "unexpected log container", t)
+	// filter by grep and since
+	count := 0
+	for _, opts := range []LogOptions{
+		{Grep: regexp.MustCompile("^nothing")},
+		{Since: time.Now()},
+	} {
+		if err := StreamLogs(containers, opts, func(e LogEntry) error {
+			count++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	def.AssertEqual(count, 0, "expected log entries to be filtered", t)
+	if _, err := ParseLogSince("bad"); err == nil {
+		t.Errorf("expected invalid since value to fail")
+	}
+}
+
+func TestStreamLogFile(t *testing.T) {
+	projectPath := path.Join("_test_data", "sample2")
+	p, e := LoadFromPath(projectPath, true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	ch := container.NewDummy()
+	p.SetContainerHandler(ch)
+	p.Start()
+	// app log is only read from application containers
+	containers := p.LogContainers(nil)
+	if err := StreamLogFile(containers, LogFileApp, LogOptions{}, func(e LogEntry) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range containers {
+		dc := ch.GetContainer(c.Config.GetContainerName())
+		if dc == nil {
+			t.Fatalf("container %s not running", c.Config.GetContainerName())
+		}
+		ran := dc.CommandHistoryIndex("tail -n 100 /var/log/app.log") >= 0
+		_, isApp := c.Definition.(def.App)
+		def.AssertEqual(ran, isApp, "unexpected app log tail for "+c.Config.GetHumanName(), t)
+	}
+	if err := StreamLogFile(containers, "bad", LogOptions{}, nil); !errors.Is(err, ErrLogFileNotFound) {
+		t.Errorf("expected unknown log file to fail, got %v", err)
+	}
+	// lines split across writes
+	entries := make([]LogEntry, 0)
+	w := &logLineWriter{source: "app/test", callback: func(e LogEntry) error {
+		entries = append(entries, e)
+		return nil
+	}}
+	w.Write([]byte("first line\r\nsecond "))
+	w.Write([]byte("line\nthird"))
+	def.AssertEqual(len(entries), 2, "wrong number of log file lines", t)
+	def.AssertEqual(entries[0].Message, "first line", "unexpected first log file line", t)
+	def.AssertEqual(entries[1].Message, "second line", "unexpected second log file line", t)
+	def.AssertEqual(entries[1].Source, "app/test", "unexpected log file line source", t)
+}
+
+// copyTestProject copies the test project with given name to a temporary directory.
+func copyTestProject(t *testing.T, name string) string {
+	src := path.Join("_test_data", name)
+	dst := t.TempDir()
+	if e := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		if info.Name() == projectJSONFilename {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), data, info.Mode())
+	}); e != nil {
+		t.Fatalf("failed to copy test project, %s", e)
+	}
+	return dst
+}
+
+func TestSlotMetadata(t *testing.T) {
+	ch := container.NewDummy()
+	p, e := LoadFromPath(copyTestProject(t, "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	p.SetContainerHandler(ch)
+	// starting does not write the project file
+	p.Start()
+	p.Stop()
+	def.AssertEqual(len(p.Slots.Info), 0, "expected start not to record slot usage", t)
+	// start slot 3 and record last used time like the start command does
+	p.SetSlot(3)
+	p.Start()
+	p.Stop()
+	// changes made to the project file since loading are kept
+	other, e := LoadFromPath(p.Path, false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	other.Variables.Set("env:OTHER", "yes")
+	if e := other.Save(); e != nil {
+		t.Fatal(e)
+	}
+	if e := p.SlotTouch(); e != nil {
+		t.Fatal(e)
+	}
+	other, e = LoadFromPath(p.Path, false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(other.Variables.GetString("env:OTHER"), "yes", "expected concurrent change to be kept", t)
+	def.AssertEqual(other.Slots.Info[3] != nil && !other.Slots.Info[3].LastUsed.IsZero(), true, "expected stored last used time", t)
+	if e := p.SlotLabel(3, "feature branch"); e != nil {
+		t.Fatal(e)
+	}
+	if e := p.SlotLabel(2, "feature branch"); !errors.Is(e, container.ErrInvalidSlot) {
+		t.Errorf("expected duplicate label to fail, got %v", e)
+	}
+	if e := p.SlotLabel(2, "2"); !errors.Is(e, container.ErrInvalidSlot) {
+		t.Errorf("expected numeric label to fail, got %v", e)
+	}
+	slot, e := p.ResolveSlot("feature branch")
+	if e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(slot, 3, "expected label to resolve to slot 3", t)
+	if _, e := p.ResolveSlot("missing"); !errors.Is(e, container.ErrInvalidSlot) {
+		t.Errorf("expected unknown label to fail, got %v", e)
+	}
+	// switch persists the active slot
+	p.SlotSwitch(slot)
+	def.AssertEqual(p.ActiveSlot(), 3, "expected active slot 3", t)
+	slots, e := p.SlotList()
+	if e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(len(slots), 2, "expected two slots", t)
+	def.AssertEqual(slots[1].Slot, 3, "unexpected slot", t)
+	def.AssertEqual(slots[1].Label, "feature branch", "unexpected slot label", t)
+	def.AssertEqual(slots[1].Active, true, "expected slot 3 to be active", t)
+	def.AssertEqual(slots[1].LastUsed.IsZero(), false, "expected slot 3 last used time", t)
+	def.AssertEqual(len(slots[1].Volumes), len(slots[0].Volumes), "expected same volumes in both slots", t)
+	for _, v := range slots[1].Volumes {
+		def.AssertEqual(strings.HasSuffix(v, "-3"), true, "expected slot 3 volume", t)
+	}
+	// deleting the slot removes its label and resets the active slot
+	if e := p.Save(); e != nil {
+		t.Fatal(e)
+	}
+	if e := p.SlotForget(3); e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(p.ActiveSlot(), 1, "expected active slot to be reset", t)
+	if _, e := p.ResolveSlot("feature branch"); !errors.Is(e, container.ErrInvalidSlot) {
+		t.Errorf("expected label of deleted slot to be removed, got %v", e)
+	}
+	other, e = LoadFromPath(p.Path, false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(other.Slots.Active, 0, "expected stored active slot to be reset", t)
+	def.AssertEqual(other.Slots.Info[3] == nil, true, "expected stored slot metadata to be removed", t)
+}
@@ -0,0 +1,289 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const cronHistoryDir = "cron"
+const cronHistoryLimit = 50
+
+var cronHistoryLock sync.Mutex
+var cronLockNameRegex = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// CronJob defines an application cron job.
+type CronJob struct {
+	App  string       `json:"app"`
+	Name string       `json:"name"`
+	Cron *def.AppCron `json:"cron"`
+}
+
+// Key returns a key that uniquely identifies the cron job in a project.
+func (c CronJob) Key() string {
+	return c.App + "/" + c.Name
+}
+
+// CronRun defines a single run of a cron job.
+type CronRun struct {
+	App      string        `json:"app"`
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	ExitCode int           `json:"exit_code"`
+	Skipped  bool          `json:"skipped"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ListCrons returns all cron jobs in the project.
+func (p *Project) ListCrons() []CronJob {
+	out := make([]CronJob, 0)
+	for _, app := range p.Apps {
+		for name, c := range app.Crons {
+			out = append(out, CronJob{App: app.Name, Name: name, Cron: c})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key() < out[j].Key()
+	})
+	return out
+}
+
+// GetCron returns the cron job with the given name, app may be empty if the name is unique.
+func (p *Project) GetCron(name string, app string) (CronJob, error) {
+	found := make([]CronJob, 0)
+	for _, c := range p.ListCrons() {
+		if c.Name == name && (app == "" || c.App == app) {
+			found = append(found, c)
+		}
+	}
+	switch len(found) {
+	case 0:
+		{
+			return CronJob{}, errors.WithStack(ErrCronNotFound)
+		}
+	case 1:
+		{
+			return found[0], nil
+		}
+	}
+	return CronJob{}, errors.Wrapf(ErrCronAmbiguous, "cron '%s' exists in more than one application", name)
+}
+
+// cronLockPath returns the path of the lock file inside the application container held while the cron job runs.
+func (c CronJob) cronLockPath() string {
+	return fmt.Sprintf(
+		"/tmp/.pcc_cron_%s_%s.lock",
+		cronLockNameRegex.ReplaceAllString(c.App, "_"),
+		cronLockNameRegex.ReplaceAllString(c.Name, "_"),
+	)
+}
+
+// lockCheckCommand returns the container command that fails if a previous run still holds the lock file.
+func (c CronJob) lockCheckCommand() []string {
+	return []string{"flock", "-n", c.cronLockPath(), "true"}
+}
+
+// command returns the container command that runs the cron job while holding its lock file.
+func (c CronJob) command() []string {
+	return []string{"flock", "-n", c.cronLockPath(), "bash", "--login", "-c", c.Cron.Command}
+}
+
+// RunCron runs given cron job in its application container as the web user.
+// The run is skipped if a previous run, from this or any other process, is still going.
+// The lock is checked before the run so that the exit code of the cron command is never
+// mistaken for a held lock, a run that loses the race for the lock is recorded as failed.
+func (p *Project) RunCron(job CronJob, out io.Writer) (CronRun, error) {
+	run := CronRun{
+		App:   job.App,
+		Name:  job.Name,
+		Start: time.Now(),
+	}
+	// find app
+	var app *def.App
+	for i := range p.Apps {
+		if p.Apps[i].Name == job.App {
+			app = &p.Apps[i]
+			break
+		}
+	}
+	if app == nil {
+		return run, errors.WithStack(ErrCronNotFound)
+	}
+	done := output.Duration(fmt.Sprintf("Run cron '%s' in '%s.'", job.Name, job.App))
+	defer done()
+	c := p.NewContainer(*app)
+	// skip if previous run is still going
+	lockExitCode, err := c.containerHandler.ContainerCommand(
+		c.Config.GetContainerName(),
+		"web",
+		job.lockCheckCommand(),
+		nil,
+	)
+	if err != nil && !errors.Is(err, container.ErrCommandExited) {
+		return run, errors.WithStack(err)
+	}
+	if lockExitCode != 0 {
+		run.Skipped = true
+		err = ErrCronRunning
+	} else {
+		run.ExitCode, err = c.containerHandler.ContainerCommand(
+			c.Config.GetContainerName(),
+			"web",
+			job.command(),
+			out,
+		)
+		if err != nil && errors.Is(err, container.ErrCommandExited) {
+			err = nil
+		}
+	}
+	run.Duration = time.Since(run.Start)
+	if err := p.addCronHistory(run, err); err != nil {
+		return run, errors.WithStack(err)
+	}
+	return run, nil
+}
+
+// CronScheduler runs all project cron jobs on their schedules until stop is closed.
+func (p *Project) CronScheduler(stop <-chan struct{}) error {
+	if !p.HasFlag(EnableCron) {
+		return errors.WithStack(ErrCronDisabled)
+	}
+	if !p.HasFlag(EnableCronScheduler) {
+		return errors.WithStack(ErrCronSchedulerDisabled)
+	}
+	jobs := p.ListCrons()
+	if len(jobs) == 0 {
+		return errors.WithStack(ErrCronNotFound)
+	}
+	scheduler := cron.New()
+	for _, job := range jobs {
+		schedule, err := job.Cron.Schedule()
+		if err != nil {
+			return errors.Wrapf(err, "invalid spec for cron '%s'", job.Key())
+		}
+		job := job
+		scheduler.Schedule(schedule, cron.FuncJob(func() {
+			run, err := p.RunCron(job, nil)
+			switch {
+			case run.Skipped:
+				{
+					output.Warn(fmt.Sprintf("Skip cron '%s', previous run still in progress.", job.Key()))
+					break
+				}
+			case err != nil:
+				{
+					output.LogError(err)
+					output.Warn(fmt.Sprintf("Cron '%s' failed, %s.", job.Key(), err.Error()))
+					break
+				}
+			default:
+				{
+					output.Info(fmt.Sprintf(
+						"Cron '%s' exited with code %d after %s.", job.Key(), run.ExitCode, run.Duration.Round(time.Millisecond),
+					))
+				}
+			}
+		}))
+		output.Info(fmt.Sprintf("Scheduled cron '%s' (%s).", job.Key(), job.Cron.Spec))
+	}
+	scheduler.Start()
+	<-stop
+	// wait for running crons to finish
+	<-scheduler.Stop().Done()
+	return nil
+}
+
+// CronHistory returns the run history of the given cron job, most recent first.
+func (p *Project) CronHistory(job CronJob) ([]CronRun, error) {
+	cronHistoryLock.Lock()
+	defer cronHistoryLock.Unlock()
+	history, err := p.loadCronHistory()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	out := history[job.Key()]
+	if out == nil {
+		out = make([]CronRun, 0)
+	}
+	return out, nil
+}
+
+func (p *Project) cronHistoryPath() string {
+	return filepath.Join(config.Path(), cronHistoryDir, p.ID+".json")
+}
+
+func (p *Project) loadCronHistory() (map[string][]CronRun, error) {
+	out := make(map[string][]CronRun)
+	raw, err := ioutil.ReadFile(p.cronHistoryPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+// addCronHistory records a cron run, runErr is stored with the run and returned.
+func (p *Project) addCronHistory(run CronRun, runErr error) error {
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	cronHistoryLock.Lock()
+	defer cronHistoryLock.Unlock()
+	history, err := p.loadCronHistory()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	key := run.App + "/" + run.Name
+	history[key] = append([]CronRun{run}, history[key]...)
+	if len(history[key]) > cronHistoryLimit {
+		history[key] = history[key][:cronHistoryLimit]
+	}
+	raw, err := json.Marshal(history)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.cronHistoryPath()), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(p.cronHistoryPath(), raw, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return runErr
+}
@@ -21,149 +21,234 @@ import (
 	"fmt"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
 
 const (
-	databaseMySQL    int = 1
-	databasePostgres int = 2
+	// DatabaseFormatSQL is a plain SQL dump.
+	DatabaseFormatSQL = "sql"
+	// DatabaseFormatPostgresCustom is a PostgreSQL custom format dump (pg_dump -Fc).
+	DatabaseFormatPostgresCustom = "pgcustom"
+	// DatabaseFormatRedisRDB is a Redis RDB snapshot.
+	DatabaseFormatRedisRDB = "rdb"
+	// DatabaseFormatMongoArchive is a MongoDB archive (mongodump --archive).
+	DatabaseFormatMongoArchive = "mongoarchive"
+	// DatabaseFormatNDJSON is a newline delimited JSON dump of Elasticsearch/OpenSearch indexes.
+	DatabaseFormatNDJSON = "ndjson"
 )
 
-var databaseTypeNames = map[int][]string{
-	databaseMySQL:    []string{"mysql", "mariadb"},
-	databasePostgres: []string{"postgresql"},
+// databaseDriver defines the commands used to interact with a database engine.
+type databaseDriver interface {
+	// Databases returns the databases (schemas, indexes, etc) of given service.
+	Databases(s def.Service) []string
+	// Shell returns the command to access the database shell.
+	Shell(s def.Service, database string) string
+	// Dump returns the command to dump a database to stdout.
+	Dump(s def.Service, database string) string
+	// DumpFormat returns the format of the dumps created by Dump and RemoteDump.
+	DumpFormat() string
+	// Import returns the command to import a dump of given format read from stdin.
+	Import(s def.Service, database string, format string) string
+	// Recreate returns the command to delete a database and create it empty.
+	Recreate(s def.Service, database string) string
+	// RemoteDump returns the command to dump a database on Platform.sh with given relationship.
+	RemoteDump(s def.Service, database string, rel map[string]interface{}, opts DatabaseDumpOptions) string
+	// RemoteImport returns the command to import a dump read from stdin in to a database on Platform.sh with
+	// given relationship, empty if importing is not supported.
+	RemoteImport(s def.Service, database string, rel map[string]interface{}) string
+}
+
+// DatabaseDumpStructureAll is the structure only table name that matches every table.
+const DatabaseDumpStructureAll = "*"
+
+// DatabaseDumpOptions limits the tables contained in a database dump.
+// Only the sql database drivers support them, other drivers ignore them.
+type DatabaseDumpOptions struct {
+	Tables        []string // only dump these tables
+	ExcludeTables []string // do not dump these tables
+	StructureOnly []string // dump only the structure of these tables, * for all tables
+}
+
+// IsEmpty returns true if no options are set.
+func (o DatabaseDumpOptions) IsEmpty() bool {
+	return len(o.Tables) == 0 && len(o.ExcludeTables) == 0 && len(o.StructureOnly) == 0
+}
+
+// IsStructureOnly returns true if only the structure of every table should be dumped.
+func (o DatabaseDumpOptions) IsStructureOnly() bool {
+	return sliceContainsString(o.StructureOnly, DatabaseDumpStructureAll)
+}
+
+// databaseTypeNames is the list of service types that are considered to be a database in order of preference.
+var databaseTypeNames = []string{
+	"mysql", "mariadb", "oracle-mysql", "postgresql", "mongodb", "redis-persistent", "redis", "elasticsearch", "opensearch",
+}
+
+// databaseEphemeralTypeNames is the list of database service types that do not persist their data on Platform.sh.
+var databaseEphemeralTypeNames = []string{"redis"}
+
+// databaseDrivers maps service types to their database driver.
+var databaseDrivers = map[string]databaseDriver{
+	"mysql":            mysqlDriver{},
+	"mariadb":          mysqlDriver{},
+	"oracle-mysql":     mysqlDriver{},
+	"postgresql":       postgresDriver{},
+	"mongodb":          mongodbDriver{},
+	"redis-persistent": redisDriver{},
+	"redis":            redisDriver{},
+	"elasticsearch":    elasticsearchDriver{},
+	"opensearch":       elasticsearchDriver{},
 }
 
 // GetDatabaseTypeNames returns list of all service types that are considered to be a database.
 func GetDatabaseTypeNames() []string {
-	out := make([]string, 0)
-	for _, v := range databaseTypeNames {
-		out = append(out, v...)
-	}
+	out := make([]string, len(databaseTypeNames))
+	copy(out, databaseTypeNames)
 	return out
 }
 
-// MatchDatabaseTypeName matches given service type with database service type.
-func MatchDatabaseTypeName(name string) int {
-	for k, v := range databaseTypeNames {
-		for _, vv := range v {
-			if vv == name {
-				return k
-			}
-		}
+// WarnDatabaseEphemeral warns when given definition is a database that does not persist its data on Platform.sh.
+func WarnDatabaseEphemeral(d interface{}) {
+	service, ok := d.(def.Service)
+	if !ok || !sliceContainsString(databaseEphemeralTypeNames, service.GetTypeName()) {
+		return
 	}
-	return 0
+	output.Warn(fmt.Sprintf(
+		"Service '%s' is a cache, its data is ephemeral on Platform.sh and can be lost at any time.", service.Name,
+	))
 }
 
-// GetDatabaseShellCommand returns the command to access the database shell for given definition.
-func (p *Project) GetDatabaseShellCommand(d interface{}, database string) string {
+// getDatabaseDriver returns the database driver for given definition or nil if it is not a database.
+func getDatabaseDriver(d interface{}) (def.Service, databaseDriver) {
 	switch service := d.(type) {
 	case def.Service:
 		{
-			switch MatchDatabaseTypeName(service.GetTypeName()) {
-			case databaseMySQL:
-				{
-					shellCmd := "mysql --password=$(cat /mnt/data/.mysql-password)"
-					if database != "" {
-						shellCmd += fmt.Sprintf(" -D%s", database)
-					}
-					return shellCmd
-				}
-			case databasePostgres:
-				{
-					shellCmd := "PGPASSWORD=main psql -U main -h 127.0.0.1"
-					if database != "" {
-						shellCmd += fmt.Sprintf(" --dbname=\"%s\"", database)
-					}
-					return shellCmd
-				}
-			}
+			return service, databaseDrivers[service.GetTypeName()]
 		}
 	}
-	return ""
+	return def.Service{}, nil
+}
+
+// GetDatabases returns the databases of given definition.
+func (p *Project) GetDatabases(d interface{}) []string {
+	service, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return []string{}
+	}
+	return driver.Databases(service)
+}
+
+// GetDatabaseShellCommand returns the command to access the database shell for given definition.
+func (p *Project) GetDatabaseShellCommand(d interface{}, database string) string {
+	service, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return ""
+	}
+	return driver.Shell(service, database)
 }
 
 // GetDatabaseDumpCommand returns the command to dump a database for given definition.
 func (p *Project) GetDatabaseDumpCommand(d interface{}, database string) string {
-	switch service := d.(type) {
-	case def.Service:
-		{
-			switch MatchDatabaseTypeName(service.GetTypeName()) {
-			case databaseMySQL:
-				{
-					shellCmd := fmt.Sprintf(
-						"mysqldump --password=$(cat /mnt/data/.mysql-password) %s",
-						database,
-					)
-					return shellCmd
-				}
-			case databasePostgres:
-				{
-					shellCmd := fmt.Sprintf(
-						"PGPASSWORD=main pg_dump -U main -h 127.0.0.1 %s",
-						database,
-					)
-					return shellCmd
-				}
-			}
-		}
+	service, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return ""
+	}
+	return driver.Dump(service, database)
+}
+
+// GetDatabaseDumpFormat returns the dump format of given definition.
+func (p *Project) GetDatabaseDumpFormat(d interface{}) string {
+	_, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return ""
 	}
-	return ""
+	return driver.DumpFormat()
+}
+
+// GetDatabaseImportCommand returns the command to import a database dump read from stdin for given definition.
+func (p *Project) GetDatabaseImportCommand(d interface{}, database string, format string) string {
+	service, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return ""
+	}
+	return driver.Import(service, database, format)
+}
+
+// GetDatabaseRecreateCommand returns the command to drop and create a database for given definition.
+func (p *Project) GetDatabaseRecreateCommand(d interface{}, database string) string {
+	service, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return ""
+	}
+	return driver.Recreate(service, database)
 }
 
 // GetPlatformSHDatabaseDumpCommand returns the command to dump a database from Platform.sh for given definition.
-func (p *Project) GetPlatformSHDatabaseDumpCommand(d interface{}, database string, rels map[string]interface{}) string {
-	switch d.(type) {
-	case def.Service:
-		{
-			service := d.(def.Service)
-			// find valid relationship and get creds
-			rel := ""
-			user := ""
-			pass := ""
-			host := ""
-			for name, endpoint := range service.Configuration["endpoints"].(map[string]interface{}) {
-				for dbName := range endpoint.(map[string]interface{})["privileges"].(map[string]interface{}) {
-					if dbName == database {
-						rel = name
-						break
-					}
-				}
-			}
-			for _, v := range rels {
-				for _, vv := range v.([]interface{}) {
-					val := vv.(map[string]interface{})
-					if val["service"] == service.Name && val["rel"] == rel {
-						user = val["username"].(string)
-						pass = val["password"].(string)
-						host = val["host"].(string)
-						break
-					}
-				}
+func (p *Project) GetPlatformSHDatabaseDumpCommand(d interface{}, database string, rels map[string]interface{}, opts DatabaseDumpOptions) string {
+	service, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return ""
+	}
+	rel := platformSHDatabaseRelationship(service, database, rels)
+	if rel == nil {
+		return ""
+	}
+	return driver.RemoteDump(service, database, rel, opts)
+}
+
+// GetPlatformSHDatabaseImportCommand returns the command to import a dump in to a database on Platform.sh for given definition.
+func (p *Project) GetPlatformSHDatabaseImportCommand(d interface{}, database string, rels map[string]interface{}) string {
+	service, driver := getDatabaseDriver(d)
+	if driver == nil {
+		return ""
+	}
+	rel := platformSHDatabaseRelationship(service, database, rels)
+	if rel == nil {
+		return ""
+	}
+	return driver.RemoteImport(service, database, rel)
+}
+
+// platformSHDatabaseRelationship returns the Platform.sh relationship used to access given database.
+func platformSHDatabaseRelationship(service def.Service, database string, rels map[string]interface{}) map[string]interface{} {
+	// find the relationship used to access the database, sql databases
+	// use the endpoint with privileges to the given schema
+	relName := ""
+	if endpoints, ok := service.Configuration["endpoints"].(map[string]interface{}); ok {
+		for name, endpoint := range endpoints {
+			privileges, _ := endpoint.(map[string]interface{})["privileges"].(map[string]interface{})
+			if _, ok := privileges[database]; ok {
+				relName = name
+				break
 			}
-			// get dump command
-			switch MatchDatabaseTypeName(service.GetTypeName()) {
-			case databaseMySQL:
-				{
-					return fmt.Sprintf(
-						`mysqldump --host="%s" -u%s --password=%s %s`,
-						host,
-						user,
-						pass,
-						database,
-					)
-				}
-			case databasePostgres:
-				{
-					return fmt.Sprintf(
-						"PGPASSWORD=%s pg_dump -U %s -h %s %s",
-						pass,
-						user,
-						host,
-						database,
-					)
-				}
+		}
+	}
+	for _, v := range rels {
+		vl, _ := v.([]interface{})
+		for _, vv := range vl {
+			val, _ := vv.(map[string]interface{})
+			if val["service"] == service.Name && (relName == "" || val["rel"] == relName) {
+				return val
 			}
 		}
 	}
-	return ""
+	return nil
+}
+
+// databaseRelationshipValue returns a relationship value as a string.
+func databaseRelationshipValue(rel map[string]interface{}, key string) string {
+	if rel[key] == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", rel[key])
+}
+
+// sliceContainsString returns true if given slice contains given string.
+func sliceContainsString(slice []string, s string) bool {
+	for _, val := range slice {
+		if val == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,111 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+const elasticsearchURL = "http://127.0.0.1:9200"
+
+// elasticsearchDriver is the database driver for Elasticsearch and OpenSearch.
+// The database is an index pattern, dumps contain the settings, mappings and
+// documents of every matching index as newline delimited JSON.
+type elasticsearchDriver struct{}
+
+// elasticsearchScriptCommand returns the command to run the dump/import script with given mode.
+// The optional auth is the username and password used for basic authentication.
+func elasticsearchScriptCommand(url string, auth string, mode string, pattern string) string {
+	return fmt.Sprintf(
+		`ES_URL="%s" ES_AUTH="%s" "$(command -v python3 || command -v python2.7)" -c "$(echo '%s' | base64 -d)" %s '%s'`,
+		url,
+		auth,
+		base64.StdEncoding.EncodeToString([]byte(elasticsearchScript)),
+		mode,
+		pattern,
+	)
+}
+
+// elasticsearchRemoteURL returns the url and basic authentication credentials of given relationship.
+func elasticsearchRemoteURL(rel map[string]interface{}) (string, string) {
+	scheme := databaseRelationshipValue(rel, "scheme")
+	if scheme == "" {
+		scheme = "http"
+	}
+	auth := ""
+	if username := databaseRelationshipValue(rel, "username"); username != "" {
+		auth = username + ":" + databaseRelationshipValue(rel, "password")
+	}
+	return fmt.Sprintf(
+		"%s://%s:%s",
+		scheme,
+		databaseRelationshipValue(rel, "host"),
+		databaseRelationshipValue(rel, "port"),
+	), auth
+}
+
+// Databases returns a pattern matching all indexes.
+func (elasticsearchDriver) Databases(s def.Service) []string {
+	return []string{"*"}
+}
+
+// Shell returns the command to open a shell with the url of the search service.
+func (elasticsearchDriver) Shell(s def.Service, database string) string {
+	return fmt.Sprintf(
+		`export ES_URL="%s"; echo "Search service available at $ES_URL."; exec bash --login`,
+		elasticsearchURL,
+	)
+}
+
+// Dump returns the command to dump matching indexes.
+func (elasticsearchDriver) Dump(s def.Service, database string) string {
+	return elasticsearchScriptCommand(elasticsearchURL, "", "dump", database)
+}
+
+// DumpFormat returns the ndjson dump format.
+func (elasticsearchDriver) DumpFormat() string {
+	return DatabaseFormatNDJSON
+}
+
+// Import returns the command to import indexes, existing indexes in the dump are replaced.
+func (elasticsearchDriver) Import(s def.Service, database string, format string) string {
+	if format != DatabaseFormatNDJSON {
+		return ""
+	}
+	return elasticsearchScriptCommand(elasticsearchURL, "", "import", database)
+}
+
+// Recreate returns the command to delete matching indexes.
+func (elasticsearchDriver) Recreate(s def.Service, database string) string {
+	return elasticsearchScriptCommand(elasticsearchURL, "", "delete", database)
+}
+
+// RemoteDump returns the command to dump matching indexes on Platform.sh.
+func (elasticsearchDriver) RemoteDump(s def.Service, database string, rel map[string]interface{}, opts DatabaseDumpOptions) string {
+	url, auth := elasticsearchRemoteURL(rel)
+	return elasticsearchScriptCommand(url, auth, "dump", database)
+}
+
+// RemoteImport returns the command to import indexes on Platform.sh, existing indexes in the dump are replaced.
+func (elasticsearchDriver) RemoteImport(s def.Service, database string, rel map[string]interface{}) string {
+	url, auth := elasticsearchRemoteURL(rel)
+	return elasticsearchScriptCommand(url, auth, "import", database)
+}
@@ -0,0 +1,164 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const databaseImportProgressInterval = time.Millisecond * 250
+
+// progressReader is a reader that reports the number of bytes read.
+type progressReader struct {
+	reader io.Reader
+	cur    int64
+	total  int64
+	last   time.Time
+	update func(cur *int64, total *int64)
+	lock   sync.Mutex
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cur += int64(n)
+	if time.Since(r.last) >= databaseImportProgressInterval || err == io.EOF {
+		r.last = time.Now()
+		cur := r.cur
+		total := r.total
+		r.update(&cur, &total)
+	}
+	return n, err
+}
+
+// databaseImportReader detects the compression and format of given dump and returns a reader for the uncompressed dump.
+func databaseImportReader(r io.Reader) (io.Reader, string, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(16)
+	if err != nil && err != io.EOF {
+		return nil, "", errors.WithStack(err)
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		{
+			gr, err := gzip.NewReader(br)
+			if err != nil {
+				return nil, "", errors.WithStack(err)
+			}
+			return databaseImportReader(gr)
+		}
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		{
+			return databaseImportReader(bzip2.NewReader(br))
+		}
+	case bytes.HasPrefix(magic, []byte("PGDMP")):
+		{
+			return br, DatabaseFormatPostgresCustom, nil
+		}
+	case bytes.HasPrefix(magic, []byte("REDIS")):
+		{
+			return br, DatabaseFormatRedisRDB, nil
+		}
+	case bytes.HasPrefix(magic, []byte{0x6d, 0xe2, 0x99, 0x81}):
+		{
+			return br, DatabaseFormatMongoArchive, nil
+		}
+	case bytes.HasPrefix(bytes.TrimSpace(magic), []byte("{")):
+		{
+			return br, DatabaseFormatNDJSON, nil
+		}
+	}
+	return br, DatabaseFormatSQL, nil
+}
+
+// DatabaseImport imports the dump at given path in to the database of given definition.
+// Gzip and bzip2 compressed dumps are decompressed while streaming. If recreate is true
+// the database is dropped and created before the import.
+func (p *Project) DatabaseImport(d interface{}, database string, path string, recreate bool) error {
+	done := output.Duration(
+		fmt.Sprintf("Import '%s' in to database '%s.'", filepath.Base(path), database),
+	)
+	WarnDatabaseEphemeral(d)
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// report progress on bytes read from the file
+	prog := output.Progress([]string{fmt.Sprintf("Import %s", filepath.Base(path))})
+	pr := &progressReader{
+		reader: f,
+		total:  stat.Size(),
+		update: func(cur *int64, total *int64) {
+			prog(0, output.ProgressMessageWait, cur, total)
+		},
+	}
+	r, format, err := databaseImportReader(pr)
+	if err != nil {
+		prog(0, output.ProgressMessageError, nil, nil)
+		return errors.WithStack(err)
+	}
+	importCmd := p.GetDatabaseImportCommand(d, database, format)
+	if importCmd == "" {
+		prog(0, output.ProgressMessageError, nil, nil)
+		return errors.Wrapf(ErrInvalidDatabaseFormat, "cannot import %s dump in to '%s'", format, p.GetDefinitionName(d))
+	}
+	c := p.NewContainer(d)
+	// drop and create database
+	if recreate {
+		if _, err := c.containerHandler.ContainerCommand(
+			c.Config.GetContainerName(),
+			"root",
+			[]string{"sh", "-c", p.GetDatabaseRecreateCommand(d, database)},
+			nil,
+		); err != nil {
+			prog(0, output.ProgressMessageError, nil, nil)
+			return errors.WithStack(err)
+		}
+	}
+	// stream dump in to database
+	if _, err := c.containerHandler.ContainerShell(
+		c.Config.GetContainerName(),
+		"root",
+		[]string{"sh", "-c", importCmd},
+		r,
+	); err != nil {
+		prog(0, output.ProgressMessageError, nil, nil)
+		return errors.WithStack(err)
+	}
+	prog(0, output.ProgressMessageDone, nil, nil)
+	done()
+	return nil
+}
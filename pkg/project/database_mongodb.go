@@ -0,0 +1,92 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+const mongodbAuth = "-u main -p main --authenticationDatabase main"
+const mongodbShell = "$(command -v mongosh || command -v mongo)"
+
+// mongodbDriver is the database driver for MongoDB.
+type mongodbDriver struct{}
+
+// Databases returns the default database.
+func (mongodbDriver) Databases(s def.Service) []string {
+	return []string{"main"}
+}
+
+// Shell returns the command to access the mongo shell.
+func (mongodbDriver) Shell(s def.Service, database string) string {
+	if database == "" {
+		database = "main"
+	}
+	return fmt.Sprintf("%s %s %s", mongodbShell, mongodbAuth, database)
+}
+
+// Dump returns the command to dump a database with mongodump.
+func (mongodbDriver) Dump(s def.Service, database string) string {
+	return fmt.Sprintf("mongodump %s --db=%s --archive 2>/dev/null", mongodbAuth, database)
+}
+
+// DumpFormat returns the mongo archive dump format.
+func (mongodbDriver) DumpFormat() string {
+	return DatabaseFormatMongoArchive
+}
+
+// Import returns the command to restore an archive with mongorestore.
+func (mongodbDriver) Import(s def.Service, database string, format string) string {
+	if format != DatabaseFormatMongoArchive {
+		return ""
+	}
+	return fmt.Sprintf("mongorestore %s --nsInclude='%s.*' --archive", mongodbAuth, database)
+}
+
+// Recreate returns the command to drop a database, it is created again on first write.
+func (d mongodbDriver) Recreate(s def.Service, database string) string {
+	return d.Shell(s, database) + " --quiet --eval 'db.dropDatabase()'"
+}
+
+// RemoteDump returns the command to dump a database on Platform.sh with mongodump.
+func (mongodbDriver) RemoteDump(s def.Service, database string, rel map[string]interface{}, opts DatabaseDumpOptions) string {
+	return fmt.Sprintf(
+		"mongodump --host=%s --port=%s -u %s -p %s --authenticationDatabase %s --db=%s --archive 2>/dev/null",
+		databaseRelationshipValue(rel, "host"),
+		databaseRelationshipValue(rel, "port"),
+		databaseRelationshipValue(rel, "username"),
+		databaseRelationshipValue(rel, "password"),
+		databaseRelationshipValue(rel, "path"),
+		database,
+	)
+}
+
+// RemoteImport returns the command to restore a mongodump archive in to a database on Platform.sh.
+func (mongodbDriver) RemoteImport(s def.Service, database string, rel map[string]interface{}) string {
+	return fmt.Sprintf(
+		"mongorestore --host=%s --port=%s -u %s -p %s --authenticationDatabase %s --nsInclude='%s.*' --drop --archive",
+		databaseRelationshipValue(rel, "host"),
+		databaseRelationshipValue(rel, "port"),
+		databaseRelationshipValue(rel, "username"),
+		databaseRelationshipValue(rel, "password"),
+		databaseRelationshipValue(rel, "path"),
+		database,
+	)
+}
@@ -0,0 +1,158 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+// mysqlDriver is the database driver for MySQL and MariaDB.
+type mysqlDriver struct{}
+
+// Databases returns the configured schemas.
+func (mysqlDriver) Databases(s def.Service) []string {
+	return databaseSchemas(s)
+}
+
+// Shell returns the command to access the mysql shell.
+func (mysqlDriver) Shell(s def.Service, database string) string {
+	shellCmd := "mysql --password=$(cat /mnt/data/.mysql-password)"
+	if database != "" {
+		shellCmd += fmt.Sprintf(" -D%s", database)
+	}
+	return shellCmd
+}
+
+// Dump returns the command to dump a database with mysqldump.
+func (mysqlDriver) Dump(s def.Service, database string) string {
+	return fmt.Sprintf(
+		"mysqldump --password=$(cat /mnt/data/.mysql-password) %s",
+		database,
+	)
+}
+
+// DumpFormat returns the sql dump format.
+func (mysqlDriver) DumpFormat() string {
+	return DatabaseFormatSQL
+}
+
+// Import returns the command to import a sql dump.
+func (d mysqlDriver) Import(s def.Service, database string, format string) string {
+	if format != DatabaseFormatSQL {
+		return ""
+	}
+	return d.Shell(s, database)
+}
+
+// Recreate returns the command to drop and create a database.
+func (d mysqlDriver) Recreate(s def.Service, database string) string {
+	return fmt.Sprintf(
+		"%s -e 'DROP DATABASE IF EXISTS `%s`; CREATE DATABASE `%s`;'",
+		d.Shell(s, ""),
+		database,
+		database,
+	)
+}
+
+// RemoteDump returns the command to dump a database on Platform.sh with mysqldump.
+// Structure only tables are dumped with a second mysqldump call without data.
+func (mysqlDriver) RemoteDump(s def.Service, database string, rel map[string]interface{}, opts DatabaseDumpOptions) string {
+	dumpCmd := fmt.Sprintf(
+		`mysqldump --host="%s" -u%s --password=%s`,
+		databaseRelationshipValue(rel, "host"),
+		databaseRelationshipValue(rel, "username"),
+		databaseRelationshipValue(rel, "password"),
+	)
+	if opts.IsStructureOnly() {
+		return fmt.Sprintf("%s --no-data %s", dumpCmd, mysqlTableArgs(database, opts.Tables, opts.ExcludeTables))
+	}
+	exclude := append(append([]string{}, opts.ExcludeTables...), opts.StructureOnly...)
+	dataTables := make([]string, 0)
+	for _, table := range opts.Tables {
+		if !sliceContainsString(exclude, table) {
+			dataTables = append(dataTables, table)
+		}
+	}
+	structureTables := make([]string, 0)
+	for _, table := range opts.StructureOnly {
+		if !sliceContainsString(opts.ExcludeTables, table) && (len(opts.Tables) == 0 || sliceContainsString(opts.Tables, table)) {
+			structureTables = append(structureTables, table)
+		}
+	}
+	cmds := make([]string, 0)
+	if len(opts.Tables) == 0 || len(dataTables) > 0 {
+		cmds = append(cmds, fmt.Sprintf("%s %s", dumpCmd, mysqlTableArgs(database, dataTables, exclude)))
+	}
+	if len(structureTables) > 0 {
+		cmds = append(cmds, fmt.Sprintf("%s --no-data %s", dumpCmd, mysqlTableArgs(database, structureTables, nil)))
+	}
+	switch len(cmds) {
+	case 0:
+		{
+			return "true"
+		}
+	case 1:
+		{
+			return cmds[0]
+		}
+	}
+	return "(" + strings.Join(cmds, " && ") + ")"
+}
+
+// RemoteImport returns the command to import a sql dump in to a database on Platform.sh.
+func (mysqlDriver) RemoteImport(s def.Service, database string, rel map[string]interface{}) string {
+	return fmt.Sprintf(
+		`mysql --host="%s" -u%s --password=%s %s`,
+		databaseRelationshipValue(rel, "host"),
+		databaseRelationshipValue(rel, "username"),
+		databaseRelationshipValue(rel, "password"),
+		database,
+	)
+}
+
+// mysqlTableArgs returns the mysqldump arguments to dump given tables of a database.
+func mysqlTableArgs(database string, tables []string, exclude []string) string {
+	out := database
+	for i, table := range exclude {
+		if !sliceContainsString(exclude[:i], table) {
+			out += fmt.Sprintf(" --ignore-table='%s.%s'", database, table)
+		}
+	}
+	for _, table := range tables {
+		if !sliceContainsString(exclude, table) {
+			out += fmt.Sprintf(" '%s'", table)
+		}
+	}
+	return out
+}
+
+// databaseSchemas returns the schemas configured for a sql database service.
+func databaseSchemas(s def.Service) []string {
+	out := make([]string, 0)
+	schemas, _ := s.Configuration["schemas"].([]interface{})
+	for _, schema := range schemas {
+		out = append(out, fmt.Sprintf("%v", schema))
+	}
+	if len(out) == 0 {
+		out = append(out, "main")
+	}
+	return out
+}
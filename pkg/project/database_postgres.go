@@ -0,0 +1,126 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+// postgresDriver is the database driver for PostgreSQL.
+type postgresDriver struct{}
+
+// Databases returns the configured schemas.
+func (postgresDriver) Databases(s def.Service) []string {
+	return databaseSchemas(s)
+}
+
+// Shell returns the command to access the psql shell.
+func (postgresDriver) Shell(s def.Service, database string) string {
+	shellCmd := "PGPASSWORD=main psql -U main -h 127.0.0.1"
+	if database != "" {
+		shellCmd += fmt.Sprintf(" --dbname=\"%s\"", database)
+	}
+	return shellCmd
+}
+
+// Dump returns the command to dump a database with pg_dump.
+func (postgresDriver) Dump(s def.Service, database string) string {
+	return fmt.Sprintf(
+		"PGPASSWORD=main pg_dump -U main -h 127.0.0.1 %s",
+		database,
+	)
+}
+
+// DumpFormat returns the sql dump format.
+func (postgresDriver) DumpFormat() string {
+	return DatabaseFormatSQL
+}
+
+// Import returns the command to import a sql or custom format dump.
+func (d postgresDriver) Import(s def.Service, database string, format string) string {
+	switch format {
+	case DatabaseFormatSQL:
+		{
+			return d.Shell(s, database)
+		}
+	case DatabaseFormatPostgresCustom:
+		{
+			return fmt.Sprintf(
+				"PGPASSWORD=main pg_restore -U main -h 127.0.0.1 --no-owner --no-acl --dbname=\"%s\"",
+				database,
+			)
+		}
+	}
+	return ""
+}
+
+// Recreate returns the command to drop and create a database.
+func (d postgresDriver) Recreate(s def.Service, database string) string {
+	return fmt.Sprintf(
+		"%s --dbname=postgres -c 'DROP DATABASE IF EXISTS \"%s\"' -c 'CREATE DATABASE \"%s\" OWNER main'",
+		d.Shell(s, ""),
+		database,
+		database,
+	)
+}
+
+// RemoteDump returns the command to dump a database on Platform.sh with pg_dump.
+func (postgresDriver) RemoteDump(s def.Service, database string, rel map[string]interface{}, opts DatabaseDumpOptions) string {
+	tableArgs := ""
+	if opts.IsStructureOnly() {
+		tableArgs += " --schema-only"
+	}
+	for _, table := range opts.Tables {
+		tableArgs += fmt.Sprintf(" --table='%s'", table)
+	}
+	for _, table := range opts.ExcludeTables {
+		tableArgs += fmt.Sprintf(" --exclude-table='%s'", table)
+	}
+	if !opts.IsStructureOnly() {
+		for _, table := range opts.StructureOnly {
+			tableArgs += fmt.Sprintf(" --exclude-table-data='%s'", table)
+		}
+	}
+	return fmt.Sprintf(
+		"PGPASSWORD=%s pg_dump -U %s -h %s%s %s",
+		databaseRelationshipValue(rel, "password"),
+		databaseRelationshipValue(rel, "username"),
+		databaseRelationshipValue(rel, "host"),
+		tableArgs,
+		database,
+	)
+}
+
+// RemoteImport returns the command to import a sql dump in to a database on Platform.sh.
+// The public schema is recreated first as dumps made with pg_dump do not drop existing tables.
+func (postgresDriver) RemoteImport(s def.Service, database string, rel map[string]interface{}) string {
+	psqlCmd := fmt.Sprintf(
+		"PGPASSWORD=%s psql -U %s -h %s --dbname=\"%s\"",
+		databaseRelationshipValue(rel, "password"),
+		databaseRelationshipValue(rel, "username"),
+		databaseRelationshipValue(rel, "host"),
+		database,
+	)
+	return fmt.Sprintf(
+		"%s -c 'DROP SCHEMA IF EXISTS public CASCADE' -c 'CREATE SCHEMA public' >/dev/null && %s",
+		psqlCmd,
+		psqlCmd,
+	)
+}
@@ -0,0 +1,91 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+// redisDriver is the database driver for Redis, plain Redis is a cache whose data is ephemeral on Platform.sh.
+// Dumps are RDB snapshots of the whole instance, the database is the
+// logical database number used by the shell.
+type redisDriver struct{}
+
+// Databases returns the default logical database.
+func (redisDriver) Databases(s def.Service) []string {
+	return []string{"0"}
+}
+
+// Shell returns the command to access redis-cli.
+func (redisDriver) Shell(s def.Service, database string) string {
+	if database == "" {
+		database = "0"
+	}
+	return fmt.Sprintf("redis-cli -n %s", database)
+}
+
+// Dump returns the command to dump a RDB snapshot.
+func (redisDriver) Dump(s def.Service, database string) string {
+	return "redis-cli --rdb /tmp/pcc-dump.rdb >/dev/null 2>&1 && cat /tmp/pcc-dump.rdb && rm -f /tmp/pcc-dump.rdb"
+}
+
+// DumpFormat returns the rdb dump format.
+func (redisDriver) DumpFormat() string {
+	return DatabaseFormatRedisRDB
+}
+
+// Import returns the command to replace the data with a RDB snapshot.
+// The snapshot is loaded with DEBUG RELOAD NOSAVE, Redis before 6.0 ignores NOSAVE and would
+// overwrite the snapshot so it, like Redis with the debug command disabled, is shutdown without
+// saving and the import waits for the service supervisor to restart it.
+func (redisDriver) Import(s def.Service, database string, format string) string {
+	if format != DatabaseFormatRedisRDB {
+		return ""
+	}
+	return `cat > /tmp/pcc-import.rdb && ` +
+		`DIR="$(redis-cli --raw CONFIG GET dir | tail -n 1)" && ` +
+		`FILE="$(redis-cli --raw CONFIG GET dbfilename | tail -n 1)" && ` +
+		`mv /tmp/pcc-import.rdb "$DIR/$FILE" && ` +
+		`VERSION="$(redis-cli --raw INFO server | grep '^redis_version:' | cut -d: -f2 | cut -d. -f1)" && ` +
+		`if [ "${VERSION:-0}" -lt 6 ] || ! redis-cli DEBUG RELOAD NOSAVE 2>/dev/null | grep -q OK; then ` +
+		`redis-cli SHUTDOWN NOSAVE >/dev/null 2>&1; ` +
+		`for i in $(seq 1 30); do sleep 1; redis-cli PING 2>/dev/null | grep -q PONG && break; done; ` +
+		`redis-cli PING 2>/dev/null | grep -q PONG; ` +
+		`fi`
+}
+
+// Recreate returns the command to flush a logical database.
+func (d redisDriver) Recreate(s def.Service, database string) string {
+	return d.Shell(s, database) + " FLUSHDB"
+}
+
+// RemoteDump returns the command to dump a RDB snapshot on Platform.sh.
+func (redisDriver) RemoteDump(s def.Service, database string, rel map[string]interface{}, opts DatabaseDumpOptions) string {
+	return fmt.Sprintf(
+		"redis-cli -h %s -p %s --rdb /tmp/pcc-dump.rdb >/dev/null 2>&1 && cat /tmp/pcc-dump.rdb && rm -f /tmp/pcc-dump.rdb",
+		databaseRelationshipValue(rel, "host"),
+		databaseRelationshipValue(rel, "port"),
+	)
+}
+
+// RemoteImport returns an empty command as RDB snapshots cannot be loaded over the network.
+func (redisDriver) RemoteImport(s def.Service, database string, rel map[string]interface{}) string {
+	return ""
+}
@@ -0,0 +1,280 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// databaseSanitizeFlagPath is the path of the file, inside the database container, that flags a database as not yet sanitized.
+const databaseSanitizeFlagPath = "/mnt/data/.pcc_unsanitized"
+
+// sanitizeSQLDialect defines the SQL syntax differences between database engines used by sanitize rules.
+type sanitizeSQLDialect struct {
+	quote string
+	hash  string
+	email string
+}
+
+// sanitizeSQLDialects maps database drivers to their SQL dialect.
+var sanitizeSQLDialects = map[databaseDriver]sanitizeSQLDialect{
+	mysqlDriver{}: {
+		quote: "`",
+		hash:  "SHA2(%s, 256)",
+		email: "CONCAT(LEFT(SHA2(%s, 256), 16), '@example.com')",
+	},
+	postgresDriver{}: {
+		quote: `"`,
+		hash:  "md5(%s)",
+		email: "left(md5(%s), 16) || '@example.com'",
+	},
+}
+
+// identifier returns the quoted identifier.
+func (d sanitizeSQLDialect) identifier(name string) string {
+	return d.quote + strings.ReplaceAll(name, d.quote, d.quote+d.quote) + d.quote
+}
+
+// SanitizeRules returns the sanitize rules of all applications and of the sanitize file option.
+func (p *Project) SanitizeRules() ([]*def.AppSanitizeRule, error) {
+	out := make([]*def.AppSanitizeRule, 0)
+	for _, app := range p.Apps {
+		out = append(out, app.Sanitize...)
+	}
+	if path := p.GetOption(OptionSanitizeFile); path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.Path, path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rules, err := def.ParseSanitizeYaml(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid sanitize file '%s'", path)
+		}
+		out = append(out, rules...)
+	}
+	return out, nil
+}
+
+// sanitizeRuleTarget returns the service and database given sanitize rule applies to.
+// A rule without a service applies to the only sql database service of the project, it
+// must name the service when there are several. A rule without a database applies to
+// the first schema of the service only.
+func (p *Project) sanitizeRuleTarget(rule *def.AppSanitizeRule) (def.Service, string, error) {
+	services := make([]def.Service, 0)
+	for _, service := range p.Services {
+		_, driver := getDatabaseDriver(service)
+		if _, ok := sanitizeSQLDialects[driver]; !ok {
+			continue
+		}
+		if rule.Service != "" && rule.Service != service.Name {
+			continue
+		}
+		services = append(services, service)
+	}
+	switch len(services) {
+	case 0:
+		{
+			if rule.Service != "" {
+				return def.Service{}, "", errors.Wrapf(ErrSanitizeNotSupported, "service '%s' not found or not a sql database", rule.Service)
+			}
+			return def.Service{}, "", errors.Wrap(ErrSanitizeNotSupported, "project has no sql database service")
+		}
+	case 1:
+		{
+			database := rule.Database
+			if database == "" {
+				database = p.GetDatabases(services[0])[0]
+			}
+			return services[0], database, nil
+		}
+	}
+	return def.Service{}, "", errors.Wrapf(ErrSanitizeServiceRequired, "rule '%s'", rule.String())
+}
+
+// GetSanitizeSQL returns the SQL statement for given sanitize rule and database service.
+func (p *Project) GetSanitizeSQL(service def.Service, rule *def.AppSanitizeRule) (string, error) {
+	_, driver := getDatabaseDriver(service)
+	dialect, ok := sanitizeSQLDialects[driver]
+	if !ok {
+		return "", errors.Wrapf(ErrSanitizeNotSupported, "service '%s' is not a sql database", service.Name)
+	}
+	if rule.Action == def.SanitizeActionSQL {
+		return strings.TrimSuffix(strings.TrimSpace(rule.SQL), ";") + ";", nil
+	}
+	table := dialect.identifier(rule.Table)
+	if rule.Action == def.SanitizeActionTruncate {
+		return fmt.Sprintf("TRUNCATE TABLE %s;", table), nil
+	}
+	column := dialect.identifier(rule.Column)
+	value := ""
+	where := rule.Where
+	switch rule.Action {
+	case def.SanitizeActionHash:
+		{
+			value = fmt.Sprintf(dialect.hash, column)
+			break
+		}
+	case def.SanitizeActionEmail:
+		{
+			value = fmt.Sprintf(dialect.email, column)
+			break
+		}
+	case def.SanitizeActionNull:
+		{
+			value = "NULL"
+			break
+		}
+	case def.SanitizeActionSet:
+		{
+			value = "'" + strings.ReplaceAll(rule.Value, "'", "''") + "'"
+			break
+		}
+	default:
+		{
+			return "", errors.Wrapf(ErrInvalidDefinition, "invalid sanitize action '%s'", rule.Action)
+		}
+	}
+	// leave null values alone when deriving the value from the column
+	if rule.Action == def.SanitizeActionHash || rule.Action == def.SanitizeActionEmail {
+		if where != "" {
+			where = fmt.Sprintf("(%s) AND ", where)
+		}
+		where += fmt.Sprintf("%s IS NOT NULL", column)
+	}
+	if where != "" {
+		return fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s;", table, column, value, where), nil
+	}
+	return fmt.Sprintf("UPDATE %s SET %s = %s;", table, column, value), nil
+}
+
+// databaseSanitizeFlag returns the path to the flag file of given database.
+func databaseSanitizeFlag(database string) string {
+	return databaseSanitizeFlagPath + "." + database
+}
+
+// DatabaseSanitizeFlagSet flags given database as not sanitized.
+func (p *Project) DatabaseSanitizeFlagSet(service def.Service, database string) error {
+	c := p.NewContainer(service)
+	_, err := c.containerHandler.ContainerCommand(
+		c.Config.GetContainerName(),
+		"root",
+		[]string{"touch", databaseSanitizeFlag(database)},
+		nil,
+	)
+	return errors.WithStack(err)
+}
+
+// DatabaseSanitizeFlagged returns true if given database is flagged as not sanitized.
+func (p *Project) DatabaseSanitizeFlagged(service def.Service, database string) (bool, error) {
+	c := p.NewContainer(service)
+	if _, err := c.containerHandler.ContainerCommand(
+		c.Config.GetContainerName(),
+		"root",
+		[]string{"test", "-e", databaseSanitizeFlag(database)},
+		nil,
+	); err != nil {
+		if errors.Is(err, container.ErrCommandExited) {
+			return false, nil
+		}
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// DatabaseSanitize runs the sanitize rules that apply to given database and removes its not sanitized flag.
+// The flag is left in place if any rule fails.
+func (p *Project) DatabaseSanitize(service def.Service, database string) error {
+	rules, err := p.SanitizeRules()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	done := output.Duration(fmt.Sprintf("Sanitize %s:%s.", service.Name, database))
+	c := p.NewContainer(service)
+	for _, rule := range rules {
+		ruleService, ruleDatabase, err := p.sanitizeRuleTarget(rule)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if ruleService.Name != service.Name || ruleDatabase != database {
+			continue
+		}
+		sql, err := p.GetSanitizeSQL(service, rule)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		output.LogDebug(fmt.Sprintf("Sanitize %s:%s.", service.Name, database), sql)
+		if _, err := c.containerHandler.ContainerShell(
+			c.Config.GetContainerName(),
+			"root",
+			[]string{"sh", "-c", p.GetDatabaseShellCommand(service, database)},
+			strings.NewReader(sql+"\n"),
+		); err != nil {
+			return errors.Wrapf(ErrSanitizeFailed, "rule '%s' failed on %s:%s, %s", rule.String(), service.Name, database, err)
+		}
+	}
+	if _, err := c.containerHandler.ContainerCommand(
+		c.Config.GetContainerName(),
+		"root",
+		[]string{"rm", "-f", databaseSanitizeFlag(database)},
+		nil,
+	); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// DatabaseSanitizeAll runs all sanitize rules against the databases they apply to.
+func (p *Project) DatabaseSanitizeAll() error {
+	rules, err := p.SanitizeRules()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(rules) == 0 {
+		output.Info("No sanitize rules defined.")
+		return nil
+	}
+	done := output.Duration("Sanitize databases.")
+	sanitized := map[string]bool{}
+	for _, rule := range rules {
+		service, database, err := p.sanitizeRuleTarget(rule)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if sanitized[service.Name+":"+database] {
+			continue
+		}
+		sanitized[service.Name+":"+database] = true
+		if err := p.DatabaseSanitize(service, database); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	done()
+	return nil
+}
@@ -0,0 +1,317 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const databaseSnapshotDir = "snapshots"
+const databaseSnapshotMetaFile = "snapshot.json"
+
+// databaseSnapshotContainerPath is the compressed dump path inside the container, it is kept
+// out of the data volume so the dump does not use up the service disk.
+const databaseSnapshotContainerPath = "/tmp/pcc-snapshot-%s-%d.gz"
+
+var databaseSnapshotNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// DatabaseSnapshot defines a named snapshot of the databases of a service.
+type DatabaseSnapshot struct {
+	Name      string    `json:"name"`
+	Service   string    `json:"service"`
+	Type      string    `json:"type"`
+	Format    string    `json:"format"`
+	Databases []string  `json:"databases"`
+	Size      int64     `json:"size"`
+	Created   time.Time `json:"created"`
+}
+
+// DatabaseSnapshotCreate dumps all databases of given service in to a new named snapshot.
+func (p *Project) DatabaseSnapshotCreate(service def.Service, name string) (DatabaseSnapshot, error) {
+	if !databaseSnapshotNameRegex.MatchString(name) {
+		return DatabaseSnapshot{}, errors.Wrapf(ErrInvalidSnapshotName, "invalid snapshot name '%s'", name)
+	}
+	WarnDatabaseEphemeral(service)
+	snapshot := DatabaseSnapshot{
+		Name:      name,
+		Service:   service.Name,
+		Type:      service.Type,
+		Format:    p.GetDatabaseDumpFormat(service),
+		Databases: p.GetDatabases(service),
+		Created:   time.Now(),
+	}
+	if snapshot.Format == "" {
+		return snapshot, errors.Wrapf(ErrInvalidDefinition, "service '%s' is not a database", service.Name)
+	}
+	path := p.databaseSnapshotPath(service.Name, name)
+	if _, err := os.Stat(path); err == nil {
+		return snapshot, errors.Wrapf(ErrSnapshotExists, "snapshot '%s' already exists for '%s'", name, service.Name)
+	}
+	done := output.Duration(fmt.Sprintf("Create snapshot '%s' of '%s.'", name, service.Name))
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return snapshot, errors.WithStack(err)
+	}
+	c := p.NewContainer(service)
+	for i, database := range snapshot.Databases {
+		done2 := output.Duration(fmt.Sprintf("Dump '%s.'", database))
+		containerPath := fmt.Sprintf(databaseSnapshotContainerPath, name, i)
+		// dump is compressed as it is written, the uncompressed dump is never stored
+		if _, err := c.containerHandler.ContainerCommand(
+			c.Config.GetContainerName(),
+			"root",
+			[]string{"bash", "-c", fmt.Sprintf(
+				"set -o pipefail; %s | gzip > %s", p.GetDatabaseDumpCommand(service, database), containerPath,
+			)},
+			nil,
+		); err != nil {
+			c.containerHandler.ContainerCommand(c.Config.GetContainerName(), "root", []string{"rm", "-f", containerPath}, nil)
+			os.RemoveAll(path)
+			return snapshot, errors.WithStack(err)
+		}
+		f, err := os.Create(filepath.Join(path, strconv.Itoa(i)+".gz"))
+		if err != nil {
+			os.RemoveAll(path)
+			return snapshot, errors.WithStack(err)
+		}
+		err = c.Download(containerPath, f)
+		f.Close()
+		if err != nil {
+			os.RemoveAll(path)
+			return snapshot, errors.WithStack(err)
+		}
+		if _, err := c.containerHandler.ContainerCommand(
+			c.Config.GetContainerName(),
+			"root",
+			[]string{"rm", "-f", containerPath},
+			nil,
+		); err != nil {
+			return snapshot, errors.WithStack(err)
+		}
+		done2()
+	}
+	// write metadata
+	var err error
+	if snapshot.Size, err = databaseSnapshotSize(path); err != nil {
+		return snapshot, errors.WithStack(err)
+	}
+	if err := p.writeDatabaseSnapshot(snapshot); err != nil {
+		os.RemoveAll(path)
+		return snapshot, errors.WithStack(err)
+	}
+	done()
+	return snapshot, nil
+}
+
+// DatabaseSnapshotList returns all database snapshots of the project, service may be empty to list all services.
+func (p *Project) DatabaseSnapshotList(service string) ([]DatabaseSnapshot, error) {
+	out := make([]DatabaseSnapshot, 0)
+	services, err := ioutil.ReadDir(filepath.Join(config.Path(), databaseSnapshotDir, p.ID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	for _, s := range services {
+		if !s.IsDir() || (service != "" && s.Name() != service) {
+			continue
+		}
+		names, err := ioutil.ReadDir(filepath.Join(config.Path(), databaseSnapshotDir, p.ID, s.Name()))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, n := range names {
+			snapshot, err := p.DatabaseSnapshotGet(s.Name(), n.Name())
+			if err != nil {
+				if errors.Is(err, ErrSnapshotNotFound) {
+					continue
+				}
+				return nil, errors.WithStack(err)
+			}
+			out = append(out, snapshot)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out, nil
+}
+
+// DatabaseSnapshotGet returns the snapshot with given name for given service.
+func (p *Project) DatabaseSnapshotGet(service string, name string) (DatabaseSnapshot, error) {
+	snapshot := DatabaseSnapshot{}
+	if !databaseSnapshotNameRegex.MatchString(name) {
+		return snapshot, errors.Wrapf(ErrInvalidSnapshotName, "invalid snapshot name '%s'", name)
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(p.databaseSnapshotPath(service, name), databaseSnapshotMetaFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snapshot, errors.Wrapf(ErrSnapshotNotFound, "snapshot '%s' not found for '%s'", name, service)
+		}
+		return snapshot, errors.WithStack(err)
+	}
+	return snapshot, errors.WithStack(json.Unmarshal(raw, &snapshot))
+}
+
+// DatabaseSnapshotRestore recreates the databases of given service from the named snapshot.
+// The restore is refused if the service type or version differs from the snapshot.
+func (p *Project) DatabaseSnapshotRestore(service def.Service, name string) error {
+	snapshot, err := p.DatabaseSnapshotGet(service.Name, name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !databaseSnapshotCompatible(snapshot.Type, service.Type) {
+		return errors.Wrapf(
+			ErrSnapshotMismatch, "snapshot '%s' was taken from %s, service '%s' is %s",
+			name, snapshot.Type, service.Name, service.Type,
+		)
+	}
+	done := output.Duration(fmt.Sprintf("Restore snapshot '%s' of '%s.'", name, service.Name))
+	path := p.databaseSnapshotPath(service.Name, name)
+	c := p.NewContainer(service)
+	for i, database := range snapshot.Databases {
+		done2 := output.Duration(fmt.Sprintf("Restore '%s.'", database))
+		importCmd := p.GetDatabaseImportCommand(service, database, snapshot.Format)
+		if importCmd == "" {
+			return errors.Wrapf(ErrInvalidDatabaseFormat, "cannot import %s dump in to '%s'", snapshot.Format, service.Name)
+		}
+		containerPath := fmt.Sprintf(databaseSnapshotContainerPath, name, i)
+		f, err := os.Open(filepath.Join(path, strconv.Itoa(i)+".gz"))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = c.Upload(containerPath, f)
+		f.Close()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if _, err := c.containerHandler.ContainerCommand(
+			c.Config.GetContainerName(),
+			"root",
+			[]string{"sh", "-c", p.GetDatabaseRecreateCommand(service, database)},
+			nil,
+		); err != nil {
+			return errors.WithStack(err)
+		}
+		exitCode, err := c.containerHandler.ContainerCommand(
+			c.Config.GetContainerName(),
+			"root",
+			[]string{"bash", "-c", fmt.Sprintf(
+				"set -o pipefail; zcat %[1]s | %[2]s && rm -f %[1]s", containerPath, importCmd,
+			)},
+			nil,
+		)
+		if err != nil {
+			return errors.WithStack(err)
+		} else if exitCode != 0 {
+			return errors.Wrapf(container.ErrCommandExited, "import of '%s' exited with code %d", database, exitCode)
+		}
+		done2()
+	}
+	done()
+	return nil
+}
+
+// DatabaseSnapshotDelete deletes the named snapshot of given service.
+func (p *Project) DatabaseSnapshotDelete(service string, name string) error {
+	if _, err := p.DatabaseSnapshotGet(service, name); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.RemoveAll(p.databaseSnapshotPath(service, name)))
+}
+
+func (p *Project) databaseSnapshotPath(service string, name string) string {
+	return filepath.Join(config.Path(), databaseSnapshotDir, p.ID, service, name)
+}
+
+func (p *Project) writeDatabaseSnapshot(snapshot DatabaseSnapshot) error {
+	raw, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	path := p.databaseSnapshotPath(snapshot.Service, snapshot.Name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(ioutil.WriteFile(filepath.Join(path, databaseSnapshotMetaFile), raw, 0644))
+}
+
+// databaseSnapshotCompatible returns true if a snapshot taken from service type a can be restored in to service type b.
+// The service types must match and the versions must have the same major and minor version.
+func databaseSnapshotCompatible(a string, b string) bool {
+	nameA, versionA := databaseParseType(a)
+	nameB, versionB := databaseParseType(b)
+	if nameA != nameB {
+		return false
+	}
+	if versionA == nil || versionB == nil {
+		return a == b
+	}
+	return versionA[0] == versionB[0] && versionA[1] == versionB[1]
+}
+
+// databaseParseType returns the name and the major and minor version of a service type, the
+// version is nil if it is not numeric.
+func databaseParseType(t string) (string, []int) {
+	parts := strings.SplitN(t, ":", 2)
+	if len(parts) < 2 {
+		return parts[0], nil
+	}
+	version := []int{0, 0}
+	for i, v := range strings.Split(parts[1], ".") {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return parts[0], nil
+		}
+		if i < len(version) {
+			version[i] = n
+		}
+	}
+	return parts[0], version
+}
+
+// databaseSnapshotSize returns the total size of the files in a snapshot directory.
+func databaseSnapshotSize(path string) (int64, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	size := int64(0)
+	for _, f := range files {
+		size += f.Size()
+	}
+	return size, nil
+}
@@ -199,12 +199,15 @@ func (p *Project) GetDefinitionEnvironmentVariables(d interface{}) map[string]st
 			break
 		}
 	}
+	// rebuild PLATFORM_VARIABLES with sensitive values decrypted
+	varJSON, _ := json.Marshal(p.decryptVariables(p.GetDefinitionVariables(d)))
+	envVars["PLATFORM_VARIABLES"] = base64.StdEncoding.EncodeToString(varJSON)
 	// append environment variables from .platform.app.yaml
 	for k, v := range vars.GetStringSubMap("env") {
 		envVars[k] = v
 	}
 	// append environment variables from project (var:set command)
-	for k, v := range p.Variables.GetStringSubMap("env") {
+	for k, v := range p.decryptVariables(p.Variables).GetStringSubMap("env") {
 		envVars[k] = v
 	}
 	return envVars
@@ -431,8 +434,9 @@ func (p *Project) GetDefinitionMountCommand(d interface{}) string {
 	return ""
 }
 
-// GetDefinitionStartOrder given list of definitions reorder them for optimal start order for relationships.
-func (p *Project) GetDefinitionStartOrder(defs []interface{}) ([]interface{}, error) {
+// GetDefinitionStartOrder given list of definitions group them in to dependency levels for relationships.
+// Definitions in a level only have relationships to definitions in previous levels.
+func (p *Project) GetDefinitionStartOrder(defs []interface{}) ([][]interface{}, error) {
 	getRelNames := func(d interface{}) []string {
 		rels := map[string]string{}
 		switch d := d.(type) {
@@ -459,58 +463,43 @@ func (p *Project) GetDefinitionStartOrder(defs []interface{}) ([]interface{}, er
 		}
 		return out
 	}
-	nameList := make([]string, 0)
-	out := make([]interface{}, 0)
-	hasDef := func(def interface{}) bool {
-		name := p.GetDefinitionName(def)
-		for _, outDef := range out {
-			if p.GetDefinitionName(outDef) == name {
-				return true
-			}
-		}
-		return false
-	}
+	started := make(map[string]bool)
 	hasAllRels := func(rels []string) bool {
 		for _, rel := range rels {
-			has := false
-			for _, name := range nameList {
-				if rel == name {
-					has = true
-					break
-				}
-			}
-			if !has {
+			if !started[rel] {
 				return false
 			}
 		}
 		return true
 	}
-	ittCount := 0
-	for len(out) < len(defs) {
-		ittCount++
+	out := make([][]interface{}, 0)
+	count := 0
+	for count < len(defs) {
+		level := make([]interface{}, 0)
 		for _, def := range defs {
-			if hasDef(def) {
+			if started[p.GetDefinitionName(def)] {
 				continue
 			}
-			name := p.GetDefinitionName(def)
-			rels := getRelNames(def)
-			if len(rels) == 0 || hasAllRels(rels) {
-				out = append(out, def)
-				nameList = append(nameList, name)
+			if hasAllRels(getRelNames(def)) {
+				level = append(level, def)
 			}
 		}
-		// assume circular relationship if more than 100 itterations are needed
-		if ittCount > 100 {
+		// no progress can be made, relationships are circular or missing
+		if len(level) == 0 {
 			invalidOut := make([]string, 0)
 			for _, def := range defs {
-				if hasDef(def) {
+				if started[p.GetDefinitionName(def)] {
 					continue
 				}
 				invalidOut = append(invalidOut, p.GetDefinitionName(def))
 			}
 			return nil, errors.Wrapf(ErrInvalidRelationship, "one or more relationships are invalid: %s", strings.Join(invalidOut, ","))
 		}
-
+		for _, def := range level {
+			started[p.GetDefinitionName(def)] = true
+		}
+		count += len(level)
+		out = append(out, level)
 	}
 	return out, nil
 }
@@ -0,0 +1,143 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// Definitions returns the parsed definitions of the project.
+func (p *Project) Definitions() def.Definitions {
+	return def.Definitions{
+		Apps:     p.Apps,
+		Services: p.Services,
+		Routes:   p.Routes,
+	}
+}
+
+// DiffOverrides returns the changes the pcc override yaml files make to the upstream definitions.
+func (p *Project) DiffOverrides() ([]def.DiffChange, error) {
+	upstream, err := loadDefinitions(p.Path, &p.globalConfig, true, p.GetOption(OptionDomainSuffix))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	overrides, err := loadDefinitions(p.Path, &p.globalConfig, false, p.GetOption(OptionDomainSuffix))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return def.Diff(upstream, overrides), nil
+}
+
+// DiffRevisions returns the changes to the definitions between two git revisions.
+// An empty newRev compares against the working tree.
+func (p *Project) DiffRevisions(oldRev string, newRev string) ([]def.DiffChange, error) {
+	done := output.Duration(fmt.Sprintf("Compare definitions at '%s' and '%s.'", oldRev, newRev))
+	old, err := p.revisionDefinitions(oldRev)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var new def.Definitions
+	if newRev == "" {
+		new, err = loadDefinitions(p.Path, &p.globalConfig, p.HasFlag(DisableYamlOverrides), p.GetOption(OptionDomainSuffix))
+	} else {
+		new, err = p.revisionDefinitions(newRev)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	done()
+	return def.Diff(old, new), nil
+}
+
+// revisionDefinitions parses the project definitions at given git revision.
+func (p *Project) revisionDefinitions(rev string) (def.Definitions, error) {
+	dir, err := ioutil.TempDir(os.TempDir(), "pcc-diff-")
+	if err != nil {
+		return def.Definitions{}, errors.WithStack(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := gitExtractPlatformFiles(p.Path, rev, dir); err != nil {
+		return def.Definitions{}, errors.WithStack(err)
+	}
+	defs, err := loadDefinitions(dir, &p.globalConfig, p.HasFlag(DisableYamlOverrides), p.GetOption(OptionDomainSuffix))
+	return defs, errors.WithStack(err)
+}
+
+// gitExtractPlatformFiles extracts the .platform files of the git tree at given revision in to dest.
+func gitExtractPlatformFiles(path string, rev string, dest string) error {
+	var stderr bytes.Buffer
+	// the pathspec wildcard also matches slashes so that nested .platform files are included
+	cmd := exec.Command("git", "archive", "--format=tar", rev, "--", "*.platform*")
+	cmd.Dir = path
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.WithStack(err)
+	}
+	tr := tar.NewReader(stdout)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			cmd.Wait()
+			return errors.WithStack(err)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.Contains("/"+header.Name, "/.platform") {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			cmd.Wait()
+			return errors.WithStack(err)
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			cmd.Wait()
+			return errors.WithStack(err)
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			cmd.Wait()
+			return errors.WithStack(err)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return errors.Wrapf(err, "git archive failed, %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
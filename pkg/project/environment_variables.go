@@ -25,9 +25,12 @@ import (
 	"strings"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/platformsh"
 )
 
 const entropySalt = "Dyt+&&*^dKfD9,$rZRA$|I^DLKr%<By"
+const defaultPlatformBranch = "pcc"
 
 // GetPlatformRoutes returns the PLATFORM_ROUTES environment variable.
 func (p *Project) GetPlatformRoutes() string {
@@ -120,6 +123,52 @@ func (p *Project) GetPlatformApplication(d interface{}) string {
 	return base64.StdEncoding.EncodeToString(pfAppJSON)
 }
 
+// mapPlatformBranch resolves the Platform.sh environment mapped to the current Git branch.
+// Only environments cached by the last sync are used, so no API request is made. Branches
+// without an environment keep their own name instead of falling back to the main environment.
+func (p *Project) mapPlatformBranch() {
+	p.pshBranchOnce.Do(func() {
+		p.pshBranch = defaultPlatformBranch
+		p.pshEnvironment = defaultPlatformBranch
+		if p.PlatformSH == nil || p.PlatformSH.ID == "" {
+			return
+		}
+		branch, err := platformsh.GitBranch(p.PlatformSH.LocalPath)
+		if err != nil || branch == "" {
+			output.LogDebug("Could not determine Git branch.", err)
+			return
+		}
+		p.pshBranch = branch
+		p.pshEnvironment = branch
+		envs, err := p.PlatformSH.CachedEnvironments()
+		if err != nil {
+			output.LogDebug("Could not load cached Platform.sh environments.", err)
+			return
+		}
+		env := (&platformsh.Project{Environments: envs}).BranchEnvironment(branch)
+		if env == nil {
+			return
+		}
+		p.pshBranch = env.Name
+		p.pshEnvironment = env.MachineName
+		if p.pshEnvironment == "" {
+			p.pshEnvironment = env.Name
+		}
+	})
+}
+
+// GetPlatformBranch returns the PLATFORM_BRANCH environment variable.
+func (p *Project) GetPlatformBranch() string {
+	p.mapPlatformBranch()
+	return p.pshBranch
+}
+
+// GetPlatformEnvironment returns the PLATFORM_ENVIRONMENT environment variable.
+func (p *Project) GetPlatformEnvironment() string {
+	p.mapPlatformBranch()
+	return p.pshEnvironment
+}
+
 // GetPlatformVariables returns the PLATFORM_VARIABLES environment variable.
 func (p *Project) GetPlatformVariables(d interface{}) string {
 	varJSON, _ := json.Marshal(p.GetDefinitionVariables(d))
@@ -159,11 +208,11 @@ func (p *Project) GetPlatformEnvironmentVariables(d interface{}) map[string]stri
 		"PLATFORM_PROJECT":          p.ID,
 		"PLATFORM_PROJECT_ENTROPY":  p.GetPlatformEntropy(),
 		"PLATFORM_APPLICATION_NAME": name,
-		"PLATFORM_BRANCH":           "pcc",
+		"PLATFORM_BRANCH":           p.GetPlatformBranch(),
 		"PLATFORM_DIR":              def.AppDir,
 		"PLATFORM_APP_DIR":          def.AppDir,
 		"PLATFORM_TREE_ID":          "-",
-		"PLATFORM_ENVIRONMENT":      "pcc",
+		"PLATFORM_ENVIRONMENT":      p.GetPlatformEnvironment(),
 		"PLATFORM_VARIABLES":        p.GetPlatformVariables(d),
 		"PLATFORM_RELATIONSHIPS":    p.GetPlatformRelationships(d),
 		"PLATFORM_ROUTES":           p.GetPlatformRoutes(),
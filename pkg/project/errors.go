@@ -32,4 +32,40 @@ var (
 	ErrContainerRunning = errors.New("container already running")
 	// ErrRegistryNotDefined is returned when a registry is not defined.
 	ErrRegistryNotDefined = errors.New("registry not defined")
+	// ErrCronNotFound is returned when a cron job is not found.
+	ErrCronNotFound = errors.New("cron not found")
+	// ErrCronAmbiguous is returned when a cron name matches more than one application.
+	ErrCronAmbiguous = errors.New("cron name is ambiguous")
+	// ErrCronRunning is returned when a cron job is already running.
+	ErrCronRunning = errors.New("cron already running")
+	// ErrCronDisabled is returned when cron jobs are not enabled for the project.
+	ErrCronDisabled = errors.New("cron jobs are disabled, enable with the enable_cron flag")
+	// ErrCronSchedulerDisabled is returned when the cron scheduler is started while the application containers run the cron jobs.
+	ErrCronSchedulerDisabled = errors.New("cron scheduler is disabled, enable with the enable_cron_scheduler flag")
+	// ErrStartCanceled is returned when starting a container is canceled due to another failure.
+	ErrStartCanceled = errors.New("start canceled")
+	// ErrInvalidDatabaseFormat is returned when a database dump format is not supported by the database service.
+	ErrInvalidDatabaseFormat = errors.New("invalid database dump format")
+	// ErrNotPHPApplication is returned when a PHP only feature is used on another runtime.
+	ErrNotPHPApplication = errors.New("application is not a php application")
+	// ErrInvalidSnapshotName is returned when a database snapshot name contains invalid characters.
+	ErrInvalidSnapshotName = errors.New("invalid snapshot name")
+	// ErrSnapshotExists is returned when creating a database snapshot with a name that is already used.
+	ErrSnapshotExists = errors.New("snapshot already exists")
+	// ErrSnapshotNotFound is returned when a database snapshot is not found.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrSnapshotMismatch is returned when restoring a database snapshot in to a service of another type or version.
+	ErrSnapshotMismatch = errors.New("snapshot service type or version does not match")
+	// ErrDatabaseSyncChecksum is returned when a database dump downloaded from Platform.sh is corrupt.
+	ErrDatabaseSyncChecksum = errors.New("database sync checksum mismatch")
+	// ErrSanitizeNotSupported is returned when a sanitize rule targets a service that is not a sql database.
+	ErrSanitizeNotSupported = errors.New("sanitize rules are only supported by sql databases")
+	// ErrSanitizeServiceRequired is returned when a sanitize rule does not name its service while the project has more than one sql database.
+	ErrSanitizeServiceRequired = errors.New("sanitize rule must name its service when there is more than one sql database")
+	// ErrSanitizeFailed is returned when a sanitize rule fails, the database is left flagged as not sanitized.
+	ErrSanitizeFailed = errors.New("database sanitize failed, database is flagged as not sanitized")
+	// ErrPushMainEnvironment is returned when pushing to the main Platform.sh environment without forcing it.
+	ErrPushMainEnvironment = errors.New("refusing to push to the main platform.sh environment, use --force-main to override")
+	// ErrLogFileNotFound is returned when a log file is unknown or not available for a container.
+	ErrLogFileNotFound = errors.New("log file not found")
 )
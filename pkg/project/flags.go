@@ -90,6 +90,8 @@ const (
 const (
 	// EnableCron enables cron jobs.
 	EnableCron = "enable_cron"
+	// EnableCronScheduler runs cron jobs with the Platform.CC cron scheduler instead of the application container.
+	EnableCronScheduler = "enable_cron_scheduler"
 	// EnableWorkers enables workers.
 	EnableWorkers = "enable_workers"
 	// EnableServiceRoutes enables routes to services like Varnish.
@@ -145,6 +147,7 @@ func (f *Flags) UnmarshalJSON(data []byte) error {
 func (f Flags) Descriptions() map[string]string {
 	return map[string]string{
 		EnableCron:                "Enables cron jobs.",
+		EnableCronScheduler:       "Run cron jobs with the cron scheduler (project:cron:start) instead of the application container.",
 		EnableWorkers:             "Enables workers.",
 		EnableServiceRoutes:       "Enable routes to services like Varnish.",
 		EnablePHPOpcache:          "Enables PHP Opcache.",
@@ -0,0 +1,125 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const healthCheckInterval = time.Second
+
+// healthCheckCommands maps service type names to a command that exits zero once the service is ready.
+var healthCheckCommands = map[string]string{
+	"php":              "[ -S /run/app.sock ] && pgrep php-fpm > /dev/null",
+	"mysql":            "mysqladmin -h 127.0.0.1 ping > /dev/null",
+	"mariadb":          "mysqladmin -h 127.0.0.1 ping > /dev/null",
+	"oracle-mysql":     "mysqladmin -h 127.0.0.1 ping > /dev/null",
+	"postgresql":       "pg_isready -h 127.0.0.1 > /dev/null",
+	"redis":            "redis-cli ping | grep -q PONG",
+	"redis-persistent": "redis-cli ping | grep -q PONG",
+	"solr":             `curl -sf "http://127.0.0.1:8080/solr/admin/cores?action=STATUS&wt=json" > /dev/null`,
+	"elasticsearch":    fmt.Sprintf(`curl -sf "%s/_cluster/health" > /dev/null`, elasticsearchURL),
+	"opensearch":       fmt.Sprintf(`curl -sf "%s/_cluster/health" > /dev/null`, elasticsearchURL),
+	"mongodb":          fmt.Sprintf(`%s --quiet --eval "db.runCommand({ping: 1}).ok" > /dev/null`, mongodbShell),
+}
+
+// healthCheckCommand returns the readiness probe for given container type and service type, empty if there is none.
+func healthCheckCommand(objectType container.ObjectContainerType, serviceType string) string {
+	// workers have no service to probe
+	if objectType == container.ObjectContainerWorker || objectType == container.ObjectContainerRouter {
+		return ""
+	}
+	return healthCheckCommands[strings.Split(serviceType, ":")[0]]
+}
+
+// CheckHealth runs the readiness probe for the container with given status and updates its health fields.
+// Running containers without a probe are considered healthy.
+func CheckHealth(ch container.Interface, status *container.Status) {
+	status.LastCheck = time.Now()
+	status.Healthy = false
+	if !status.Running {
+		return
+	}
+	cmd := healthCheckCommand(status.ObjectType, status.Type)
+	if cmd == "" {
+		status.Healthy = true
+		return
+	}
+	id := status.ID
+	if id == "" {
+		id = status.Name
+	}
+	if _, err := ch.ContainerCommand(id, "root", []string{"sh", "-c", cmd}, nil); err != nil {
+		output.LogDebug(fmt.Sprintf("Health check failed for '%s.'", status.Name), err)
+		return
+	}
+	status.Healthy = true
+}
+
+// WaitHealthy waits for the containers of given definitions to pass their readiness probes.
+// Containers that are not ready before the health timeout option are reported and skipped.
+func (p *Project) WaitHealthy(defs []interface{}) error {
+	timeout, _ := strconv.Atoi(p.GetOption(OptionHealthTimeout))
+	if timeout <= 0 {
+		return nil
+	}
+	done := output.Duration("Wait for containers to become ready.")
+	deadline := time.Now().Add(time.Second * time.Duration(timeout))
+	waiting := make([]Container, 0)
+	for _, d := range defs {
+		waiting = append(waiting, p.NewContainer(d))
+	}
+	for len(waiting) > 0 {
+		next := make([]Container, 0)
+		for _, c := range waiting {
+			status, err := c.containerHandler.ContainerStatus(c.Config.GetContainerName())
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			status.ObjectType = c.Config.ObjectType
+			status.Type = p.GetDefinitionType(c.Definition)
+			status.ID = c.Config.GetContainerName()
+			CheckHealth(c.containerHandler, &status)
+			if !status.Healthy {
+				next = append(next, c)
+			}
+		}
+		waiting = next
+		if len(waiting) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			for _, c := range waiting {
+				output.Warn(fmt.Sprintf(
+					"Container '%s' not ready after %d seconds.", c.Config.GetContainerName(), timeout,
+				))
+			}
+			break
+		}
+		time.Sleep(healthCheckInterval)
+	}
+	done()
+	return nil
+}
@@ -0,0 +1,222 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// logReorderDelay is how long followed log lines are held back so lines from all containers are ordered by time.
+const logReorderDelay = 250 * time.Millisecond
+
+// LogEntry is a line of container log output.
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Message string    `json:"message"`
+}
+
+// LogOptions defines options for streaming project logs.
+type LogOptions struct {
+	Follow bool
+	Since  time.Time
+	Grep   *regexp.Regexp
+}
+
+// matches returns true if given log entry passes the filters.
+func (o LogOptions) matches(e LogEntry) bool {
+	if !o.Since.IsZero() && e.Time.Before(o.Since) {
+		return false
+	}
+	return o.Grep == nil || o.Grep.MatchString(e.Message)
+}
+
+// ParseLogSince parses a duration (i.e. 10m) or a timestamp in to the time logs should start at.
+func ParseLogSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.WithStack(fmt.Errorf("invalid since value '%s', expected a duration or timestamp", value))
+}
+
+// LogContainers returns the containers to read logs from, all of them if services is empty.
+// Services match either the definition name or the human readable name (i.e. app/name).
+func (p *Project) LogContainers(services []string) []Container {
+	defs := make([]interface{}, 0)
+	for _, a := range p.Apps {
+		defs = append(defs, a)
+		if p.HasFlag(EnableWorkers) {
+			for _, w := range a.Workers {
+				defs = append(defs, w)
+			}
+		}
+	}
+	for _, s := range p.Services {
+		if s.GetTypeName() == "network-storage" {
+			continue
+		}
+		defs = append(defs, s)
+	}
+	out := make([]Container, 0)
+	for _, d := range defs {
+		c := p.NewContainer(d)
+		if len(services) > 0 && !sliceContainsString(services, c.Name) && !sliceContainsString(services, c.Config.GetHumanName()) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// parseLogLine converts a timestamped container log line in to a log entry.
+func parseLogLine(source string, line string) LogEntry {
+	line = strings.TrimRight(line, "\r")
+	lineSplit := strings.SplitN(line, " ", 2)
+	if len(lineSplit) == 2 {
+		if t, err := time.Parse(time.RFC3339Nano, lineSplit[0]); err == nil {
+			return LogEntry{Time: t, Source: source, Message: lineSplit[1]}
+		}
+	}
+	return LogEntry{Time: time.Now(), Source: source, Message: line}
+}
+
+// streamLog passes each line of the container log to callback.
+func (c Container) streamLog(opts container.LogOptions, callback func(LogEntry)) error {
+	opts.Timestamps = true
+	rc, err := c.containerHandler.ContainerLog(c.Config.GetContainerName(), opts)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer rc.Close()
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		callback(parseLogLine(c.Config.GetHumanName(), scanner.Text()))
+	}
+	return errors.WithStack(scanner.Err())
+}
+
+// readLogs reads logs of all given containers concurrently, containers that can't be read are skipped.
+func readLogs(containers []Container, opts container.LogOptions, callback func(LogEntry)) {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	for _, c := range containers {
+		wg.Add(1)
+		go func(c Container) {
+			defer wg.Done()
+			if err := c.streamLog(opts, func(e LogEntry) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				callback(e)
+			}); err != nil {
+				output.LogDebug(fmt.Sprintf("Skip logs for container '%s.'", c.Config.GetContainerName()), err.Error())
+			}
+		}(c)
+	}
+	wg.Wait()
+}
+
+// sortLogEntries sorts log entries by time.
+func sortLogEntries(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+}
+
+// StreamLogs passes the log lines of given containers accepted by opts to callback interleaved by time,
+// waiting for new lines if opts.Follow is set.
+func StreamLogs(containers []Container, opts LogOptions, callback func(LogEntry) error) error {
+	// existing lines
+	start := time.Now()
+	entries := make([]LogEntry, 0)
+	readLogs(containers, container.LogOptions{Since: opts.Since}, func(e LogEntry) {
+		if opts.matches(e) && (!opts.Follow || e.Time.Before(start)) {
+			entries = append(entries, e)
+		}
+	})
+	sortLogEntries(entries)
+	for _, e := range entries {
+		if err := callback(e); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if !opts.Follow {
+		return nil
+	}
+	// follow new lines, held back briefly so they can be ordered
+	entryChan := make(chan LogEntry)
+	go func() {
+		readLogs(containers, container.LogOptions{Follow: true, Since: start}, func(e LogEntry) {
+			if opts.matches(e) && !e.Time.Before(start) {
+				entryChan <- e
+			}
+		})
+		close(entryChan)
+	}()
+	ticker := time.NewTicker(logReorderDelay)
+	defer ticker.Stop()
+	pending := make([]LogEntry, 0)
+	flush := func(until time.Time) error {
+		sortLogEntries(pending)
+		i := 0
+		for ; i < len(pending) && (until.IsZero() || pending[i].Time.Before(until)); i++ {
+			if err := callback(pending[i]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		pending = pending[i:]
+		return nil
+	}
+	for {
+		select {
+		case e, ok := <-entryChan:
+			{
+				if !ok {
+					return errors.WithStack(flush(time.Time{}))
+				}
+				pending = append(pending, e)
+				break
+			}
+		case <-ticker.C:
+			{
+				if err := flush(time.Now().Add(-logReorderDelay)); err != nil {
+					return errors.WithStack(err)
+				}
+				break
+			}
+		}
+	}
+}
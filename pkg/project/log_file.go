@@ -0,0 +1,184 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const (
+	// LogFileApp is the application log.
+	LogFileApp = "app"
+	// LogFilePHP is the PHP-FPM access log.
+	LogFilePHP = "php"
+	// LogFileAccess is the web server access log.
+	LogFileAccess = "access"
+	// LogFileError is the web server error log.
+	LogFileError = "error"
+	// LogFileDeploy is the deploy hook log.
+	LogFileDeploy = "deploy"
+	// LogFileCron is the cron job log.
+	LogFileCron = "cron"
+)
+
+// logFileTail is the number of existing lines to output from a log file.
+const logFileTail = 100
+
+// logFilePaths maps log file names to their path inside application containers.
+var logFilePaths = map[string]string{
+	LogFileApp:    "/var/log/app.log",
+	LogFilePHP:    "/var/log/php.access.log",
+	LogFileAccess: "/var/log/access.log",
+	LogFileError:  "/var/log/error.log",
+	LogFileDeploy: "/var/log/deploy.log",
+	LogFileCron:   "/var/log/cron.log",
+}
+
+// LogFileNames returns the list of log file names.
+func LogFileNames() []string {
+	return []string{LogFileApp, LogFilePHP, LogFileAccess, LogFileError, LogFileDeploy, LogFileCron}
+}
+
+// LogFilePath returns the path to given log file inside the container.
+func (c Container) LogFilePath(name string) (string, error) {
+	path, ok := logFilePaths[name]
+	if !ok {
+		return "", errors.Wrapf(ErrLogFileNotFound, "unknown log file '%s', expected one of %s", name, strings.Join(LogFileNames(), ", "))
+	}
+	switch d := c.Definition.(type) {
+	case def.App:
+		{
+			if name == LogFilePHP && !isPHPApp(d) {
+				return "", errors.WithStack(ErrNotPHPApplication)
+			}
+			return path, nil
+		}
+	case *def.AppWorker:
+		{
+			if name == LogFileApp || name == LogFileDeploy || name == LogFileCron {
+				return path, nil
+			}
+			break
+		}
+	}
+	return "", errors.Wrapf(ErrLogFileNotFound, "%s '%s' has no %s log", c.Config.ObjectType.TypeName(), c.Name, name)
+}
+
+// logLineWriter passes each line written to it to the callback as a log entry.
+type logLineWriter struct {
+	buf      bytes.Buffer
+	source   string
+	callback func(LogEntry) error
+}
+
+// Write implements io.Writer.
+func (w *logLineWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// keep partial line for the next write
+			w.buf.Reset()
+			w.buf.Write(line)
+			return len(b), nil
+		}
+		entry := LogEntry{
+			Time:    time.Now(),
+			Source:  w.source,
+			Message: strings.TrimRight(string(line), "\r\n"),
+		}
+		if err := w.callback(entry); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+}
+
+// streamLogFile passes the lines of given log file to callback, waiting for new lines if follow is set.
+func (c Container) streamLogFile(name string, follow bool, callback func(LogEntry) error) error {
+	path, err := c.LogFilePath(name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	output.LogInfo(fmt.Sprintf("Read log file '%s' for container '%s.'", path, c.Config.GetContainerName()))
+	cmd := []string{"tail", "-n", fmt.Sprintf("%d", logFileTail), path}
+	if follow {
+		cmd = []string{"tail", "-n", fmt.Sprintf("%d", logFileTail), "-F", path}
+	}
+	w := &logLineWriter{source: c.Config.GetHumanName(), callback: callback}
+	if _, err := c.containerHandler.ContainerCommand(c.Config.GetContainerName(), "root", cmd, w); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// StreamLogFile passes the lines of given log file accepted by opts.Grep from all given containers to callback,
+// waiting for new lines if opts.Follow is set. Containers without the log file are skipped.
+func StreamLogFile(containers []Container, name string, opts LogOptions, callback func(LogEntry) error) error {
+	if _, ok := logFilePaths[name]; !ok {
+		return errors.Wrapf(ErrLogFileNotFound, "unknown log file '%s', expected one of %s", name, strings.Join(LogFileNames(), ", "))
+	}
+	// only keep containers that have the log file
+	fileContainers := make([]Container, 0)
+	for _, c := range containers {
+		if _, err := c.LogFilePath(name); err != nil {
+			output.LogDebug(fmt.Sprintf("Skip log file for container '%s.'", c.Config.GetContainerName()), err.Error())
+			continue
+		}
+		fileContainers = append(fileContainers, c)
+	}
+	if len(fileContainers) == 0 {
+		return errors.Wrapf(ErrLogFileNotFound, "no container has a %s log", name)
+	}
+	var mutex sync.Mutex
+	filtered := func(e LogEntry) error {
+		if !opts.matches(e) {
+			return nil
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		return callback(e)
+	}
+	if !opts.Follow {
+		for _, c := range fileContainers {
+			if err := c.streamLogFile(name, false, filtered); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+	errChan := make(chan error, len(fileContainers))
+	for _, c := range fileContainers {
+		go func(c Container) {
+			errChan <- c.streamLogFile(name, true, filtered)
+		}(c)
+	}
+	for range fileContainers {
+		if err := <-errChan; err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/martinlindhe/base36"
@@ -46,26 +47,32 @@ var registries = map[string]string{
 	"psh": "docker.registry.platform.sh",
 }
 var defaultRegistry = "cc"
+var containerBackendOverride = ""
 
 const projectJSONFilename = ".platform_cc.json"
 
 // Project defines a platform.sh/cc project.
 type Project struct {
-	ID               string            `json:"id"`
-	Path             string            `json:"-"`
-	Apps             []def.App         `json:"-"`
-	Routes           []def.Route       `json:"-"`
-	Services         []def.Service     `json:"-"`
-	Variables        def.Variables     `json:"vars"`
-	Flags            Flags             `json:"flags"`   // local project flags
-	Options          map[Option]string `json:"options"` // local project options
-	relationships    []map[string]interface{}
-	containerHandler container.Interface
-	globalConfig     def.GlobalConfig
-	PlatformSH       *platformsh.Project `json:"-"`
-	slot             int                 // set volume slot
-	noCommit         bool                // flag that signifies apps should not be committed
-	noBuild          bool                // flag that signifies apps should not be built on start up
+	ID                string            `json:"id"`
+	Path              string            `json:"-"`
+	Apps              []def.App         `json:"-"`
+	Routes            []def.Route       `json:"-"`
+	Services          []def.Service     `json:"-"`
+	Variables         def.Variables     `json:"vars"`
+	Flags             Flags             `json:"flags"`   // local project flags
+	Options           map[Option]string `json:"options"` // local project options
+	Slots             Slots             `json:"slots"`   // volume slot metadata
+	relationships     []map[string]interface{}
+	relationshipsLock sync.Mutex
+	containerHandler  container.Interface
+	globalConfig      def.GlobalConfig
+	PlatformSH        *platformsh.Project `json:"-"`
+	pshBranch         string              // mapped platform.sh environment name
+	pshEnvironment    string              // mapped platform.sh environment machine name
+	pshBranchOnce     sync.Once           // ensures git branch is only mapped once
+	slot              int                 // set volume slot
+	noCommit          bool                // flag that signifies apps should not be committed
+	noBuild           bool                // flag that signifies apps should not be built on start up
 }
 
 // LoadFromPath loads a project from its path.
@@ -87,23 +94,22 @@ func LoadFromPath(path string, parseYaml bool) (*Project, error) {
 	}
 	// build project
 	path, _ = filepath.Abs(path)
-	// TODO allow container handler to be configured
-	containerHandler, err := container.NewDocker()
-	if err != nil {
-		return nil, err
-	}
 	o := &Project{
-		ID:               "",
-		Path:             path,
-		Variables:        make(map[string]interface{}),
-		Options:          make(map[Option]string),
-		PlatformSH:       psh,
-		containerHandler: containerHandler,
-		relationships:    make([]map[string]interface{}, 0),
-		slot:             1,
-		globalConfig:     gc,
+		ID:            "",
+		Path:          path,
+		Variables:     make(map[string]interface{}),
+		Options:       make(map[Option]string),
+		PlatformSH:    psh,
+		relationships: make([]map[string]interface{}, 0),
+		slot:          1,
+		globalConfig:  gc,
 	}
 	o.Load()
+	// container handler
+	o.containerHandler, err = container.NewHandler(o.ContainerBackend())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 	if o.ID == "" {
 		if psh != nil && psh.ID != "" {
 			o.ID = psh.ID
@@ -112,62 +118,15 @@ func LoadFromPath(path string, parseYaml bool) (*Project, error) {
 		}
 		o.Save()
 	}
-	// read app yaml
-	apps := make([]def.App, 0)
-	if parseYaml {
-		appYamlFiles := scanPlatformAppYaml(path, o.HasFlag(DisableYamlOverrides))
-		if len(appYamlFiles) == 0 {
-			return nil, errors.WithStack(fmt.Errorf("could not locate app yaml file"))
-		}
-		for _, appYamlFileList := range appYamlFiles {
-			app, err := def.ParseAppYamlFiles(appYamlFileList, &gc)
-			if err != nil {
-				return nil, errors.WithStack(err)
-			}
-			apps = append(apps, *app)
-		}
-		o.Apps = apps
-	}
-	// read services yaml
+	// read app, services and routes yaml
 	if parseYaml {
-		serviceYamlPaths := make([]string, 0)
-		for _, fn := range serviceYamlFilenames {
-			serviceYamlPaths = append(
-				serviceYamlPaths,
-				filepath.Join(path, fn),
-			)
-			if o.HasFlag(DisableYamlOverrides) {
-				break
-			}
-		}
-		o.Services, err = def.ParseServiceYamlFiles(serviceYamlPaths)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-	}
-	// read routes yaml
-	if parseYaml {
-		routesYamlPaths := make([]string, 0)
-		for _, fn := range routesYamlFilenames {
-			routesYamlPaths = append(
-				routesYamlPaths,
-				filepath.Join(path, fn),
-			)
-			if o.HasFlag(DisableYamlOverrides) {
-				break
-			}
-		}
-		o.Routes, err = def.ParseRoutesYamlFiles(routesYamlPaths)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		o.Routes, err = def.ExpandRoutes(
-			o.Routes,
-			o.GetOption(OptionDomainSuffix),
-		)
+		defs, err := loadDefinitions(path, &gc, o.HasFlag(DisableYamlOverrides), o.GetOption(OptionDomainSuffix))
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+		o.Apps = defs.Apps
+		o.Services = defs.Services
+		o.Routes = defs.Routes
 	}
 	if !parseYaml {
 		output.Info("Skipped (parseYaml=false).")
@@ -178,6 +137,60 @@ func LoadFromPath(path string, parseYaml bool) (*Project, error) {
 	return o, nil
 }
 
+// loadDefinitions parses the app, services and routes yaml files of the project at given path.
+func loadDefinitions(path string, gc *def.GlobalConfig, disableOverrides bool, domainSuffix string) (def.Definitions, error) {
+	out := def.Definitions{}
+	// read app yaml
+	appYamlFiles := scanPlatformAppYaml(path, disableOverrides)
+	if len(appYamlFiles) == 0 {
+		return out, errors.WithStack(fmt.Errorf("could not locate app yaml file"))
+	}
+	out.Apps = make([]def.App, 0)
+	for _, appYamlFileList := range appYamlFiles {
+		app, err := def.ParseAppYamlFiles(appYamlFileList, gc)
+		if err != nil {
+			return out, errors.WithStack(err)
+		}
+		out.Apps = append(out.Apps, *app)
+	}
+	// read services yaml
+	var err error
+	serviceYamlPaths := make([]string, 0)
+	for _, fn := range serviceYamlFilenames {
+		serviceYamlPaths = append(
+			serviceYamlPaths,
+			filepath.Join(path, fn),
+		)
+		if disableOverrides {
+			break
+		}
+	}
+	out.Services, err = def.ParseServiceYamlFiles(serviceYamlPaths)
+	if err != nil {
+		return out, errors.WithStack(err)
+	}
+	// read routes yaml
+	routesYamlPaths := make([]string, 0)
+	for _, fn := range routesYamlFilenames {
+		routesYamlPaths = append(
+			routesYamlPaths,
+			filepath.Join(path, fn),
+		)
+		if disableOverrides {
+			break
+		}
+	}
+	out.Routes, err = def.ParseRoutesYamlFiles(routesYamlPaths)
+	if err != nil {
+		return out, errors.WithStack(err)
+	}
+	out.Routes, err = def.ExpandRoutes(out.Routes, domainSuffix)
+	if err != nil {
+		return out, errors.WithStack(err)
+	}
+	return out, nil
+}
+
 func scanPlatformAppYaml(topPath string, disableOverrides bool) [][]string {
 	o := make([][]string, 0)
 	appYamlPaths := make([]string, 0)
@@ -280,60 +293,180 @@ func (p *Project) Start() error {
 		}
 	}
 	// determine start order
-	var err error
-	serviceList, err = p.GetDefinitionStartOrder(serviceList)
+	levels, err := p.GetDefinitionStartOrder(serviceList)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	// itterate and start
-	for _, service := range serviceList {
-		// start
-		c := p.NewContainer(service)
-		if err := c.Start(); err != nil {
-			if errors.Is(err, ErrContainerRunning) {
-				output.Info(fmt.Sprintf("Container '%s' is already running.", c.Config.GetContainerName()))
-				output.IndentLevel--
-				continue
-			}
+	// start each level, definitions within a level start concurrently
+	concurrency, _ := strconv.Atoi(p.GetOption(OptionStartConcurrency))
+	for _, level := range levels {
+		if err := p.startLevel(level, concurrency); err != nil {
 			return errors.WithStack(err)
 		}
-		// container type specific operations
-		switch c.Config.ObjectType {
-		case container.ObjectContainerApp, container.ObjectContainerWorker:
+	}
+	// wait for readiness
+	readyDefs := make([]interface{}, 0)
+	for _, level := range levels {
+		readyDefs = append(readyDefs, level...)
+	}
+	if err := p.WaitHealthy(readyDefs); err != nil {
+		return errors.WithStack(err)
+	}
+	// post-deploy
+	for _, level := range levels {
+		for _, service := range level {
+			c := p.NewContainer(service)
+			if err := c.PostDeploy(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	done()
+	return nil
+}
+
+// startLevel starts, builds and opens given definitions with at most concurrency running at once.
+// The first failure cancels all definitions that have not yet finished.
+func (p *Project) startLevel(defs []interface{}, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency == 1 || len(defs) == 1 {
+		for _, d := range defs {
+			c := p.NewContainer(d)
+			if _, err := p.startContainer(c, nil); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+	// containers write their output to the log file while running concurrently
+	msgs := make([]string, len(defs))
+	containers := make([]Container, len(defs))
+	for i, d := range defs {
+		containers[i] = p.NewContainer(d)
+		containers[i].quiet = true
+		containers[i].Config.Quiet = true
+		containers[i].stdout = output.LogWriter(fmt.Sprintf("[%s] ", containers[i].Config.GetContainerName()))
+		msgs[i] = fmt.Sprintf("Start %s '%s.'", containers[i].Config.ObjectType.TypeName(), containers[i].Name)
+	}
+	prog := output.Progress(msgs)
+	var firstErr error
+	var errLock sync.Mutex
+	cancel := make(chan struct{})
+	canceled := func() bool {
+		select {
+		case <-cancel:
 			{
-				// build
-				if !p.noBuild && !c.HasBuild() {
-					if err := c.Build(); err != nil {
-						return errors.WithStack(err)
-					}
-					if !p.noCommit {
-						if err := c.Commit(); err != nil {
-							return errors.WithStack(err)
-						}
+				return true
+			}
+		default:
+			{
+				return false
+			}
+		}
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range containers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if canceled() {
+				prog(i, output.ProgressMessageCancel, nil, nil)
+				return
+			}
+			skipped, err := p.startContainer(containers[i], canceled)
+			switch {
+			case errors.Is(err, ErrStartCanceled):
+				{
+					prog(i, output.ProgressMessageCancel, nil, nil)
+					break
+				}
+			case err != nil:
+				{
+					prog(i, output.ProgressMessageError, nil, nil)
+					output.LogError(err)
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = err
+						close(cancel)
 					}
+					errLock.Unlock()
+					break
+				}
+			case skipped:
+				{
+					prog(i, output.ProgressMessageSkip, nil, nil)
+					break
 				}
-				// setup mounts
-				if err := c.SetupMounts(); err != nil {
-					return errors.WithStack(err)
+			default:
+				{
+					prog(i, output.ProgressMessageDone, nil, nil)
 				}
 			}
+		}(i)
+	}
+	wg.Wait()
+	return errors.WithStack(firstErr)
+}
+
+// startContainer starts, builds and opens a single container.
+// Returns true if the container was already running. The optional canceled function
+// is checked between each step to stop early.
+func (p *Project) startContainer(c Container, canceled func() bool) (bool, error) {
+	checkCanceled := func() error {
+		if canceled != nil && canceled() {
+			return errors.WithStack(ErrStartCanceled)
 		}
-		// open + process relationships
-		rels, err := c.Open()
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		p.relationships = append(p.relationships, rels...)
+		return nil
 	}
-	// post-deploy
-	for _, service := range serviceList {
-		c := p.NewContainer(service)
-		if err := c.PostDeploy(); err != nil {
-			return errors.WithStack(err)
+	// start
+	if err := c.Start(); err != nil {
+		if errors.Is(err, ErrContainerRunning) {
+			c.info(fmt.Sprintf("Container '%s' is already running.", c.Config.GetContainerName()))
+			return true, nil
+		}
+		return false, errors.WithStack(err)
+	}
+	// container type specific operations
+	switch c.Config.ObjectType {
+	case container.ObjectContainerApp, container.ObjectContainerWorker:
+		{
+			// build
+			if !p.noBuild && !c.HasBuild() {
+				if err := checkCanceled(); err != nil {
+					return false, err
+				}
+				if err := c.Build(); err != nil {
+					return false, errors.WithStack(err)
+				}
+				if !p.noCommit {
+					if err := c.Commit(); err != nil {
+						return false, errors.WithStack(err)
+					}
+				}
+			}
+			// setup mounts
+			if err := c.SetupMounts(); err != nil {
+				return false, errors.WithStack(err)
+			}
 		}
 	}
-	done()
-	return nil
+	if err := checkCanceled(); err != nil {
+		return false, err
+	}
+	// open + process relationships
+	rels, err := c.Open()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	p.relationshipsLock.Lock()
+	p.relationships = append(p.relationships, rels...)
+	p.relationshipsLock.Unlock()
+	return false, nil
 }
 
 // Stop stops the project.
@@ -502,6 +635,39 @@ func (p *Project) SetContainerHandler(c container.Interface) {
 	p.containerHandler = c
 }
 
+// ContainerBackend returns the name of the container backend used by the project.
+func (p *Project) ContainerBackend() string {
+	if containerBackendOverride != "" {
+		return containerBackendOverride
+	}
+	return p.GetOption(OptionContainerBackend)
+}
+
+// SetContainerBackend overrides the container backend for all projects and global operations.
+func SetContainerBackend(name string) error {
+	if err := OptionContainerBackend.Validate(name); err != nil {
+		return errors.WithStack(err)
+	}
+	containerBackendOverride = name
+	return nil
+}
+
+// NewGlobalContainerHandler creates the container handler used outside of a project.
+func NewGlobalContainerHandler() (container.Interface, error) {
+	if containerBackendOverride != "" {
+		return container.NewHandler(containerBackendOverride)
+	}
+	gc, err := config.Load()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	name := gc.Options[string(OptionContainerBackend)]
+	if name == "" {
+		name = OptionContainerBackend.DefaultValue()
+	}
+	return container.NewHandler(name)
+}
+
 // SetGlobalConfig sets the global config, used for testing.
 func (p *Project) SetGlobalConfig(gc def.GlobalConfig) {
 	p.globalConfig = gc
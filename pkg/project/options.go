@@ -17,7 +17,14 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 
 package project
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+)
 
 // Option defines a project option.
 type Option string
@@ -27,6 +34,26 @@ const (
 	OptionDomainSuffix Option = "domain_suffix"
 	// OptionMountStrategy defines the strategy of dealing with mounts.
 	OptionMountStrategy Option = "mount_strategy"
+	// OptionContainerBackend defines the container backend used to run containers.
+	OptionContainerBackend Option = "container_backend"
+	// OptionXdebugClient defines the host and port xdebug connects to.
+	OptionXdebugClient Option = "xdebug_client"
+	// OptionStartConcurrency defines how many containers can be started at once.
+	OptionStartConcurrency Option = "start_concurrency"
+	// OptionHealthTimeout defines how many seconds to wait for containers to become ready.
+	OptionHealthTimeout Option = "health_timeout"
+	// OptionShareTransport defines the tunnel transport used to share the project.
+	OptionShareTransport Option = "share_transport"
+	// OptionShareServer defines the relay or SSH server used to share the project.
+	OptionShareServer Option = "share_server"
+	// OptionShareSecret defines the secret used to register with the relay server.
+	OptionShareSecret Option = "share_secret"
+	// OptionShareRemotePort defines the port opened on the SSH server when sharing the project.
+	OptionShareRemotePort Option = "share_remote_port"
+	// OptionSharePublicURL overrides the public URL printed when sharing the project.
+	OptionSharePublicURL Option = "share_public_url"
+	// OptionSanitizeFile defines a YAML file with database sanitize rules, relative to the project root.
+	OptionSanitizeFile Option = "sanitize_file"
 )
 
 const (
@@ -58,6 +85,34 @@ func (o Option) DefaultValue() string {
 		{
 			return MountStrategyNone
 		}
+	case OptionContainerBackend:
+		{
+			return container.DefaultHandler
+		}
+	case OptionXdebugClient:
+		{
+			return "host.docker.internal:9003"
+		}
+	case OptionStartConcurrency:
+		{
+			return "4"
+		}
+	case OptionHealthTimeout:
+		{
+			return "120"
+		}
+	case OptionShareTransport:
+		{
+			return "relay"
+		}
+	case OptionShareServer:
+		{
+			return "127.0.0.1:7070"
+		}
+	case OptionShareRemotePort:
+		{
+			return "8080"
+		}
 	}
 	return ""
 }
@@ -80,6 +135,52 @@ func (o Option) Validate(v string) error {
 			}
 			return fmt.Errorf("mount strategy must be one of %s,%s,%s", MountStrategyNone, MountStrategySymlink, MountStrategyVolume)
 		}
+	case OptionContainerBackend:
+		{
+			if container.HasHandler(v) {
+				return nil
+			}
+			return fmt.Errorf("container backend must be one of %s", strings.Join(container.ListHandlers(), ","))
+		}
+	case OptionXdebugClient:
+		{
+			_, port, err := net.SplitHostPort(v)
+			if err != nil {
+				return fmt.Errorf("xdebug client must be in the format host:port")
+			}
+			if _, err := strconv.Atoi(port); err != nil {
+				return fmt.Errorf("xdebug client port must be a number")
+			}
+			return nil
+		}
+	case OptionStartConcurrency:
+		{
+			if n, err := strconv.Atoi(v); err != nil || n < 1 {
+				return fmt.Errorf("start concurrency must be a number greater than zero")
+			}
+			return nil
+		}
+	case OptionHealthTimeout:
+		{
+			if n, err := strconv.Atoi(v); err != nil || n < 0 {
+				return fmt.Errorf("health timeout must be a number of seconds, zero disables waiting")
+			}
+			return nil
+		}
+	case OptionShareTransport:
+		{
+			if v == "relay" || v == "ssh" {
+				return nil
+			}
+			return fmt.Errorf("share transport must be one of relay,ssh")
+		}
+	case OptionShareRemotePort:
+		{
+			if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("share remote port must be a port number")
+			}
+			return nil
+		}
 	}
 	return nil
 
@@ -90,6 +191,16 @@ func ListOptions() []Option {
 	return []Option{
 		OptionDomainSuffix,
 		OptionMountStrategy,
+		OptionContainerBackend,
+		OptionXdebugClient,
+		OptionStartConcurrency,
+		OptionHealthTimeout,
+		OptionShareTransport,
+		OptionShareServer,
+		OptionShareSecret,
+		OptionShareRemotePort,
+		OptionSharePublicURL,
+		OptionSanitizeFile,
 	}
 }
 
@@ -103,6 +214,18 @@ func ListOptionDescription() map[Option]string {
 			MountStrategySymlink,
 			MountStrategyVolume,
 		),
+		OptionContainerBackend: fmt.Sprintf(
+			"Defines which container backend to use. (%s).",
+			strings.Join(container.ListHandlers(), ","),
+		),
+		OptionXdebugClient:     "Host and port xdebug connects to. (host:port).",
+		OptionStartConcurrency: "Maximum number of containers to start at once.",
+		OptionHealthTimeout:    "Seconds to wait for containers to become ready before post-deploy. (0 to disable).",
+		OptionShareTransport:   "Tunnel transport used by router:share. (relay,ssh).",
+		OptionShareServer:      "Relay server (host:port) or SSH server ([user@]host[:port]) used by router:share.",
+		OptionShareSecret:      "Secret printed by router:relay, required by the relay transport.",
+		OptionShareRemotePort:  "Port opened on the SSH server by router:share.",
+		OptionSharePublicURL:   "Public URL printed by router:share, defaults to the one given by the transport.",
 	}
 }
 
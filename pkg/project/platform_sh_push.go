@@ -0,0 +1,172 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/platformsh"
+)
+
+const pshPushContainerDumpPath = "/mnt/data/pcc-push.gz"
+
+// platformSHPushPreflight returns the platform.sh environment to push to.
+// The main environment is refused unless forceMain is true.
+func (p *Project) platformSHPushPreflight(envName string, forceMain bool) (*platformsh.Environment, error) {
+	if err := p.platformSHSyncPreflight(envName); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	env, err := p.PlatformSHEnvironment(envName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if env.IsMain {
+		if !forceMain {
+			return nil, errors.Wrapf(ErrPushMainEnvironment, "environment '%s' is the main environment", env.Name)
+		}
+		output.Warn(fmt.Sprintf("Push to main environment '%s.'", env.Name))
+	}
+	return env, nil
+}
+
+// PlatformSHPushMounts pushes the local project's mounts to the given platform.sh environment.
+// An empty environment name uses the environment mapped to the current Git branch.
+func (p *Project) PlatformSHPushMounts(envName string, forceMain bool) error {
+	done := output.Duration("Push mounts.")
+	env, err := p.platformSHPushPreflight(envName, forceMain)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := p.platformSHRsyncMounts(env, true); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// PlatformSHPushDatabases pushes the local project's databases to the given platform.sh environment.
+// An empty environment name uses the environment mapped to the current Git branch.
+func (p *Project) PlatformSHPushDatabases(envName string, forceMain bool) error {
+	done := output.Duration("Push databases.")
+	env, err := p.platformSHPushPreflight(envName, forceMain)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// fetch relationships for import credentials
+	relationships, err := p.PlatformSH.PlatformRelationships(env, p.Apps[0].Name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, service := range p.Services {
+		if p.GetDatabaseDumpFormat(service) == "" {
+			continue
+		}
+		for _, db := range p.GetDatabases(service) {
+			if err := p.platformSHPushDatabase(env, service, db, relationships); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	done()
+	return nil
+}
+
+// platformSHPushDatabase dumps a single local database and imports it in to the database on Platform.sh.
+func (p *Project) platformSHPushDatabase(env *platformsh.Environment, service def.Service, db string, relationships map[string]interface{}) error {
+	done := output.Duration(fmt.Sprintf("%s:%s", service.Name, db))
+	importCmd := p.GetPlatformSHDatabaseImportCommand(service, db, relationships)
+	if importCmd == "" {
+		output.Warn(fmt.Sprintf("Push not supported or no relationship found for '%s', skipped.", service.Name))
+		return nil
+	}
+	// create dump, a failed dump must never be pushed as it would replace the remote data
+	done2 := output.Duration("Create dump.")
+	cont := p.NewContainer(service)
+	exitCode, err := cont.containerHandler.ContainerCommand(
+		cont.Config.GetContainerName(),
+		"root",
+		[]string{"bash", "-c", fmt.Sprintf(
+			"set -o pipefail; %s | gzip > %s", p.GetDatabaseDumpCommand(service, db), pshPushContainerDumpPath,
+		)},
+		nil,
+	)
+	if err == nil && exitCode != 0 {
+		err = errors.Wrapf(container.ErrCommandExited, "exit code %d", exitCode)
+	}
+	if err != nil {
+		cont.containerHandler.ContainerCommand(cont.Config.GetContainerName(), "root", []string{"rm", "-f", pshPushContainerDumpPath}, nil)
+		return errors.Wrapf(err, "dump of '%s:%s' failed, push refused", service.Name, db)
+	}
+	dumpPath := filepath.Join(
+		config.Path(),
+		pshSyncCacheDir,
+		fmt.Sprintf("push-%s-%s-%s-%s.gz", p.ID, env.MachineName, service.Name, db),
+	)
+	if err := os.MkdirAll(filepath.Dir(dumpPath), 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(dumpPath)
+	f, err := os.Create(dumpPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = cont.Download(pshPushContainerDumpPath, f)
+	f.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := cont.containerHandler.ContainerCommand(
+		cont.Config.GetContainerName(),
+		"root",
+		[]string{"rm", "-f", pshPushContainerDumpPath},
+		nil,
+	); err != nil {
+		return errors.WithStack(err)
+	}
+	done2()
+	// upload dump
+	done2 = output.Duration("Upload dump.")
+	f, err = os.Open(dumpPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	remotePath := fmt.Sprintf("/tmp/pcc-push-%s-%s.gz", service.Name, db)
+	if err := p.PlatformSH.SSHUpload(env, p.Apps[0].Name, remotePath, f); err != nil {
+		return errors.WithStack(err)
+	}
+	done2()
+	// import dump
+	done2 = output.Duration("Import dump.")
+	if _, err := p.PlatformSH.SSHCommand(
+		env, p.Apps[0].Name,
+		fmt.Sprintf("set -o pipefail; zcat '%[1]s' | %[2]s; rc=$?; rm -f '%[1]s'; exit $rc", remotePath, importCmd),
+	); err != nil {
+		return errors.WithStack(err)
+	}
+	done2()
+	done()
+	return nil
+}
@@ -19,8 +19,14 @@ package project
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
@@ -35,6 +41,7 @@ import (
 
 const pshSyncSSHCertPath = "/mnt/pcc_ssh_cert"
 const pshSyncSSHKeyPath = "/mnt/pcc_ssh_key"
+const pshSyncCacheDir = "psh_sync"
 
 func (p *Project) platformSHSyncPreflight(envName string) error {
 	if p.PlatformSH == nil || p.PlatformSH.ID == "" {
@@ -49,7 +56,35 @@ func (p *Project) platformSHSyncPreflight(envName string) error {
 	return nil
 }
 
+// PlatformSHEnvironment returns the platform.sh environment matching given name.
+// When no name is given the environment mapped to the current Git branch is returned.
+func (p *Project) PlatformSHEnvironment(envName string) (*platformsh.Environment, error) {
+	if p.PlatformSH == nil || p.PlatformSH.ID == "" {
+		return nil, errors.WithStack(platformsh.ErrProjectNotFound)
+	}
+	if err := p.PlatformSH.FetchEnvironments(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if envName != "" {
+		env := p.PlatformSH.GetEnvironment(envName)
+		if env == nil {
+			return nil, errors.Wrapf(platformsh.ErrEnvironmentNotFound, "platform.sh environment '%s' not found", envName)
+		}
+		return env, nil
+	}
+	branch, err := platformsh.GitBranch(p.PlatformSH.LocalPath)
+	if err != nil {
+		output.LogDebug("Could not determine Git branch.", err)
+	}
+	env := p.PlatformSH.MapBranch(branch)
+	if env == nil {
+		return nil, errors.Wrapf(platformsh.ErrEnvironmentNotFound, "no platform.sh environment found for branch '%s'", branch)
+	}
+	return env, nil
+}
+
 // PlatformSHSyncVariables syncs the given platform.sh environment's variables to the local project.
+// An empty environment name uses the environment mapped to the current Git branch.
 func (p *Project) PlatformSHSyncVariables(envName string) error {
 
 	done := output.Duration("Sync variables.")
@@ -57,16 +92,16 @@ func (p *Project) PlatformSHSyncVariables(envName string) error {
 	if err := p.platformSHSyncPreflight(envName); err != nil {
 		return errors.WithStack(err)
 	}
-	env := p.PlatformSH.GetEnvironment(envName)
-	if env == nil {
-		return errors.Wrapf(platformsh.ErrEnvironmentNotFound, "platform.sh environment '%s' not found", envName)
+	env, err := p.PlatformSHEnvironment(envName)
+	if err != nil {
+		return errors.WithStack(err)
 	}
-	vars, err := p.PlatformSH.Variables(env, p.Apps[0].Name)
+	vars, sensitive, err := p.PlatformSH.Variables(env, p.Apps[0].Name)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	for k, v := range vars {
-		if err := p.VarSet(k, v); err != nil {
+		if err := p.platformSHSyncVariable(k, v, sensitive[k]); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -75,7 +110,7 @@ func (p *Project) PlatformSHSyncVariables(envName string) error {
 		return errors.WithStack(err)
 	}
 	for k, v := range pvars {
-		if err := p.VarSet(k, def.InterfaceToString(v)); err != nil {
+		if err := p.platformSHSyncVariable(k, def.InterfaceToString(v), sensitive[k]); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -87,7 +122,16 @@ func (p *Project) PlatformSHSyncVariables(envName string) error {
 	return nil
 }
 
+// platformSHSyncVariable sets a variable synced from platform.sh, sensitive variables are stored encrypted.
+func (p *Project) platformSHSyncVariable(key string, value string, sensitive bool) error {
+	if sensitive {
+		return errors.WithStack(p.VarSetSensitive(key, value))
+	}
+	return errors.WithStack(p.VarSet(key, value))
+}
+
 // PlatformSHSyncMounts syncs the given platform.sh environment's mounts to the local project.
+// An empty environment name uses the environment mapped to the current Git branch.
 func (p *Project) PlatformSHSyncMounts(envName string) error {
 
 	done := output.Duration("Sync mounts.")
@@ -96,24 +140,32 @@ func (p *Project) PlatformSHSyncMounts(envName string) error {
 	if err := p.platformSHSyncPreflight(envName); err != nil {
 		return errors.WithStack(err)
 	}
-	env := p.PlatformSH.GetEnvironment(envName)
-	if env == nil {
-		return errors.Wrapf(platformsh.ErrEnvironmentNotFound, "platform.sh environment '%s' not found", envName)
+	env, err := p.PlatformSHEnvironment(envName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := p.platformSHRsyncMounts(env, false); err != nil {
+		return errors.WithStack(err)
 	}
+	done()
+	return nil
+}
+
+// platformSHRsyncMounts rsyncs the mounts of all applications between the local project and given platform.sh environment.
+// Mounts are pulled from the environment unless push is true.
+func (p *Project) platformSHRsyncMounts(env *platformsh.Environment, push bool) error {
 
 	// get ssh cert
 	sshCert, err := p.PlatformSH.SSHCertficiate()
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	certReader := bytes.NewReader(sshCert)
 
 	// get ssh key
 	sshKey, err := config.PrivateKey()
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	keyReader := bytes.NewReader(sshKey)
 
 	// set volume mount strategy to ensure mount sync works
 	p.Options[OptionMountStrategy] = MountStrategyVolume
@@ -148,15 +200,21 @@ func (p *Project) PlatformSHSyncMounts(envName string) error {
 			}
 		}
 		// upload ssh cert and key
-		if err := cont.Upload(pshSyncSSHCertPath, certReader); err != nil {
+		if err := cont.Upload(pshSyncSSHCertPath, bytes.NewReader(sshCert)); err != nil {
 			return errors.WithStack(err)
 		}
-		if err := cont.Upload(pshSyncSSHKeyPath, keyReader); err != nil {
+		if err := cont.Upload(pshSyncSSHKeyPath, bytes.NewReader(sshKey)); err != nil {
 			return errors.WithStack(err)
 		}
 		// itterate mounts and rsync
 		for dest := range mounts {
 			done2 := output.Duration(fmt.Sprintf("%s:%s", name, dest))
+			localPath := fmt.Sprintf("/app/%s/", strings.Trim(dest, "/"))
+			remotePath := fmt.Sprintf("%s:/app/%s/", sshURL, strings.Trim(dest, "/"))
+			src, dst := remotePath, localPath
+			if push {
+				src, dst = localPath, remotePath
+			}
 			if _, err := cont.Shell(
 				"root",
 				[]string{
@@ -164,14 +222,13 @@ func (p *Project) PlatformSHSyncMounts(envName string) error {
 					"bash",
 					"-c",
 					fmt.Sprintf(
-						`chmod 0600 %s && chmod 0600 %s && ssh-add %s && rsync -avzh -e "ssh -i %s" %s:/app/%s/ /app/%s/`,
+						`chmod 0600 %s && chmod 0600 %s && ssh-add %s && rsync -avzh -e "ssh -i %s" %s %s`,
 						pshSyncSSHKeyPath,
 						pshSyncSSHCertPath,
 						pshSyncSSHKeyPath,
 						pshSyncSSHCertPath,
-						sshURL,
-						strings.Trim(dest, "/"),
-						strings.Trim(dest, "/"),
+						src,
+						dst,
 					),
 				},
 			); err != nil {
@@ -201,12 +258,13 @@ func (p *Project) PlatformSHSyncMounts(envName string) error {
 			}
 		}
 	}
-	done()
 	return nil
 }
 
 // PlatformSHSyncDatabases syncs the given platform.sh environment's databases to the local project.
-func (p *Project) PlatformSHSyncDatabases(envName string) error {
+// An empty environment name uses the environment mapped to the current Git branch.
+// The given dump options limit the tables that are synced.
+func (p *Project) PlatformSHSyncDatabases(envName string, opts DatabaseDumpOptions) error {
 
 	done := output.Duration("Sync databases .")
 
@@ -214,9 +272,9 @@ func (p *Project) PlatformSHSyncDatabases(envName string) error {
 	if err := p.platformSHSyncPreflight(envName); err != nil {
 		return errors.WithStack(err)
 	}
-	env := p.PlatformSH.GetEnvironment(envName)
-	if env == nil {
-		return errors.Wrapf(platformsh.ErrEnvironmentNotFound, "environment '%s' not found", envName)
+	env, err := p.PlatformSHEnvironment(envName)
+	if err != nil {
+		return errors.WithStack(err)
 	}
 
 	// fetch relationships for dump passwords
@@ -227,64 +285,13 @@ func (p *Project) PlatformSHSyncDatabases(envName string) error {
 
 	// itterate services to find database services
 	for _, service := range p.Services {
-		for _, dbType := range GetDatabaseTypeNames() {
-			if dbType == service.GetTypeName() {
-				// itterate databases
-				for _, dbIf := range service.Configuration["schemas"].([]interface{}) {
-					db := dbIf.(string)
-					done2 := output.Duration(fmt.Sprintf("%s:%s", service.Name, db))
-					// create dump
-					done3 := output.Duration("Create dump.")
-					if _, err := p.PlatformSH.SSHCommand(
-						env, p.Apps[0].Name,
-						p.GetPlatformSHDatabaseDumpCommand(service, db, relationships)+" | gzip > /tmp/db.sql.gz",
-					); err != nil {
-						return errors.WithStack(err)
-					}
-					done3()
-					// download dump
-					done3 = output.Duration("Download dump.")
-					dbPath, err := p.PlatformSH.SSHDownload(
-						env, p.Apps[0].Name,
-						"/tmp/db.sql.gz",
-					)
-					if err != nil {
-						return errors.WithStack(err)
-					}
-					// delete remote dump
-					if _, err := p.PlatformSH.SSHCommand(
-						env, p.Apps[0].Name,
-						"rm /tmp/db.sql.gz",
-					); err != nil {
-						return errors.WithStack(err)
-					}
-					done3()
-					// import dump
-					done3 = output.Duration("Import dump.")
-					dbOpen, err := os.Open(dbPath)
-					if err != nil {
-						return errors.WithStack(err)
-					}
-					defer func() {
-						dbOpen.Close()
-						os.Remove(dbPath)
-					}()
-					cont := p.NewContainer(service)
-					if err := cont.Upload("/mnt/data/db.sql.gz", dbOpen); err != nil {
-						return errors.WithStack(err)
-					}
-					if _, err := cont.containerHandler.ContainerShell(
-						cont.Config.GetContainerName(),
-						"root",
-						[]string{"sh", "-c", fmt.Sprintf("zcat /mnt/data/db.sql.gz | %s && rm /mnt/data/db.sql.gz", p.GetDatabaseShellCommand(service, db))},
-						nil,
-					); err != nil {
-						return errors.WithStack(err)
-					}
-					done3()
-					done2()
-				}
-				break
+		if p.GetDatabaseDumpFormat(service) == "" {
+			continue
+		}
+		// itterate databases
+		for _, db := range p.GetDatabases(service) {
+			if err := p.platformSHSyncDatabase(env, service, db, relationships, opts); err != nil {
+				return errors.WithStack(err)
 			}
 		}
 	}
@@ -293,3 +300,175 @@ func (p *Project) PlatformSHSyncDatabases(envName string) error {
 	return nil
 
 }
+
+// platformSHSyncDumpState tracks a Platform.sh database dump that is being downloaded to the sync cache.
+type platformSHSyncDumpState struct {
+	Key        string `json:"key"`
+	RemotePath string `json:"remote_path"`
+	Size       int64  `json:"size"`
+	Checksum   string `json:"checksum"`
+}
+
+// platformSHSyncCachePath returns the path to the sync cache file for given database.
+func (p *Project) platformSHSyncCachePath(env *platformsh.Environment, service def.Service, db string) string {
+	return filepath.Join(
+		config.Path(),
+		pshSyncCacheDir,
+		fmt.Sprintf("%s-%s-%s-%s.gz", p.ID, env.MachineName, service.Name, db),
+	)
+}
+
+// platformSHSyncLoadDumpState loads the state of the dump cached at given path.
+func platformSHSyncLoadDumpState(cachePath string) platformSHSyncDumpState {
+	out := platformSHSyncDumpState{}
+	rawState, err := ioutil.ReadFile(cachePath + ".json")
+	if err != nil {
+		return out
+	}
+	if err := json.Unmarshal(rawState, &out); err != nil {
+		output.LogDebug("Invalid database sync state.", err)
+	}
+	return out
+}
+
+// platformSHSyncCreateDump creates a compressed database dump on Platform.sh and returns its state.
+func (p *Project) platformSHSyncCreateDump(env *platformsh.Environment, key string, dumpCmd string) (platformSHSyncDumpState, error) {
+	remotePath := fmt.Sprintf("/tmp/pcc-sync-%s.gz", key[0:12])
+	out, err := p.PlatformSH.SSHCommand(
+		env, p.Apps[0].Name,
+		fmt.Sprintf(
+			"set -o pipefail; %[1]s | gzip > '%[2]s.part' && mv '%[2]s.part' '%[2]s' && stat -c %%s '%[2]s' && md5sum '%[2]s'",
+			dumpCmd, remotePath,
+		),
+	)
+	if err != nil {
+		return platformSHSyncDumpState{}, errors.WithStack(err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return platformSHSyncDumpState{}, errors.Errorf("unexpected output while creating dump '%s'", remotePath)
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return platformSHSyncDumpState{}, errors.WithStack(err)
+	}
+	return platformSHSyncDumpState{
+		Key:        key,
+		RemotePath: remotePath,
+		Size:       size,
+		Checksum:   fields[1],
+	}, nil
+}
+
+// platformSHSyncDatabase dumps a single database on Platform.sh and imports it in to the local service.
+// The compressed dump is downloaded to a cache file in the config directory, an interrupted
+// download is resumed on the next sync as long as the remote dump is still available.
+// The sanitize rules are run after the import.
+func (p *Project) platformSHSyncDatabase(env *platformsh.Environment, service def.Service, db string, relationships map[string]interface{}, opts DatabaseDumpOptions) error {
+	done := output.Duration(fmt.Sprintf("%s:%s", service.Name, db))
+	dumpCmd := p.GetPlatformSHDatabaseDumpCommand(service, db, relationships, opts)
+	if dumpCmd == "" {
+		output.Warn(fmt.Sprintf("No relationship found for '%s', skipped.", service.Name))
+		return nil
+	}
+	WarnDatabaseEphemeral(service)
+	if !opts.IsEmpty() && p.GetDatabaseDumpFormat(service) != DatabaseFormatSQL {
+		output.Warn(fmt.Sprintf("Table options are not supported by '%s', ignored.", service.Name))
+	}
+	cachePath := p.platformSHSyncCachePath(env, service, db)
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	// resume previous download if the remote dump still exists
+	key := fmt.Sprintf("%x", md5.Sum([]byte(dumpCmd)))
+	state := platformSHSyncLoadDumpState(cachePath)
+	resume := false
+	if state.Key == key {
+		out, err := p.PlatformSH.SSHCommand(
+			env, p.Apps[0].Name,
+			fmt.Sprintf("stat -c %%s '%s' 2>/dev/null || true", state.RemotePath),
+		)
+		resume = err == nil && strings.TrimSpace(string(out)) == strconv.FormatInt(state.Size, 10)
+	}
+	// create dump
+	if !resume {
+		done2 := output.Duration("Create dump.")
+		var err error
+		state, err = p.platformSHSyncCreateDump(env, key, dumpCmd)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+		rawState, err := json.Marshal(state)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := ioutil.WriteFile(cachePath+".json", rawState, 0600); err != nil {
+			return errors.WithStack(err)
+		}
+		done2()
+	} else {
+		output.Info("Resume download of existing dump.")
+	}
+	// download dump
+	prog := output.Progress([]string{"Download dump."})
+	if err := p.PlatformSH.SSHResumeDownload(
+		env, p.Apps[0].Name, state.RemotePath, cachePath, state.Size,
+		func(cur int64, total int64) {
+			prog(0, output.ProgressMessageWait, &cur, &total)
+		},
+	); err != nil {
+		prog(0, output.ProgressMessageError, nil, nil)
+		return errors.WithStack(err)
+	}
+	checksum, err := fileMD5(cachePath)
+	if err != nil {
+		prog(0, output.ProgressMessageError, nil, nil)
+		return errors.WithStack(err)
+	}
+	if checksum != state.Checksum {
+		prog(0, output.ProgressMessageError, nil, nil)
+		os.Remove(cachePath)
+		os.Remove(cachePath + ".json")
+		return errors.Wrapf(ErrDatabaseSyncChecksum, "checksum of '%s' does not match remote dump", cachePath)
+	}
+	prog(0, output.ProgressMessageDone, nil, nil)
+	// import dump, the database stays flagged until it is sanitized
+	if err := p.DatabaseSanitizeFlagSet(service, db); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := p.DatabaseImport(service, db, cachePath, false); err != nil {
+		return errors.WithStack(err)
+	}
+	// clean up
+	if _, err := p.PlatformSH.SSHCommand(
+		env, p.Apps[0].Name,
+		fmt.Sprintf("rm -f '%s'", state.RemotePath),
+	); err != nil {
+		return errors.WithStack(err)
+	}
+	os.Remove(cachePath)
+	os.Remove(cachePath + ".json")
+	// sanitize
+	if err := p.DatabaseSanitize(service, db); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// fileMD5 returns the hex encoded md5 checksum of given file.
+func fileMD5(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -18,11 +18,20 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 package project
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/platformsh"
 )
 
 func TestFromPath(t *testing.T) {
@@ -178,6 +187,45 @@ func TestVariables(t *testing.T) {
 	def.AssertEqual(v, "", "secret:api_secret unexpected value", t)
 }
 
+func TestSensitiveVariables(t *testing.T) {
+	config.SetPath(t.TempDir())
+	defer config.SetPath("")
+	p, e := LoadFromPath(path.Join("_test_data", "sample2"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	apiSecret := "secret123"
+	if err := p.VarSetSensitive("env:API_SECRET", apiSecret); err != nil {
+		t.Fatal(err)
+	}
+	if !p.VarIsSensitive("env:API_SECRET") {
+		t.Errorf("expected env:API_SECRET to be sensitive")
+	}
+	if strings.Contains(p.Variables.GetString("env:API_SECRET"), apiSecret) {
+		t.Errorf("expected env:API_SECRET to be stored encrypted")
+	}
+	v, err := p.VarGet("env:API_SECRET")
+	if err != nil {
+		t.Error(err)
+	}
+	def.AssertEqual(v, def.SensitiveVariableMask, "env:API_SECRET should be masked", t)
+	envVars := p.GetDefinitionEnvironmentVariables(p.Apps[0])
+	def.AssertEqual(envVars["API_SECRET"], apiSecret, "env:API_SECRET should be decrypted in container env", t)
+	platformVars, _ := base64.StdEncoding.DecodeString(envVars["PLATFORM_VARIABLES"])
+	if !strings.Contains(string(platformVars), apiSecret) {
+		t.Errorf("expected PLATFORM_VARIABLES to contain decrypted env:API_SECRET")
+	}
+	// import as sensitive
+	if err := p.VarImport(def.Variables{"env:IMPORTED_SECRET": apiSecret}, true); err != nil {
+		t.Fatal(err)
+	}
+	if !p.VarIsSensitive("env:IMPORTED_SECRET") {
+		t.Errorf("expected imported env:IMPORTED_SECRET to be sensitive")
+	}
+	envVars = p.GetDefinitionEnvironmentVariables(p.Apps[0])
+	def.AssertEqual(envVars["IMPORTED_SECRET"], apiSecret, "env:IMPORTED_SECRET should be decrypted in container env", t)
+}
+
 func TestYAMLFunction(t *testing.T) {
 	projectPath := path.Join("_test_data", "sample5")
 	p, e := LoadFromPath(projectPath, true)
@@ -259,3 +307,573 @@ func TestFlags(t *testing.T) {
 		t,
 	)
 }
+
+func TestContainerBackendOption(t *testing.T) {
+	p := Project{
+		Options: map[Option]string{},
+	}
+	p.SetGlobalConfig(def.GlobalConfig{})
+	def.AssertEqual(
+		p.ContainerBackend(),
+		container.HandlerDocker,
+		"expected default container backend",
+		t,
+	)
+	p.SetGlobalConfig(def.GlobalConfig{
+		Options: map[string]string{string(OptionContainerBackend): container.HandlerPodman},
+	})
+	def.AssertEqual(
+		p.ContainerBackend(),
+		container.HandlerPodman,
+		"expected global container backend",
+		t,
+	)
+	p.Options[OptionContainerBackend] = container.HandlerDummy
+	def.AssertEqual(
+		p.ContainerBackend(),
+		container.HandlerDummy,
+		"expected local container backend",
+		t,
+	)
+	def.AssertEqual(
+		OptionContainerBackend.Validate("invalid") != nil,
+		true,
+		"expected invalid container backend to fail validation",
+		t,
+	)
+	c, err := container.NewHandler(p.ContainerBackend())
+	if err != nil {
+		t.Errorf("failed to create container handler, %s", err)
+	}
+	if _, ok := c.(container.Dummy); !ok {
+		t.Errorf("expected dummy container handler")
+	}
+}
+
+func TestCrons(t *testing.T) {
+	p, e := LoadFromPath(path.Join("_test_data", "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	crons := p.ListCrons()
+	def.AssertEqual(len(crons), 2, "unexpected number of crons", t)
+	def.AssertEqual(crons[0].Key(), "test_app/test", "unexpected cron key", t)
+	c, e := p.GetCron("test2", "")
+	if e != nil {
+		t.Errorf("failed to get cron, %s", e)
+	}
+	def.AssertEqual(c.Cron.Spec, "*/5 1 * * *", "unexpected cron spec", t)
+	if _, e := p.GetCron("test2", "not_an_app"); e == nil {
+		t.Errorf("expected cron not found error")
+	}
+	// cron runs hold a lock file in the container so overlapping runs are skipped
+	cmd := strings.Join(c.command(), " ")
+	def.AssertEqual(
+		strings.HasPrefix(cmd, "flock -n /tmp/.pcc_cron_"+c.App+"_test2.lock bash --login -c "),
+		true,
+		"unexpected cron command "+cmd,
+		t,
+	)
+	def.AssertEqual(
+		strings.Join(c.lockCheckCommand(), " "),
+		"flock -n /tmp/.pcc_cron_"+c.App+"_test2.lock true",
+		"unexpected cron lock check command",
+		t,
+	)
+	// crons are passed to the container unless the cron scheduler runs them
+	p.Flags.Set(EnableCron, FlagOn)
+	configJSON, e := p.BuildConfigJSON(p.Apps[0])
+	if e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(strings.Contains(string(configJSON), "TEST2"), true, "expected crons in config.json", t)
+	p.Flags.Set(EnableCronScheduler, FlagOn)
+	configJSON, e = p.BuildConfigJSON(p.Apps[0])
+	if e != nil {
+		t.Fatal(e)
+	}
+	def.AssertEqual(strings.Contains(string(configJSON), "TEST2"), false, "expected crons to be left out of config.json", t)
+}
+
+func TestDefinitionStartOrder(t *testing.T) {
+	p, e := LoadFromPath(path.Join("_test_data", "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	defs := []interface{}{p.Apps[0]}
+	for _, s := range p.Services {
+		defs = append(defs, s)
+	}
+	levels, e := p.GetDefinitionStartOrder(defs)
+	if e != nil {
+		t.Errorf("failed to get start order, %s", e)
+	}
+	def.AssertEqual(len(levels), 2, "unexpected number of start levels", t)
+	def.AssertEqual(len(levels[0]), len(p.Services), "expected all services in first level", t)
+	def.AssertEqual(p.GetDefinitionName(levels[1][0]), p.Apps[0].Name, "expected app in last level", t)
+	// circular relationships
+	_, e = p.GetDefinitionStartOrder([]interface{}{
+		def.Service{Name: "a", Type: "redis:3.2", Relationships: map[string]string{"b": "b:redis"}},
+		def.Service{Name: "b", Type: "redis:3.2", Relationships: map[string]string{"a": "a:redis"}},
+	})
+	if !errors.Is(e, ErrInvalidRelationship) {
+		t.Errorf("expected invalid relationship error")
+	}
+}
+
+func TestDatabaseDriverMySQL(t *testing.T) {
+	p := Project{}
+	s := def.Service{Name: "db", Type: "mariadb:10.4", Configuration: map[string]interface{}{
+		"schemas": []interface{}{"main", "legacy"},
+	}}
+	def.AssertEqual(strings.Join(p.GetDatabases(s), ","), "main,legacy", "unexpected mysql databases", t)
+	def.AssertEqual(p.GetDatabaseDumpFormat(s), DatabaseFormatSQL, "unexpected mysql dump format", t)
+	def.AssertEqual(
+		p.GetDatabaseImportCommand(s, "legacy", DatabaseFormatSQL),
+		"mysql --password=$(cat /mnt/data/.mysql-password) -Dlegacy",
+		"unexpected mysql import command",
+		t,
+	)
+	def.AssertEqual(p.GetDatabaseImportCommand(s, "legacy", DatabaseFormatPostgresCustom), "", "expected no mysql pgcustom import", t)
+	def.AssertEqual(
+		strings.Contains(p.GetDatabaseRecreateCommand(s, "legacy"), "DROP DATABASE IF EXISTS `legacy`; CREATE DATABASE `legacy`;"),
+		true,
+		"expected mysql recreate to drop and create the database",
+		t,
+	)
+}
+
+func TestDatabaseDriverPostgres(t *testing.T) {
+	p := Project{}
+	s := def.Service{Name: "db", Type: "postgresql:13"}
+	def.AssertEqual(
+		p.GetDatabaseImportCommand(s, "main", DatabaseFormatSQL),
+		`PGPASSWORD=main psql -U main -h 127.0.0.1 --dbname="main"`,
+		"unexpected postgresql sql import command",
+		t,
+	)
+	def.AssertEqual(
+		strings.HasPrefix(p.GetDatabaseImportCommand(s, "main", DatabaseFormatPostgresCustom), "PGPASSWORD=main pg_restore "),
+		true,
+		"expected postgresql custom format import to use pg_restore",
+		t,
+	)
+	def.AssertEqual(p.GetDatabaseImportCommand(s, "main", DatabaseFormatRedisRDB), "", "expected no postgresql rdb import", t)
+}
+
+func TestDatabaseDriverRedis(t *testing.T) {
+	p := Project{}
+	// plain redis is a cache but can still be dumped and imported
+	cache := def.Service{Name: "cache", Type: "redis:6.0"}
+	def.AssertEqual(strings.Join(p.GetDatabases(cache), ","), "0", "unexpected redis databases", t)
+	def.AssertEqual(p.GetDatabaseShellCommand(cache, ""), "redis-cli -n 0", "unexpected redis shell command", t)
+	s := def.Service{Name: "sessions", Type: "redis-persistent:6.0"}
+	def.AssertEqual(strings.Join(p.GetDatabases(s), ","), "0", "unexpected redis-persistent databases", t)
+	def.AssertEqual(p.GetDatabaseShellCommand(s, "1"), "redis-cli -n 1", "unexpected redis-persistent shell command", t)
+	def.AssertEqual(p.GetDatabaseRecreateCommand(s, "1"), "redis-cli -n 1 FLUSHDB", "unexpected redis-persistent recreate command", t)
+	def.AssertEqual(p.GetDatabaseImportCommand(s, "0", DatabaseFormatSQL), "", "expected no redis-persistent sql import", t)
+	importCmd := p.GetDatabaseImportCommand(s, "0", DatabaseFormatRedisRDB)
+	def.AssertEqual(strings.Contains(importCmd, "DEBUG RELOAD NOSAVE"), true, "expected redis-persistent import to reload the snapshot", t)
+	def.AssertEqual(strings.HasSuffix(importCmd, "grep -q PONG; fi"), true, "expected redis-persistent import to wait for a restart", t)
+	def.AssertEqual(strings.Contains(importCmd, `[ "${VERSION:-0}" -lt 6 ]`), true, "expected redis-persistent import to shutdown redis before 6.0", t)
+	def.AssertEqual(p.GetPlatformSHDatabaseImportCommand(s, "0", map[string]interface{}{}), "", "expected no redis-persistent remote import", t)
+}
+
+func TestDatabaseDriverMongoDB(t *testing.T) {
+	p := Project{}
+	s := def.Service{Name: "mongo", Type: "mongodb:4.0"}
+	def.AssertEqual(p.GetDatabaseDumpFormat(s), DatabaseFormatMongoArchive, "unexpected mongodb dump format", t)
+	def.AssertEqual(
+		strings.HasSuffix(p.GetDatabaseDumpCommand(s, "main"), "--db=main --archive 2>/dev/null"),
+		true,
+		"expected mongodb dump of the main database",
+		t,
+	)
+	rels := map[string]interface{}{
+		"mongo": []interface{}{
+			map[string]interface{}{"service": "mongo", "rel": "mongodb", "host": "mongo.internal", "port": float64(27017), "username": "user", "password": "pass", "path": "main"},
+		},
+	}
+	def.AssertEqual(
+		strings.HasPrefix(
+			p.GetPlatformSHDatabaseImportCommand(s, "main", rels),
+			"mongorestore --host=mongo.internal --port=27017 -u user -p pass --authenticationDatabase main",
+		),
+		true,
+		"expected mongodb remote import to use relationship",
+		t,
+	)
+}
+
+func TestDatabaseDriverElasticsearch(t *testing.T) {
+	p := Project{}
+	s := def.Service{Name: "search", Type: "elasticsearch:7.10"}
+	def.AssertEqual(strings.Join(p.GetDatabases(s), ","), "*", "unexpected elasticsearch databases", t)
+	def.AssertEqual(
+		strings.HasPrefix(p.GetDatabaseDumpCommand(s, "*"), `ES_URL="http://127.0.0.1:9200" ES_AUTH="" `),
+		true,
+		"expected elasticsearch dump to use local service",
+		t,
+	)
+	rels := map[string]interface{}{
+		"search": []interface{}{
+			map[string]interface{}{"service": "search", "rel": "elasticsearch", "scheme": "https", "host": "search.internal", "port": float64(9200), "username": "user", "password": "pass"},
+		},
+	}
+	expected := `ES_URL="https://search.internal:9200" ES_AUTH="user:pass" `
+	def.AssertEqual(
+		strings.HasPrefix(p.GetPlatformSHDatabaseDumpCommand(s, "*", rels, DatabaseDumpOptions{}), expected),
+		true,
+		"expected elasticsearch remote dump to use relationship credentials",
+		t,
+	)
+	def.AssertEqual(
+		strings.HasPrefix(p.GetPlatformSHDatabaseImportCommand(s, "*", rels), expected),
+		true,
+		"expected elasticsearch remote import to use relationship credentials",
+		t,
+	)
+}
+
+func TestDatabaseDriverNone(t *testing.T) {
+	p := Project{}
+	s := def.Service{Name: "files", Type: "network-storage:1.0"}
+	def.AssertEqual(len(p.GetDatabases(s)), 0, "expected no databases for non database service", t)
+	def.AssertEqual(p.GetDatabaseShellCommand(s, ""), "", "expected no shell command for non database service", t)
+	def.AssertEqual(p.GetDatabaseDumpCommand(s, ""), "", "expected no dump command for non database service", t)
+}
+
+func TestDatabaseDumpOptions(t *testing.T) {
+	p := Project{}
+	rels := map[string]interface{}{
+		"database": []interface{}{
+			map[string]interface{}{"service": "db", "rel": "mysql", "host": "db.internal", "username": "user", "password": "pass"},
+		},
+	}
+	mysql := def.Service{Name: "db", Type: "mariadb:10.4"}
+	def.AssertEqual(
+		p.GetPlatformSHDatabaseDumpCommand(mysql, "main", rels, DatabaseDumpOptions{}),
+		`mysqldump --host="db.internal" -uuser --password=pass main`,
+		"unexpected mysql remote dump",
+		t,
+	)
+	def.AssertEqual(
+		p.GetPlatformSHDatabaseDumpCommand(mysql, "main", rels, DatabaseDumpOptions{
+			ExcludeTables: []string{"sessions"},
+			StructureOnly: []string{"cache", "sessions"},
+		}),
+		`(mysqldump --host="db.internal" -uuser --password=pass main --ignore-table='main.sessions' --ignore-table='main.cache' && `+
+			`mysqldump --host="db.internal" -uuser --password=pass --no-data main 'cache')`,
+		"unexpected mysql remote dump with structure only tables",
+		t,
+	)
+	def.AssertEqual(
+		p.GetPlatformSHDatabaseDumpCommand(mysql, "main", rels, DatabaseDumpOptions{
+			Tables:        []string{"users", "cache"},
+			StructureOnly: []string{DatabaseDumpStructureAll},
+		}),
+		`mysqldump --host="db.internal" -uuser --password=pass --no-data main 'users' 'cache'`,
+		"unexpected mysql structure only remote dump",
+		t,
+	)
+	def.AssertEqual(
+		p.GetPlatformSHDatabaseDumpCommand(mysql, "main", rels, DatabaseDumpOptions{
+			Tables:        []string{"cache"},
+			StructureOnly: []string{"cache"},
+		}),
+		`mysqldump --host="db.internal" -uuser --password=pass --no-data main 'cache'`,
+		"unexpected mysql remote dump of structure only table",
+		t,
+	)
+	rels["database"].([]interface{})[0].(map[string]interface{})["rel"] = "postgresql"
+	postgres := def.Service{Name: "db", Type: "postgresql:13"}
+	def.AssertEqual(
+		p.GetPlatformSHDatabaseDumpCommand(postgres, "main", rels, DatabaseDumpOptions{
+			Tables:        []string{"users*"},
+			ExcludeTables: []string{"users_log"},
+			StructureOnly: []string{"users_sessions"},
+		}),
+		"PGPASSWORD=pass pg_dump -U user -h db.internal --table='users*' --exclude-table='users_log' --exclude-table-data='users_sessions' main",
+		"unexpected postgresql remote dump",
+		t,
+	)
+}
+
+func TestSanitizeRules(t *testing.T) {
+	sanitizePath := filepath.Join(t.TempDir(), "sanitize.yaml")
+	if e := ioutil.WriteFile(sanitizePath, []byte(`
+sanitize:
+    - service: pgsql
+      table: customers
+      column: phone
+      action: set
+      value: "0000"
+      where: "country = 'NL'"
+`), 0644); e != nil {
+		t.Fatalf("failed to write sanitize file, %s", e)
+	}
+	p := Project{
+		Apps: []def.App{{Name: "app", Sanitize: []*def.AppSanitizeRule{
+			{Service: "mysqldb", Table: "users", Column: "email", Action: def.SanitizeActionEmail, Where: "id > 1"},
+			{Service: "mysqldb", Table: "sessions", Action: def.SanitizeActionTruncate},
+		}}},
+		Services: []def.Service{
+			{Name: "cache", Type: "redis:6.0"},
+			{Name: "mysqldb", Type: "mariadb:10.4"},
+			{Name: "pgsql", Type: "postgresql:13"},
+		},
+		Options: map[Option]string{OptionSanitizeFile: sanitizePath},
+	}
+	rules, e := p.SanitizeRules()
+	if e != nil {
+		t.Errorf("failed to load sanitize rules, %s", e)
+	}
+	def.AssertEqual(len(rules), 3, "unexpected number of sanitize rules", t)
+	expected := []string{
+		"mysqldb:main:UPDATE `users` SET `email` = CONCAT(LEFT(SHA2(`email`, 256), 16), '@example.com') WHERE (id > 1) AND `email` IS NOT NULL;",
+		"mysqldb:main:TRUNCATE TABLE `sessions`;",
+		`pgsql:main:UPDATE "customers" SET "phone" = '0000' WHERE country = 'NL';`,
+	}
+	for i, rule := range rules {
+		service, database, e := p.sanitizeRuleTarget(rule)
+		if e != nil {
+			t.Errorf("failed to find sanitize rule target, %s", e)
+		}
+		sql, e := p.GetSanitizeSQL(service, rule)
+		if e != nil {
+			t.Errorf("failed to build sanitize sql, %s", e)
+		}
+		def.AssertEqual(service.Name+":"+database+":"+sql, expected[i], "unexpected sanitize sql", t)
+	}
+	if _, e := p.GetSanitizeSQL(p.Services[0], rules[0]); !errors.Is(e, ErrSanitizeNotSupported) {
+		t.Errorf("expected sanitize to be unsupported by redis, got %v", e)
+	}
+	// rules must name the service when there is more than one sql database
+	unqualified := &def.AppSanitizeRule{Table: "sessions", Action: def.SanitizeActionTruncate}
+	if _, _, e := p.sanitizeRuleTarget(unqualified); !errors.Is(e, ErrSanitizeServiceRequired) {
+		t.Errorf("expected sanitize rule to require a service, got %v", e)
+	}
+	p.Services = p.Services[:2]
+	service, database, e := p.sanitizeRuleTarget(unqualified)
+	if e != nil {
+		t.Errorf("failed to find sanitize rule target, %s", e)
+	}
+	def.AssertEqual(service.Name+":"+database, "mysqldb:main", "unexpected sanitize rule target", t)
+}
+
+func TestWatch(t *testing.T) {
+	ch := container.NewDummy()
+	p, e := LoadFromPath(path.Join("_test_data", "sample1"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	p.SetContainerHandler(ch)
+	p.Start()
+	files := p.WatchFiles()
+	def.AssertEqual(len(files), 6, "unexpected number of watched files", t)
+	def.AssertEqual(
+		files[0], filepath.Join(p.Path, ".platform.app.pcc.yaml"), "expected app yaml override to be watched", t,
+	)
+	// unchanged project
+	n, e := p.reload()
+	if e != nil {
+		t.Errorf("failed to reload project, %s", e)
+	}
+	def.AssertEqual(diffDefinitions(p, n).Empty(), true, "expected no changes", t)
+	// change service, app and routes
+	for i := range n.Services {
+		if n.Services[i].Name == "redis-cache" {
+			n.Services[i].Type = "redis:5.0"
+		}
+	}
+	n.Apps[0].Disk += 1024
+	n.Routes = n.Routes[1:]
+	changes := diffDefinitions(p, n)
+	def.AssertEqual(changes.Routes, true, "expected route change", t)
+	def.AssertEqual(strings.Join(changes.Services, ","), "redis-cache", "unexpected service changes", t)
+	def.AssertEqual(strings.Join(changes.Apps, ","), "test_app", "unexpected app changes", t)
+	routesReloaded := false
+	if err := p.applyWatchChanges(n, changes, func(p *Project) error {
+		routesReloaded = true
+		return nil
+	}); err != nil {
+		t.Errorf("failed to apply changes, %s", err)
+	}
+	def.AssertEqual(routesReloaded, true, "expected routes to be reloaded", t)
+	redis := ch.GetContainer(n.NewContainer(n.Services[1]).Config.GetContainerName())
+	if redis == nil {
+		t.Errorf("expected redis container to be running")
+	} else {
+		def.AssertEqual(redis.Config.Images[0], n.GetDefinitionImages(n.Services[1])[0], "expected redis to use new image", t)
+	}
+	app := ch.GetContainer(n.NewContainer(n.Apps[0]).Config.GetContainerName())
+	if app == nil {
+		t.Errorf("expected app container to be running")
+	} else {
+		def.AssertEqual(app.CommandHistoryIndex(appDeployCmd) >= 0, true, "expected app deploy hook", t)
+	}
+	// applications related to a changed service are restarted
+	def.AssertEqual(strings.Join(n.watchDependentApps([]string{"redis-cache"}, nil), ","), "test_app", "unexpected dependent apps", t)
+	def.AssertEqual(len(n.watchDependentApps([]string{"redis-cache"}, []string{"test_app"})), 0, "expected changed app not to be dependent", t)
+	def.AssertEqual(len(n.watchDependentApps([]string{"solr-search"}, nil)), 0, "expected no dependent apps", t)
+	n, _ = p.reload()
+	n2, e := n.reload()
+	if e != nil {
+		t.Errorf("failed to reload project, %s", e)
+	}
+	for i := range n2.Services {
+		if n2.Services[i].Name == "redis-cache" {
+			n2.Services[i].Type = "redis:6.0"
+		}
+	}
+	changes = diffDefinitions(n, n2)
+	def.AssertEqual(len(changes.Apps), 0, "expected no app changes", t)
+	ch.GetContainer(n.NewContainer(n.Apps[0]).Config.GetContainerName()).CommandHistory = nil
+	if err := n.applyWatchChanges(n2, changes, nil); err != nil {
+		t.Errorf("failed to apply changes, %s", err)
+	}
+	app = ch.GetContainer(n2.NewContainer(n2.Apps[0]).Config.GetContainerName())
+	if app == nil {
+		t.Errorf("expected app container to be running")
+	} else {
+		def.AssertEqual(app.CommandHistoryIndex(appDeployCmd) >= 0, true, "expected dependent app to be restarted", t)
+	}
+}
+
+func TestDiffOverrides(t *testing.T) {
+	p, e := LoadFromPath(path.Join("_test_data", "sample4"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	changes, e := p.DiffOverrides()
+	if e != nil {
+		t.Errorf("failed to diff overrides, %s", e)
+	}
+	def.AssertEqual(len(changes), 5, "unexpected number of changes", t)
+	paths := make([]string, 0)
+	for _, c := range changes {
+		paths = append(paths, c.Name+"="+c.FullPath()+":"+c.Change)
+	}
+	def.AssertEqual(
+		strings.Join(paths, ","),
+		"test_app=app.variables.env:TEST_ENV:changed,"+
+			"test_app=app.variables.env:TEST_ENV_TWO:added,"+
+			"test_app=app.runtime.extensions.2:added,"+
+			"mysqldb=service:removed,"+
+			"rediscache_two=service:added",
+		"unexpected changes",
+		t,
+	)
+	def.AssertEqual(changes[0].Old, "yes", "unexpected old value", t)
+	def.AssertEqual(changes[0].New, "no", "unexpected new value", t)
+}
+
+func TestPlatformBranch(t *testing.T) {
+	p, e := LoadFromPath(path.Join("_test_data", "sample2"), true)
+	if e != nil {
+		t.Errorf("failed to load project, %s", e)
+	}
+	def.AssertEqual(p.GetPlatformBranch(), "pcc", "unexpected default PLATFORM_BRANCH", t)
+	// git worktree with a .git file pointing at its git directory
+	gitPath := t.TempDir()
+	gitDir := t.TempDir()
+	if e := ioutil.WriteFile(filepath.Join(gitPath, ".git"), []byte("gitdir: "+gitDir+"\n"), 0644); e != nil {
+		t.Fatalf("failed to write git directory pointer, %s", e)
+	}
+	psh := &platformsh.Project{
+		ID:        "abcdefghijkl",
+		LocalPath: gitPath,
+		Environments: []platformsh.Environment{
+			{Name: "main", MachineName: "main-abc123", IsMain: true, Status: "active"},
+			{Name: "staging", MachineName: "staging-def456", Parent: "main", Status: "active"},
+			{Name: "feature", MachineName: "feature-ghi789", Parent: "staging", Status: "inactive"},
+		},
+	}
+	config.SetPath(t.TempDir())
+	defer config.SetPath("")
+	cachePath := filepath.Join(config.Path(), "psh_environments", psh.ID+".json")
+	loadBranch := func(branch string) *Project {
+		if e := ioutil.WriteFile(
+			filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/"+branch+"\n"), 0644,
+		); e != nil {
+			t.Fatalf("failed to write git head, %s", e)
+		}
+		p, e := LoadFromPath(path.Join("_test_data", "sample2"), true)
+		if e != nil {
+			t.Errorf("failed to load project, %s", e)
+		}
+		p.PlatformSH = psh
+		return p
+	}
+	// without cached environments the git branch is used
+	p = loadBranch("staging")
+	vars := p.GetPlatformEnvironmentVariables(p.Apps[0])
+	def.AssertEqual(vars["PLATFORM_BRANCH"], "staging", "unexpected uncached PLATFORM_BRANCH", t)
+	def.AssertEqual(vars["PLATFORM_ENVIRONMENT"], "staging", "unexpected uncached PLATFORM_ENVIRONMENT", t)
+	// cached environments are mapped without the api, unknown branches do not fall back to main
+	if e := os.MkdirAll(filepath.Dir(cachePath), 0755); e != nil {
+		t.Fatalf("failed to create environment cache directory, %s", e)
+	}
+	rawEnvs, _ := json.Marshal(psh.Environments)
+	if e := ioutil.WriteFile(cachePath, rawEnvs, 0644); e != nil {
+		t.Fatalf("failed to write environment cache, %s", e)
+	}
+	for branch, expected := range map[string][]string{
+		"staging": {"staging", "staging-def456"},
+		"feature": {"staging", "staging-def456"},
+		"unknown": {"unknown", "unknown"},
+	} {
+		p := loadBranch(branch)
+		vars := p.GetPlatformEnvironmentVariables(p.Apps[0])
+		def.AssertEqual(vars["PLATFORM_BRANCH"], expected[0], "unexpected PLATFORM_BRANCH for branch "+branch, t)
+		def.AssertEqual(vars["PLATFORM_ENVIRONMENT"], expected[1], "unexpected PLATFORM_ENVIRONMENT for branch "+branch, t)
+	}
+	// sync commands map unknown branches to the main environment
+	for branch, expected := range map[string]string{
+		"staging": "staging-def456",
+		"feature": "staging-def456",
+		"unknown": "main-abc123",
+	} {
+		p := loadBranch(branch)
+		env, e := p.PlatformSHEnvironment("")
+		if e != nil {
+			t.Errorf("failed to map branch %s, %s", branch, e)
+		}
+		def.AssertEqual(env.MachineName, expected, "unexpected environment mapped to branch "+branch, t)
+	}
+}
+
+func TestPlatformSHPush(t *testing.T) {
+	p := Project{
+		Apps:     []def.App{{Name: "app"}},
+		Services: []def.Service{{Name: "db", Type: "mariadb:10.4"}},
+		PlatformSH: &platformsh.Project{
+			ID: "abcdefghijkl",
+			Environments: []platformsh.Environment{
+				{Name: "main", MachineName: "main-abc123", IsMain: true, Status: "active"},
+			},
+		},
+	}
+	for _, push := range []func(string, bool) error{p.PlatformSHPushMounts, p.PlatformSHPushDatabases} {
+		if e := push("main", false); !errors.Is(e, ErrPushMainEnvironment) {
+			t.Errorf("expected push to main environment to be refused, got %v", e)
+		}
+	}
+	rels := map[string]interface{}{
+		"database": []interface{}{
+			map[string]interface{}{"service": "db", "rel": "mysql", "host": "db.internal", "username": "user", "password": "pass"},
+		},
+	}
+	def.AssertEqual(
+		p.GetPlatformSHDatabaseImportCommand(p.Services[0], "main", rels),
+		`mysql --host="db.internal" -uuser --password=pass main`,
+		"unexpected mysql remote import",
+		t,
+	)
+	def.AssertEqual(
+		p.GetPlatformSHDatabaseImportCommand(def.Service{Name: "cache", Type: "redis:6.0"}, "0", rels),
+		"",
+		"expected no remote import without relationship",
+		t,
+	)
+}
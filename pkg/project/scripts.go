@@ -208,3 +208,115 @@ until [ -f /tmp/.ready1 ]; do sleep 1; done
 timeout 1m bash -c 'until [ -f /tmp/.ready2 ]; do sleep 1; done'
 echo '%s' | base64 -d | /etc/platform/commands/open
 `
+
+// appXdebugIniCmd locates the xdebug ini file in the php ini scan directory.
+const appXdebugIniCmd = `
+SCAN_DIR="$(php --ini | grep "Scan for additional" | sed -e 's/.*:\s*//')"
+if [ -z "$SCAN_DIR" ] || [ "$SCAN_DIR" = "(none)" ]; then
+	SCAN_DIR="$(dirname "$(php --ini | grep "Loaded Configuration" | sed -e 's/.*:\s*//')")/conf.d"
+fi
+XDEBUG_INI="$SCAN_DIR/zz-pcc-xdebug.ini"
+`
+
+// appXdebugEnableCmd is the command to enable xdebug in an application.
+// The client host, client port and idekey are given as the arguments.
+const appXdebugEnableCmd = appXdebugIniCmd + `
+if [ ! -f "$(php -r 'echo ini_get("extension_dir");')/xdebug.so" ]; then
+	echo "xdebug extension is not available"
+	exit 1
+fi
+mkdir -p "$SCAN_DIR"
+XDEBUG_VERSION="$(php -d zend_extension=xdebug.so -r 'echo phpversion("xdebug");' 2>/dev/null)"
+if [ "${XDEBUG_VERSION%%.*}" -ge 3 ] 2>/dev/null; then
+cat > "$XDEBUG_INI" <<EOF
+zend_extension=xdebug.so
+xdebug.mode=debug
+xdebug.start_with_request=trigger
+xdebug.client_host=$1
+xdebug.client_port=$2
+xdebug.idekey=$3
+EOF
+else
+cat > "$XDEBUG_INI" <<EOF
+zend_extension=xdebug.so
+xdebug.remote_enable=1
+xdebug.remote_host=$1
+xdebug.remote_port=$2
+xdebug.idekey=$3
+EOF
+fi
+pkill -USR2 -f "php-fpm: master" || true
+`
+
+// appXdebugDisableCmd is the command to disable xdebug in an application.
+const appXdebugDisableCmd = appXdebugIniCmd + `
+rm -f "$XDEBUG_INI"
+pkill -USR2 -f "php-fpm: master" || true
+`
+
+// elasticsearchScript is the python script used to dump, import and delete Elasticsearch/OpenSearch indexes.
+const elasticsearchScript = `
+import base64, json, os, sys
+try:
+    from urllib2 import urlopen, Request, HTTPError
+except ImportError:
+    from urllib.request import urlopen, Request
+    from urllib.error import HTTPError
+url = os.environ.get("ES_URL", "http://127.0.0.1:9200").rstrip("/")
+auth = os.environ.get("ES_AUTH", "")
+mode = sys.argv[1]
+pattern = sys.argv[2] if len(sys.argv) > 2 and sys.argv[2] else "*"
+def req(method, path, body=None, ctype="application/json"):
+    headers = {"Content-Type": ctype}
+    if auth:
+        headers["Authorization"] = "Basic " + base64.b64encode(auth.encode("utf-8")).decode("ascii")
+    r = Request(url + path, data=body.encode("utf-8") if body is not None else None, headers=headers)
+    r.get_method = lambda: method
+    try:
+        return json.loads(urlopen(r).read().decode("utf-8"))
+    except HTTPError as e:
+        if e.code == 404 and method in ("GET", "DELETE"):
+            return {}
+        raise
+def indices():
+    return [n for n in req("GET", "/" + pattern + "?expand_wildcards=open") if not n.startswith(".")]
+if mode == "dump":
+    for name, idx in req("GET", "/" + pattern + "?expand_wildcards=open").items():
+        if name.startswith("."):
+            continue
+        settings = idx.get("settings", {}).get("index", {})
+        for k in ("uuid", "creation_date", "version", "provided_name", "routing", "resize", "blocks"):
+            settings.pop(k, None)
+        print(json.dumps({"pcc_index": name, "settings": {"index": settings}, "mappings": idx.get("mappings", {})}))
+        res = req("POST", "/" + name + "/_search?scroll=1m", json.dumps({"size": 500, "sort": ["_doc"]}))
+        while res.get("hits", {}).get("hits"):
+            for hit in res["hits"]["hits"]:
+                print(json.dumps({"pcc_doc": hit["_source"], "index": name, "id": hit["_id"], "type": hit.get("_type")}))
+            res = req("POST", "/_search/scroll", json.dumps({"scroll": "1m", "scroll_id": res["_scroll_id"]}))
+elif mode == "delete":
+    for name in indices():
+        req("DELETE", "/" + name)
+elif mode == "import":
+    bulk = []
+    def flush():
+        if bulk:
+            req("POST", "/_bulk", "\n".join(bulk) + "\n", "application/x-ndjson")
+            del bulk[:]
+    for line in sys.stdin:
+        if not line.strip():
+            continue
+        data = json.loads(line)
+        if "pcc_index" in data:
+            flush()
+            req("DELETE", "/" + data["pcc_index"])
+            req("PUT", "/" + data["pcc_index"], json.dumps({"settings": data["settings"], "mappings": data["mappings"]}))
+            continue
+        meta = {"_index": data["index"], "_id": data["id"]}
+        if data.get("type") and data["type"] != "_doc":
+            meta["_type"] = data["type"]
+        bulk.append(json.dumps({"index": meta}))
+        bulk.append(json.dumps(data["pcc_doc"]))
+        if len(bulk) >= 1000:
+            flush()
+    flush()
+`
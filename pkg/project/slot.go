@@ -0,0 +1,222 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// SlotInfo contains user metadata about a volume slot.
+type SlotInfo struct {
+	Label    string    `json:"label,omitempty"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Slots contains the active volume slot and metadata about each slot.
+type Slots struct {
+	Active int               `json:"active,omitempty"`
+	Info   map[int]*SlotInfo `json:"info,omitempty"`
+}
+
+// SlotStatus contains information about a volume slot.
+type SlotStatus struct {
+	Slot     int       `json:"slot"`
+	Label    string    `json:"label"`
+	Active   bool      `json:"active"`
+	LastUsed time.Time `json:"last_used"`
+	Volumes  []string  `json:"volumes"`
+	Size     int64     `json:"size"` // size in bytes, -1 if unknown
+}
+
+// slotInfo returns the metadata of given slot, creating it if needed.
+func (p *Project) slotInfo(slot int) *SlotInfo {
+	if p.Slots.Info == nil {
+		p.Slots.Info = make(map[int]*SlotInfo)
+	}
+	if p.Slots.Info[slot] == nil {
+		p.Slots.Info[slot] = &SlotInfo{}
+	}
+	return p.Slots.Info[slot]
+}
+
+// GetSlot returns the current slot.
+func (p *Project) GetSlot() int {
+	return p.slot
+}
+
+// ActiveSlot returns the slot persisted with the slot switch command.
+func (p *Project) ActiveSlot() int {
+	if p.Slots.Active <= 0 {
+		return 1
+	}
+	return p.Slots.Active
+}
+
+// ResolveSlot returns the slot with given number or label.
+func (p *Project) ResolveSlot(value string) (int, error) {
+	if slot, err := strconv.Atoi(value); err == nil {
+		if slot <= 0 {
+			return 0, errors.Wrapf(container.ErrInvalidSlot, "slot %d must be greater than zero", slot)
+		}
+		return slot, nil
+	}
+	for slot, info := range p.Slots.Info {
+		if info != nil && info.Label == value {
+			return slot, nil
+		}
+	}
+	return 0, errors.Wrapf(container.ErrInvalidSlot, "no slot labeled '%s'", value)
+}
+
+// SlotLabel sets the label of given slot, an empty label removes it.
+func (p *Project) SlotLabel(slot int, label string) error {
+	label = strings.TrimSpace(label)
+	if slot <= 0 {
+		return errors.Wrapf(container.ErrInvalidSlot, "slot %d must be greater than zero", slot)
+	}
+	if _, err := strconv.Atoi(label); err == nil {
+		return errors.Wrapf(container.ErrInvalidSlot, "label '%s' can not be a number", label)
+	}
+	for s, info := range p.Slots.Info {
+		if s != slot && info != nil && label != "" && info.Label == label {
+			return errors.Wrapf(container.ErrInvalidSlot, "label '%s' is already used by slot %d", label, s)
+		}
+	}
+	output.Info(fmt.Sprintf("Label slot %d '%s.'", slot, label))
+	p.slotInfo(slot).Label = label
+	return nil
+}
+
+// SlotSwitch sets the current slot and persists it as the active slot.
+func (p *Project) SlotSwitch(slot int) {
+	p.SetSlot(slot)
+	p.Slots.Active = p.slot
+}
+
+// updateSlots applies update to the slot metadata in the project file. Only the slot metadata
+// is rewritten so changes made to the file since the project was loaded are kept.
+func (p *Project) updateSlots(update func(s *Slots)) error {
+	projectJSONPath := filepath.Join(p.Path, projectJSONFilename)
+	data, err := ioutil.ReadFile(projectJSONPath)
+	if os.IsNotExist(err) {
+		update(&p.Slots)
+		return errors.WithStack(p.Save())
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.WithStack(err)
+	}
+	slots := Slots{}
+	if len(raw["slots"]) > 0 {
+		if err := json.Unmarshal(raw["slots"], &slots); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	update(&slots)
+	if raw["slots"], err = json.Marshal(slots); err != nil {
+		return errors.WithStack(err)
+	}
+	if data, err = json.Marshal(raw); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(projectJSONPath, data, 0655); err != nil {
+		return errors.WithStack(err)
+	}
+	p.Slots = slots
+	return nil
+}
+
+// SlotTouch records in the project file that the current slot was used.
+func (p *Project) SlotTouch() error {
+	slot := p.slot
+	return errors.WithStack(p.updateSlots(func(s *Slots) {
+		if s.Info == nil {
+			s.Info = make(map[int]*SlotInfo)
+		}
+		if s.Info[slot] == nil {
+			s.Info[slot] = &SlotInfo{}
+		}
+		s.Info[slot].LastUsed = time.Now()
+	}))
+}
+
+// SlotForget removes the metadata of given slot from the project file, the active slot
+// is reset if it is the given slot.
+func (p *Project) SlotForget(slot int) error {
+	return errors.WithStack(p.updateSlots(func(s *Slots) {
+		delete(s.Info, slot)
+		if s.Active == slot {
+			s.Active = 0
+		}
+	}))
+}
+
+// SlotList returns the status of every slot that has volumes or metadata.
+func (p *Project) SlotList() ([]SlotStatus, error) {
+	vols, err := p.containerHandler.ProjectVolumes(p.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	slots := make(map[int]*SlotStatus)
+	getStatus := func(slot int) *SlotStatus {
+		if slots[slot] == nil {
+			slots[slot] = &SlotStatus{Slot: slot, Active: slot == p.ActiveSlot(), Volumes: make([]string, 0)}
+			if info := p.Slots.Info[slot]; info != nil {
+				slots[slot].Label = info.Label
+				slots[slot].LastUsed = info.LastUsed
+			}
+		}
+		return slots[slot]
+	}
+	for slot := range p.Slots.Info {
+		getStatus(slot)
+	}
+	getStatus(p.ActiveSlot())
+	for _, v := range vols {
+		s := getStatus(v.Slot)
+		s.Volumes = append(s.Volumes, v.Name)
+		if v.Size < 0 || s.Size < 0 {
+			s.Size = -1
+			continue
+		}
+		s.Size += v.Size
+	}
+	out := make([]SlotStatus, 0)
+	for _, s := range slots {
+		sort.Strings(s.Volumes)
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Slot < out[j].Slot
+	})
+	return out, nil
+}
@@ -32,6 +32,10 @@ func (p *Project) Status() []container.Status {
 			status.ObjectType = c.Config.ObjectType
 			status.Type = app.Type
 		}
+		if isPHPApp(app) {
+			status.Xdebug = p.xdebugEnabled(c, status)
+		}
+		CheckHealth(c.containerHandler, &status)
 		out = append(out, status)
 		for _, worker := range app.Workers {
 			wc := p.NewContainer(worker)
@@ -41,6 +45,7 @@ func (p *Project) Status() []container.Status {
 				status.ObjectType = wc.Config.ObjectType
 				status.Type = worker.Type
 			}
+			CheckHealth(c.containerHandler, &status)
 			out = append(out, status)
 		}
 	}
@@ -52,6 +57,7 @@ func (p *Project) Status() []container.Status {
 			status.ObjectType = c.Config.ObjectType
 			status.Type = service.Type
 		}
+		CheckHealth(c.containerHandler, &status)
 		out = append(out, status)
 	}
 	return out
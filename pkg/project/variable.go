@@ -22,6 +22,8 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
 
@@ -35,16 +37,55 @@ func (p *Project) VarSet(key string, value string) error {
 	return errors.WithStack(p.Variables.Set(key, value))
 }
 
+// VarSetSensitive encrypts and sets a sensitive project variable.
+func (p *Project) VarSetSensitive(key string, value string) error {
+	output.Info(
+		fmt.Sprintf("Set sensitive var '%s.'", key),
+	)
+	value, err := config.EncryptVariable(strings.TrimSpace(value))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(p.Variables.Set(key, value))
+}
+
+// VarIsSensitive returns true if given project variable is sensitive.
+func (p *Project) VarIsSensitive(key string) bool {
+	return p.Variables.IsSensitive(key)
+}
+
 // VarGet retrieves a project variable.
 func (p *Project) VarGet(key string) (string, error) {
 	output.Info(
 		fmt.Sprintf("Get var '%s.'", key),
 	)
 	out := p.Variables.GetString(key)
+	if p.VarIsSensitive(key) {
+		out = def.SensitiveVariableMask
+	}
 	output.LogDebug(fmt.Sprintf("Get var '%s.'", key), out)
 	return out, nil
 }
 
+// VarImport sets all given project variables, values keep their type unless they are stored as sensitive.
+func (p *Project) VarImport(vars def.Variables, sensitive bool) error {
+	for _, k := range vars.Keys() {
+		if sensitive {
+			if err := p.VarSetSensitive(k, def.InterfaceToString(vars.Get(k))); err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+		output.Info(
+			fmt.Sprintf("Set var '%s.'", k),
+		)
+		if err := p.Variables.Set(k, vars.Get(k)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
 // VarDelete deletes a project variable.
 func (p *Project) VarDelete(key string) error {
 	output.Info(
@@ -53,3 +94,21 @@ func (p *Project) VarDelete(key string) error {
 	p.Variables.Delete(key)
 	return nil
 }
+
+// decryptVariables returns a copy of given variables with sensitive values decrypted.
+func (p *Project) decryptVariables(vars def.Variables) def.Variables {
+	out := make(def.Variables)
+	for k, v := range vars {
+		if def.IsSensitiveVariable(v) {
+			value, err := config.DecryptVariable(v.(string))
+			if err != nil {
+				output.Warn(fmt.Sprintf("Could not decrypt sensitive var '%s.'", k))
+				output.LogError(err)
+				continue
+			}
+			v = value
+		}
+		out[k] = v
+	}
+	return out
+}
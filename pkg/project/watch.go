@@ -0,0 +1,331 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const watchInterval = time.Second
+
+// WatchChanges defines the definitions that changed between two loads of a project.
+type WatchChanges struct {
+	Routes          bool     `json:"routes"`
+	Apps            []string `json:"apps"`             // added or modified applications
+	RemovedApps     []string `json:"removed_apps"`     // applications no longer defined
+	Services        []string `json:"services"`         // added or modified services
+	RemovedServices []string `json:"removed_services"` // services no longer defined
+}
+
+// Empty returns true if nothing changed.
+func (w WatchChanges) Empty() bool {
+	return !w.Routes && len(w.Apps) == 0 && len(w.RemovedApps) == 0 &&
+		len(w.Services) == 0 && len(w.RemovedServices) == 0
+}
+
+// WatchFiles returns the paths of all yaml files that define the project, including
+// pcc overrides and files that do not exist yet.
+func (p *Project) WatchFiles() []string {
+	out := make([]string, 0)
+	dirs := map[string]bool{}
+	for _, appYamlFiles := range scanPlatformAppYaml(p.Path, false) {
+		dirs[filepath.Dir(appYamlFiles[0])] = true
+	}
+	for dir := range dirs {
+		for _, fn := range appYamlFilenames {
+			out = append(out, filepath.Join(dir, fn))
+		}
+	}
+	for _, fn := range serviceYamlFilenames {
+		out = append(out, filepath.Join(p.Path, fn))
+	}
+	for _, fn := range routesYamlFilenames {
+		out = append(out, filepath.Join(p.Path, fn))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// watchState returns the modification state of given files, missing files have an empty state.
+func watchState(files []string) map[string]string {
+	out := make(map[string]string)
+	for _, f := range files {
+		stat, err := os.Stat(f)
+		if err != nil {
+			out[f] = ""
+			continue
+		}
+		out[f] = fmt.Sprintf("%d:%d", stat.ModTime().UnixNano(), stat.Size())
+	}
+	return out
+}
+
+// diffDefinitions compares the definitions of two loads of a project.
+func diffDefinitions(old *Project, new *Project) WatchChanges {
+	out := WatchChanges{
+		Apps:            make([]string, 0),
+		RemovedApps:     make([]string, 0),
+		Services:        make([]string, 0),
+		RemovedServices: make([]string, 0),
+	}
+	seen := map[string]bool{}
+	for _, c := range def.Diff(old.Definitions(), new.Definitions()) {
+		if c.Type == def.DiffTypeRoute {
+			out.Routes = true
+			continue
+		}
+		key := c.Type + "/" + c.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		removed := c.Path == "" && c.Change == def.DiffRemoved
+		switch {
+		case c.Type == def.DiffTypeApp && removed:
+			{
+				out.RemovedApps = append(out.RemovedApps, c.Name)
+				break
+			}
+		case c.Type == def.DiffTypeApp:
+			{
+				out.Apps = append(out.Apps, c.Name)
+				break
+			}
+		case c.Type == def.DiffTypeService && removed:
+			{
+				out.RemovedServices = append(out.RemovedServices, c.Name)
+				break
+			}
+		case c.Type == def.DiffTypeService:
+			{
+				out.Services = append(out.Services, c.Name)
+			}
+		}
+	}
+	return out
+}
+
+// Watch watches the project yaml files until stop is closed and applies the minimal action
+// for each change. Changed services are restarted, changed applications are rebuilt and
+// reloadRoutes is called when the routes or applications change.
+func (p *Project) Watch(stop <-chan struct{}, reloadRoutes func(p *Project) error) error {
+	cur := p
+	files := cur.WatchFiles()
+	state := watchState(files)
+	output.Info(fmt.Sprintf("Watch %d file(s) for changes.", len(files)))
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			{
+				return nil
+			}
+		case <-ticker.C:
+			{
+				// scan again so application directories created while watching are picked up
+				files = cur.WatchFiles()
+				newState := watchState(files)
+				if reflect.DeepEqual(state, newState) {
+					continue
+				}
+				// wait for writes to settle before parsing
+				time.Sleep(watchInterval)
+				state = watchState(files)
+				n, err := cur.reload()
+				if err != nil {
+					output.Warn(fmt.Sprintf("Failed to load project, %s.", err))
+					continue
+				}
+				if valErrs := n.Validate(); len(valErrs) > 0 {
+					output.Warn(fmt.Sprintf("Validation failed with %d error(s), changes not applied.", len(valErrs)))
+					output.IndentLevel++
+					for _, e := range valErrs {
+						output.Warn(e.Error())
+					}
+					output.IndentLevel--
+					continue
+				}
+				changes := diffDefinitions(cur, n)
+				if changes.Empty() {
+					output.Info("No definition changes.")
+					continue
+				}
+				if err := cur.applyWatchChanges(n, changes, reloadRoutes); err != nil {
+					output.LogError(err)
+					output.Warn(fmt.Sprintf("Failed to apply changes, %s.", err))
+				}
+				cur = n
+				files = cur.WatchFiles()
+				state = watchState(files)
+			}
+		}
+	}
+}
+
+// reload loads the project again from its path, keeping the runtime settings.
+func (p *Project) reload() (*Project, error) {
+	n, err := LoadFromPath(p.Path, true)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n.containerHandler = p.containerHandler
+	n.slot = p.slot
+	n.noCommit = p.noCommit
+	n.noBuild = p.noBuild
+	return n, nil
+}
+
+// watchDependentApps returns the unchanged applications with a relationship to one of the given services.
+func (p *Project) watchDependentApps(services []string, changedApps []string) []string {
+	out := make([]string, 0)
+	for _, a := range p.Apps {
+		if sliceContainsString(changedApps, a.Name) {
+			continue
+		}
+		for _, rel := range a.Relationships {
+			if sliceContainsString(services, strings.Split(rel, ":")[0]) {
+				out = append(out, a.Name)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// applyWatchChanges applies the changes between the project and given newly loaded project.
+// Applications with a relationship to a restarted service are restarted so they receive
+// the new address of the service.
+func (p *Project) applyWatchChanges(n *Project, changes WatchChanges, reloadRoutes func(p *Project) error) error {
+	done := output.Duration("Apply definition changes.")
+	dependentApps := n.watchDependentApps(changes.Services, changes.Apps)
+	restartApps := append(append([]string{}, changes.Apps...), dependentApps...)
+	// stop removed and changed definitions
+	for _, d := range p.watchDefinitions(changes.RemovedServices, changes.RemovedApps) {
+		if err := p.NewContainer(d).Stop(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, d := range p.watchDefinitions(changes.Services, restartApps) {
+		if err := p.NewContainer(d).Stop(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// restart changed services
+	for _, d := range n.watchDefinitions(changes.Services, nil) {
+		if _, err := n.startContainer(n.NewContainer(d), nil); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// rebuild changed applications, restart dependent applications from their committed build
+	if len(changes.Apps) > 0 || len(dependentApps) > 0 {
+		if err := n.openServices(changes.Services); err != nil {
+			return errors.WithStack(err)
+		}
+		rebuildDefs := n.watchDefinitions(nil, changes.Apps)
+		defs := append(rebuildDefs, n.watchDefinitions(nil, dependentApps)...)
+		for i, d := range defs {
+			c := n.NewContainer(d)
+			if i < len(rebuildDefs) {
+				if err := c.DeleteCommit(); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			if _, err := n.startContainer(c, nil); err != nil {
+				return errors.WithStack(err)
+			}
+			if err := c.Deploy(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if err := n.WaitHealthy(defs); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, d := range defs {
+			if err := n.NewContainer(d).PostDeploy(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	// regenerate routes
+	if reloadRoutes != nil && (changes.Routes || len(changes.Apps) > 0 || len(dependentApps) > 0 || len(changes.RemovedApps) > 0) {
+		if err := reloadRoutes(n); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	done()
+	return nil
+}
+
+// openServices collects the relationships of all running services not listed in skip.
+func (p *Project) openServices(skip []string) error {
+	skipped := map[string]bool{}
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	for _, s := range p.Services {
+		if s.GetTypeName() == "network-storage" || skipped[s.Name] {
+			continue
+		}
+		c := p.NewContainer(s)
+		rels, err := c.Open()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		p.relationshipsLock.Lock()
+		p.relationships = append(p.relationships, rels...)
+		p.relationshipsLock.Unlock()
+	}
+	return nil
+}
+
+// watchDefinitions returns the service and application definitions (with workers) with the given names.
+func (p *Project) watchDefinitions(services []string, apps []string) []interface{} {
+	out := make([]interface{}, 0)
+	for _, s := range p.Services {
+		for _, name := range services {
+			if s.Name == name && s.GetTypeName() != "network-storage" {
+				out = append(out, s)
+			}
+		}
+	}
+	for _, a := range p.Apps {
+		for _, name := range apps {
+			if a.Name != name {
+				continue
+			}
+			out = append(out, a)
+			if p.HasFlag(EnableWorkers) {
+				for _, w := range a.Workers {
+					out = append(out, w)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// xdebugStateDir is the config directory that records the containers xdebug is enabled in.
+const xdebugStateDir = "xdebug"
+
+// isPHPApp returns true if given app uses the php runtime.
+func isPHPApp(app def.App) bool {
+	return strings.HasPrefix(app.Type, "php")
+}
+
+// XdebugEnable enables xdebug in the running container of given app.
+func (p *Project) XdebugEnable(app def.App) error {
+	done := output.Duration(fmt.Sprintf("Enable xdebug for '%s.'", app.Name))
+	c, status, err := p.xdebugContainer(app)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	host, port, err := net.SplitHostPort(p.GetOption(OptionXdebugClient))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// values are passed as arguments so that they are never evaluated by the shell
+	if _, err := c.containerHandler.ContainerCommand(
+		c.Config.GetContainerName(),
+		"root",
+		[]string{"bash", "-c", appXdebugEnableCmd, "bash", host, port, app.Runtime.Xdebug.IdeKey},
+		nil,
+	); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.xdebugStatePath(c)), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(p.xdebugStatePath(c), []byte(status.ID), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// XdebugDisable disables xdebug in the running container of given app.
+func (p *Project) XdebugDisable(app def.App) error {
+	done := output.Duration(fmt.Sprintf("Disable xdebug for '%s.'", app.Name))
+	c, _, err := p.xdebugContainer(app)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := c.containerHandler.ContainerCommand(
+		c.Config.GetContainerName(),
+		"root",
+		[]string{"bash", "-c", appXdebugDisableCmd},
+		nil,
+	); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.Remove(p.xdebugStatePath(c)); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// xdebugEnabled returns true if xdebug was enabled in the container with given status.
+// A recreated container has a new id and starts without xdebug.
+func (p *Project) xdebugEnabled(c Container, status container.Status) bool {
+	if !status.Running || status.ID == "" {
+		return false
+	}
+	id, err := ioutil.ReadFile(p.xdebugStatePath(c))
+	return err == nil && string(id) == status.ID
+}
+
+// xdebugStatePath returns the path to the file that records the id of the container xdebug is enabled in.
+func (p *Project) xdebugStatePath(c Container) string {
+	return filepath.Join(config.Path(), xdebugStateDir, c.Config.GetContainerName())
+}
+
+// xdebugContainer returns the container and its status for given app if it is a running php application.
+func (p *Project) xdebugContainer(app def.App) (Container, container.Status, error) {
+	if !isPHPApp(app) {
+		return Container{}, container.Status{}, errors.WithStack(ErrNotPHPApplication)
+	}
+	c := p.NewContainer(app)
+	status, err := c.containerHandler.ContainerStatus(c.Config.GetContainerName())
+	if err != nil {
+		return Container{}, container.Status{}, errors.WithStack(err)
+	}
+	if !status.Running {
+		return Container{}, container.Status{}, errors.WithStack(container.ErrContainerNotRunning)
+	}
+	return c, status, nil
+}
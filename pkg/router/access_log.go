@@ -0,0 +1,316 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
+)
+
+// nginxAccessLogPath is the path to the structured access log in the router container.
+const nginxAccessLogPath = "/var/log/nginx/pcc_access.log"
+
+// nativeAccessLogFile is the name of the native router access log in the config directory.
+const nativeAccessLogFile = "router_access.log"
+
+// accessLogTail is the number of existing log lines shown before following.
+const accessLogTail = 100
+
+// accessLogMaxSize is the size in bytes at which an access log is rotated.
+const accessLogMaxSize = 10 * 1024 * 1024
+
+// accessLogRotateCmd is a shell loop that rotates the nginx access log in the router container.
+var accessLogRotateCmd = fmt.Sprintf(
+	`while true; do sleep 60; if [ "$(stat -c %%s %[1]s 2>/dev/null || echo 0)" -gt %[2]d ]; then mv -f %[1]s %[1]s.1 && nginx -s reopen; fi; done &`,
+	nginxAccessLogPath, accessLogMaxSize,
+)
+
+// AccessLogEntry is a single request handled by the router.
+type AccessLogEntry struct {
+	Time         time.Time `json:"time"`
+	ProjectID    string    `json:"project_id"`
+	Host         string    `json:"host"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int       `json:"status"`
+	Latency      float64   `json:"latency"`
+	RequestSize  int64     `json:"request_size"`
+	ResponseSize int64     `json:"response_size"`
+	Upstream     string    `json:"upstream"`
+	RouteType    string    `json:"route_type"`
+	RoutePath    string    `json:"route_path"`
+	Route        string    `json:"route"`
+	Cache        string    `json:"cache"`
+}
+
+// accessLogWriter records the status and size of a response.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher so streamed upstream responses are not buffered.
+func (w *accessLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker so upgraded connections such as WebSockets can be proxied.
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.WithStack(ErrHijackNotSupported)
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, nil
+}
+
+// fileAccessLog appends access log entries to a file as JSON lines.
+// The file is moved to a '.1' suffix once it grows past maxSize.
+type fileAccessLog struct {
+	lock    sync.Mutex
+	path    string
+	maxSize int64
+}
+
+// Log writes an entry to the access log.
+func (l *fileAccessLog) Log(entry AccessLogEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		output.LogError(err)
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if info, err := os.Stat(l.path); err == nil && l.maxSize > 0 && info.Size() >= l.maxSize {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			output.LogError(err)
+		}
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		output.LogError(err)
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+// nativeAccessLogPath returns the path to the native router access log.
+func nativeAccessLogPath() string {
+	return filepath.Join(config.Path(), nativeAccessLogFile)
+}
+
+// ProjectHosts returns the hosts routed to given project.
+func ProjectHosts(p *project.Project) []string {
+	out := make([]string, 0)
+	for _, hostMap := range MapHostRoutes(p.RoutesReplaceDefault(p.Routes)) {
+		out = append(out, hostMap.Host)
+	}
+	return out
+}
+
+// accessLogParser parses JSON log lines written to it.
+type accessLogParser struct {
+	buf      bytes.Buffer
+	filter   func(AccessLogEntry) bool
+	callback func(AccessLogEntry) error
+}
+
+// Write implements io.Writer.
+func (p *accessLogParser) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		line, err := p.buf.ReadBytes('\n')
+		if err != nil {
+			// keep partial line for the next write
+			p.buf.Reset()
+			p.buf.Write(line)
+			return len(b), nil
+		}
+		if err := p.parseLine(line); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+}
+
+// parseLine parses a single log line and passes it to the callback.
+func (p *accessLogParser) parseLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	entry := AccessLogEntry{}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		output.LogDebug("Skip invalid access log line.", string(line))
+		return nil
+	}
+	if p.filter != nil && !p.filter(entry) {
+		return nil
+	}
+	return p.callback(entry)
+}
+
+// StreamAccessLog passes router access log entries accepted by filter to callback, waiting for new entries if follow is set.
+func StreamAccessLog(follow bool, filter func(AccessLogEntry) bool, callback func(AccessLogEntry) error) error {
+	parser := &accessLogParser{filter: filter, callback: callback}
+	if IsNative() {
+		return errors.WithStack(tailFile(nativeAccessLogPath(), follow, parser))
+	}
+	ch, err := getContainerHandler()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	cmd := []string{"tail", "-n", fmt.Sprintf("%d", accessLogTail), nginxAccessLogPath}
+	if follow {
+		cmd = []string{"tail", "-n", fmt.Sprintf("%d", accessLogTail), "-F", nginxAccessLogPath}
+	}
+	if _, err := ch.ContainerCommand(
+		GetContainerConfig().GetContainerName(),
+		"root",
+		cmd,
+		parser,
+	); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// tailFile writes the last lines of a file to w and, if follow is set, keeps writing appended lines.
+func tailFile(path string, follow bool, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !follow {
+			return nil
+		}
+		for os.IsNotExist(err) {
+			time.Sleep(250 * time.Millisecond)
+			f, err = os.Open(path)
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	defer func() { f.Close() }()
+	// read last lines
+	lines := make([]string, 0, accessLogTail)
+	reader := bufio.NewReader(f)
+	partial := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			partial = line
+			break
+		}
+		lines = append(lines, line)
+		if len(lines) > accessLogTail {
+			lines = lines[1:]
+		}
+	}
+	if _, err := w.Write([]byte(strings.Join(lines, "") + partial)); err != nil {
+		return errors.WithStack(err)
+	}
+	for follow {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			if _, err := w.Write([]byte(line)); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if err == io.EOF {
+			time.Sleep(250 * time.Millisecond)
+			// reopen the file once it has been rotated
+			if rotated(f, path) {
+				nf, err := os.Open(path)
+				if err != nil {
+					continue
+				}
+				// pass on what was written before the rotation
+				if rest, _ := ioutil.ReadAll(reader); len(rest) > 0 {
+					if _, err := w.Write(rest); err != nil {
+						nf.Close()
+						return errors.WithStack(err)
+					}
+				}
+				f.Close()
+				f = nf
+				reader = bufio.NewReader(f)
+			}
+			continue
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// rotated returns true if path no longer refers to the open file f.
+func rotated(f *os.File, path string) bool {
+	openInfo, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(openInfo, pathInfo)
+}
@@ -0,0 +1,358 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// cacheHeader is the response header reporting if a response came from the cache.
+const cacheHeader = "X-Platform-Cache"
+
+// maxCacheBodySize is the largest response body the native router will cache.
+const maxCacheBodySize = 8 * 1024 * 1024
+
+// nginxCachePath is the directory in the router container containing cached responses.
+const nginxCachePath = "/var/cache/pcc"
+
+// nativeCacheClearSuffix is the suffix of marker files asking the native router to clear its cache.
+const nativeCacheClearSuffix = ".clearcache"
+
+// nativeCacheClearAll is the marker name used to clear the cache of all projects.
+const nativeCacheClearAll = "_"
+
+// cacheableStatus are the response codes that are cached using the route default TTL.
+var cacheableStatus = []int{http.StatusOK, http.StatusMovedPermanently, http.StatusFound}
+
+// nonAlphaNumeric matches characters not allowed in nginx variable and zone names.
+var nonAlphaNumeric = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// cacheContextKey is the request context key holding the cache request.
+type cacheContextKey struct{}
+
+// cacheRequest is attached to proxied requests of routes with cache enabled.
+type cacheRequest struct {
+	key        string
+	defaultTTL int
+}
+
+// cacheEntry is a cached upstream response.
+type cacheEntry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// responseCache is an in memory cache of upstream responses.
+type responseCache struct {
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// newResponseCache creates an empty response cache.
+func newResponseCache() *responseCache {
+	return &responseCache{entries: map[string]*cacheEntry{}}
+}
+
+// get returns the unexpired entry for given key.
+func (c *responseCache) get(key string) *cacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := c.entries[key]
+	if entry == nil {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry
+}
+
+// set stores an entry.
+func (c *responseCache) set(key string, entry *cacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = entry
+}
+
+// clear removes all entries of given project, or all entries if pid is empty.
+func (c *responseCache) clear(pid string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.entries {
+		if pid == "" || strings.HasPrefix(key, pid+"\n") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// write sends the cached response.
+func (e *cacheEntry) write(w http.ResponseWriter) {
+	for k, v := range e.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(cacheHeader, "HIT")
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// isCookiePattern returns true if given cache cookie name is a regular expression.
+func isCookiePattern(name string) bool {
+	return len(name) > 1 && strings.HasPrefix(name, "/") && strings.HasSuffix(name, "/")
+}
+
+// cacheKey builds the cache key of a request from the route cache configuration.
+func cacheKey(pid string, cache activeRouterCache, r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	key := []string{pid, scheme, r.Method, r.Host, r.URL.RequestURI()}
+	for _, header := range cache.Headers {
+		key = append(key, r.Header.Get(header))
+	}
+	cookies := r.Cookies()
+	for _, name := range cache.Cookies {
+		switch {
+		case name == "*":
+			{
+				key = append(key, r.Header.Get("Cookie"))
+				break
+			}
+		case isCookiePattern(name):
+			{
+				re, err := regexp.Compile(strings.Trim(name, "/"))
+				if err != nil {
+					key = append(key, r.Header.Get("Cookie"))
+					break
+				}
+				matched := make([]string, 0)
+				for _, cookie := range cookies {
+					if re.MatchString(cookie.Name) {
+						matched = append(matched, cookie.Name+"="+cookie.Value)
+					}
+				}
+				sort.Strings(matched)
+				key = append(key, strings.Join(matched, ";"))
+				break
+			}
+		default:
+			{
+				value := ""
+				if cookie, err := r.Cookie(name); err == nil {
+					value = cookie.Value
+				}
+				key = append(key, value)
+				break
+			}
+		}
+	}
+	return strings.Join(key, "\n")
+}
+
+// cacheTTL returns how long a response may be cached for, zero if it may not.
+func cacheTTL(resp *http.Response, defaultTTL int) time.Duration {
+	if resp.Header.Get("Set-Cookie") != "" {
+		return 0
+	}
+	maxAge := -1
+	sharedMaxAge := -1
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store", directive == "no-cache", directive == "private":
+			{
+				return 0
+			}
+		case strings.HasPrefix(directive, "s-maxage="):
+			{
+				sharedMaxAge, _ = strconv.Atoi(strings.TrimPrefix(directive, "s-maxage="))
+				break
+			}
+		case strings.HasPrefix(directive, "max-age="):
+			{
+				maxAge, _ = strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+				break
+			}
+		}
+	}
+	if sharedMaxAge >= 0 {
+		return time.Duration(sharedMaxAge) * time.Second
+	}
+	if maxAge >= 0 {
+		return time.Duration(maxAge) * time.Second
+	}
+	for _, status := range cacheableStatus {
+		if resp.StatusCode == status {
+			return time.Duration(defaultTTL) * time.Second
+		}
+	}
+	return 0
+}
+
+// modifyResponse reports cache status and stores cacheable upstream responses.
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	cacheReq, ok := resp.Request.Context().Value(cacheContextKey{}).(*cacheRequest)
+	if !ok {
+		return nil
+	}
+	resp.Header.Set(cacheHeader, "MISS")
+	if cacheReq.key == "" || resp.ContentLength > maxCacheBodySize {
+		return nil
+	}
+	ttl := cacheTTL(resp, cacheReq.defaultTTL)
+	if ttl <= 0 {
+		return nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCacheBodySize+1))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(body) > maxCacheBodySize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return nil
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	header := resp.Header.Clone()
+	header.Del(cacheHeader)
+	p.cache.set(cacheReq.key, &cacheEntry{
+		status:  resp.StatusCode,
+		header:  header,
+		body:    body,
+		expires: time.Now().Add(ttl),
+	})
+	return nil
+}
+
+// ClearCache clears cached responses of given project, or of all projects if pid is empty.
+func (p *Proxy) ClearCache(pid string) {
+	p.cache.clear(pid)
+}
+
+// nginxCacheZone returns the name of the nginx cache zone of given project.
+func nginxCacheZone(pid string) string {
+	return "pcc_" + nonAlphaNumeric.ReplaceAllString(pid, "_")
+}
+
+// nginxCacheKey returns the nginx proxy_cache_key for the cache variables of a route.
+func nginxCacheKey(cache activeRouterCache) string {
+	key := []string{"$scheme", "$request_method", "$host", "$request_uri"}
+	for _, header := range cache.Headers {
+		key = append(key, "$http_"+strings.ToLower(nonAlphaNumeric.ReplaceAllString(header, "_")))
+	}
+	for _, name := range cache.Cookies {
+		if name == "*" || isCookiePattern(name) || nonAlphaNumeric.MatchString(name) {
+			key = append(key, "$http_cookie")
+			continue
+		}
+		key = append(key, "$cookie_"+name)
+	}
+	return strings.Join(key, "|")
+}
+
+// ClearCache clears the router cache of given project, or of all projects if pid is empty.
+func ClearCache(pid string) error {
+	done := output.Duration("Clear router cache.")
+	if IsNative() {
+		if pid == "" {
+			pid = nativeCacheClearAll
+		}
+		if err := os.MkdirAll(filepath.Join(config.Path(), nativeRouteDir), 0755); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := ioutil.WriteFile(
+			filepath.Join(config.Path(), nativeRouteDir, pid+nativeCacheClearSuffix), []byte{}, 0644,
+		); err != nil {
+			return errors.WithStack(err)
+		}
+		done()
+		return nil
+	}
+	ch, err := getContainerHandler()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	path := nginxCachePath + "/*"
+	if pid != "" {
+		path = fmt.Sprintf("%s/%s/*", nginxCachePath, pid)
+	}
+	containerConf := GetContainerConfig()
+	if _, err := ch.ContainerCommand(
+		containerConf.GetContainerName(),
+		"root",
+		[]string{"sh", "-c", "rm -rf " + path},
+		nil,
+	); err != nil {
+		if errors.Is(err, container.ErrContainerNotFound) {
+			output.LogDebug("Router not running.", nil)
+			done()
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// processNativeCacheClears clears the proxy cache for every pending clear marker.
+func processNativeCacheClears(proxy *Proxy) error {
+	files, err := ioutil.ReadDir(filepath.Join(config.Path(), nativeRouteDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), nativeCacheClearSuffix) {
+			continue
+		}
+		pid := strings.TrimSuffix(f.Name(), nativeCacheClearSuffix)
+		if pid == nativeCacheClearAll {
+			pid = ""
+		}
+		proxy.ClearCache(pid)
+		if err := os.Remove(filepath.Join(config.Path(), nativeRouteDir, f.Name())); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/container"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
+)
+
+// caCertFile is the name of the CA certificate file, kept compatible with minica.
+const caCertFile = "minica.pem"
+
+// caKeyFile is the name of the CA private key file, kept compatible with minica.
+const caKeyFile = "minica-key.pem"
+
+// nginxSSLPath is the path to the certificates in the global volume of the router container.
+const nginxSSLPath = "/var/pcc_global/ssl"
+
+// nativeSSLDir is the config sub directory containing certificates for the native router.
+const nativeSSLDir = "ssl"
+
+// hostCertDir is the sub directory containing leaf certificates of each host.
+const hostCertDir = "hosts"
+
+// hostCertRenewBefore is how long before expiry a host certificate is reissued.
+const hostCertRenewBefore = 30 * 24 * time.Hour
+
+// caWaitTimeout is how long to wait for the router container to create the CA.
+const caWaitTimeout = 10 * time.Second
+
+// certificateAuthority signs leaf certificates for routed hosts.
+type certificateAuthority struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+}
+
+// parseCertificateAuthority parses a PEM bundle containing the CA certificate and private key.
+func parseCertificateAuthority(data []byte) (*certificateAuthority, error) {
+	ca := &certificateAuthority{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			{
+				cert, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				ca.cert = cert
+				ca.certPEM = pem.EncodeToMemory(block)
+				break
+			}
+		case "RSA PRIVATE KEY":
+			{
+				key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				ca.key = key
+				break
+			}
+		case "EC PRIVATE KEY":
+			{
+				key, err := x509.ParseECPrivateKey(block.Bytes)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				ca.key = key
+				break
+			}
+		case "PRIVATE KEY":
+			{
+				key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				signer, ok := key.(crypto.Signer)
+				if !ok {
+					return nil, errors.WithStack(ErrCertificateCANotFound)
+				}
+				ca.key = signer
+				break
+			}
+		}
+	}
+	if ca.cert == nil || ca.key == nil {
+		return nil, errors.WithStack(ErrCertificateCANotFound)
+	}
+	return ca, nil
+}
+
+// newCertificateAuthority creates a new self signed CA, also returning its PEM encoded private key.
+func newCertificateAuthority() (*certificateAuthority, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Platform.CC CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+	ca, err := parseCertificateAuthority(append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM...,
+	))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return ca, keyPEM, nil
+}
+
+// issue signs a new leaf certificate for given host, returning the PEM encoded certificate chain and key.
+func (ca *certificateAuthority) issue(host string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(2, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	certPEM := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), ca.certPEM...)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+	return certPEM, keyPEM, nil
+}
+
+// nativeSSLPath returns the path to the certificates of the native router.
+func nativeSSLPath() string {
+	return filepath.Join(config.Path(), nativeSSLDir)
+}
+
+// loadCertificateAuthority loads the CA used to sign host certificates.
+func loadCertificateAuthority() (*certificateAuthority, error) {
+	if IsNative() {
+		data, err := DumpCertificateCA()
+		if err == nil {
+			return parseCertificateAuthority(data)
+		}
+		if !os.IsNotExist(errors.Cause(err)) {
+			return nil, errors.WithStack(err)
+		}
+		ca, keyPEM, err := newCertificateAuthority()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := os.MkdirAll(nativeSSLPath(), 0700); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(nativeSSLPath(), caCertFile), ca.certPEM, 0644); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(nativeSSLPath(), caKeyFile), keyPEM, 0600); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return ca, nil
+	}
+	// the router container creates the CA in the global volume on start
+	start := time.Now()
+	for {
+		data, err := DumpCertificateCA()
+		if err == nil {
+			return parseCertificateAuthority(data)
+		}
+		if !errors.Is(err, container.ErrCommandExited) || time.Since(start) > caWaitTimeout {
+			return nil, errors.WithStack(err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// listHostCertificates returns the hosts that have a certificate in the router container
+// which is still valid at the given time.
+func listHostCertificates(ch container.Interface, validAt time.Time) []string {
+	var buf bytes.Buffer
+	if _, err := ch.ContainerCommand(
+		GetContainerConfig().GetContainerName(),
+		"root",
+		[]string{"sh", "-c", fmt.Sprintf(
+			`for f in %s/%s/*/cert.pem; do [ -f "$f" ] && cat "$f"; done`, nginxSSLPath, hostCertDir,
+		)},
+		&buf,
+	); err != nil {
+		return []string{}
+	}
+	return validHostCertificates(buf.Bytes(), validAt)
+}
+
+// validHostCertificates returns the hosts of the PEM encoded certificates in data that are valid at the given time.
+func validHostCertificates(data []byte, validAt time.Time) []string {
+	out := make([]string, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return out
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || len(cert.DNSNames) == 0 || validAt.After(cert.NotAfter) {
+			continue
+		}
+		out = append(out, cert.DNSNames[0])
+	}
+}
+
+// addHostCertificates adds certificates for all hosts of given project that do not have a valid one to the tarball.
+// Certificates that expire within hostCertRenewBefore are reissued.
+func addHostCertificates(ch container.Interface, p *project.Project, tarball *tar.Writer) error {
+	existing := listHostCertificates(ch, time.Now().Add(hostCertRenewBefore))
+	hosts := make([]string, 0)
+	for _, hostMap := range MapHostRoutes(p.RoutesReplaceDefault(p.Routes)) {
+		hasCert := false
+		for _, host := range append(existing, hosts...) {
+			if host == hostMap.Host {
+				hasCert = true
+				break
+			}
+		}
+		if !hasCert {
+			hosts = append(hosts, hostMap.Host)
+		}
+	}
+	return errors.WithStack(writeHostCertificates(hosts, tarball))
+}
+
+// writeHostCertificates issues certificates for given hosts and adds them to the tarball.
+func writeHostCertificates(hosts []string, tarball *tar.Writer) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	ca, err := loadCertificateAuthority()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, host := range hosts {
+		output.LogDebug(fmt.Sprintf("Issue certificate for '%s.'", host), nil)
+		certPEM, keyPEM, err := ca.issue(host)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for name, data := range map[string][]byte{"cert.pem": certPEM, "key.pem": keyPEM} {
+			if err := tarball.WriteHeader(&tar.Header{
+				Name: fmt.Sprintf("%s/%s/%s/%s", nginxSSLPath, hostCertDir, host, name),
+				Size: int64(len(data)),
+				Mode: 0600,
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+			if _, err := tarball.Write(data); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// certificateStore issues and caches leaf certificates for the native router.
+type certificateStore struct {
+	ca    *certificateAuthority
+	lock  sync.Mutex
+	certs map[string]*tls.Certificate
+	allow func(host string) bool
+}
+
+// newCertificateStore creates a store issuing certificates for hosts accepted by allow.
+func newCertificateStore(ca *certificateAuthority, allow func(host string) bool) *certificateStore {
+	return &certificateStore{
+		ca:    ca,
+		certs: map[string]*tls.Certificate{},
+		allow: allow,
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate, issuing certificates on demand.
+func (s *certificateStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := strings.ToLower(hello.ServerName)
+	if host == "" || !s.allow(host) {
+		host = "localhost"
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if cert := s.certs[host]; cert != nil && time.Now().Before(cert.Leaf.NotAfter) {
+		return cert, nil
+	}
+	certPEM, keyPEM, err := s.ca.issue(host)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s.certs[host] = &cert
+	return &cert, nil
+}
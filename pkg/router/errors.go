@@ -22,4 +22,24 @@ import "github.com/pkg/errors"
 var (
 	// ErrUpstreamNotFound is an error returned when a route upstream is not found.
 	ErrUpstreamNotFound = errors.New("upstream not found")
+	// ErrCertificateCANotFound is an error returned when the CA certificate or key could not be loaded.
+	ErrCertificateCANotFound = errors.New("certificate authority not found")
+	// ErrInvalidTrustBundleFormat is an error returned when an unknown trust bundle format is requested.
+	ErrInvalidTrustBundleFormat = errors.New("invalid trust bundle format")
+	// ErrCertutilNotFound is an error returned when certutil is needed but not installed.
+	ErrCertutilNotFound = errors.New("certutil not found, install nss tools (libnss3-tools)")
+	// ErrNoNSSDatabase is an error returned when no NSS certificate database was found.
+	ErrNoNSSDatabase = errors.New("no nss certificate database found")
+	// ErrShareHostNotFound is an error returned when the host to share is not routed to the project.
+	ErrShareHostNotFound = errors.New("share host not found")
+	// ErrInvalidShareTransport is an error returned when an unknown share transport is requested.
+	ErrInvalidShareTransport = errors.New("invalid share transport")
+	// ErrShareRelayRefused is an error returned when the relay server refuses a client.
+	ErrShareRelayRefused = errors.New("relay refused connection")
+	// ErrShareSecretRequired is an error returned when the relay transport has no share secret.
+	ErrShareSecretRequired = errors.New("share secret required")
+	// ErrKnownHostsNotFound is an error returned when SSH host keys can not be verified.
+	ErrKnownHostsNotFound = errors.New("known_hosts file not found")
+	// ErrHijackNotSupported is an error returned when a response writer can not be hijacked.
+	ErrHijackNotSupported = errors.New("response writer does not support hijacking")
 )
@@ -93,9 +93,12 @@ func GenerateTemplateVars(proj *project.Project) ([]map[string]interface{}, erro
 			redirects := make([]map[string]interface{}, 0)
 			for k, v := range route.Redirects.Paths {
 				redirects = append(redirects, map[string]interface{}{
-					"path": k,
-					"to":   v.To,
-					"code": v.Code,
+					"path":          k,
+					"to":            v.To,
+					"code":          v.Code,
+					"regexp":        v.Regexp.Get(),
+					"prefix":        v.Prefix.Get(),
+					"append_suffix": v.AppendSuffix.Get(),
 				})
 			}
 			upstreamHost := ""
@@ -117,7 +120,14 @@ func GenerateTemplateVars(proj *project.Project) ([]map[string]interface{}, erro
 						"upstream":  upstreamHost,
 						"to":        route.To,
 						"redirects": redirects,
-						"route":     route,
+						"cache": activeRouterCache{
+							Enabled:    route.Cache.Enabled.Get(),
+							Headers:    route.Cache.Headers,
+							Cookies:    route.Cache.Cookies,
+							DefaultTTL: route.Cache.DefaultTTL,
+						},
+						"ssi":   route.SSI.Enabled.Get(),
+						"route": route,
 					},
 				)
 			}
@@ -144,7 +154,9 @@ func GenerateTemplateVars(proj *project.Project) ([]map[string]interface{}, erro
 
 // GenerateNginxConfig creates nginx configuration for given application.
 func GenerateNginxConfig(proj *project.Project) ([]byte, error) {
-	t, err := template.New("nginx.conf").Parse(nginxServerTemplate)
+	t, err := template.New("nginx.conf").Funcs(template.FuncMap{
+		"cacheKey": nginxCacheKey,
+	}).Parse(nginxServerTemplate)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -153,7 +165,12 @@ func GenerateNginxConfig(proj *project.Project) ([]byte, error) {
 		return nil, errors.WithStack(err)
 	}
 	var buf bytes.Buffer
-	if err := t.Execute(&buf, templateVars); err != nil {
+	if err := t.Execute(&buf, map[string]interface{}{
+		"pid":       proj.ID,
+		"cachePath": nginxCachePath,
+		"cacheZone": nginxCacheZone(proj.ID),
+		"hosts":     templateVars,
+	}); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	return buf.Bytes(), nil
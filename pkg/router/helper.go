@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -31,6 +32,9 @@ import (
 
 // ListActiveProjects returns list of project ids of projects current loaded in to the router.
 func ListActiveProjects() ([]string, error) {
+	if IsNative() {
+		return listNativeProjects()
+	}
 	ch, err := getContainerHandler()
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -71,15 +75,36 @@ func ListActiveProjects() ([]string, error) {
 	return projectIDs, nil
 }
 
+type activeRouterRedirect struct {
+	Path         string `json:"path"`
+	To           string `json:"to"`
+	Code         int    `json:"code"`
+	Regexp       bool   `json:"regexp"`
+	Prefix       bool   `json:"prefix"`
+	AppendSuffix bool   `json:"append_suffix"`
+}
+
+type activeRouterCache struct {
+	Enabled    bool     `json:"enabled"`
+	Headers    []string `json:"headers"`
+	Cookies    []string `json:"cookies"`
+	DefaultTTL int      `json:"default_ttl"`
+}
+
+type activeRouterRoute struct {
+	Path      string                 `json:"path"`
+	Type      string                 `json:"type"`
+	Upstream  string                 `json:"upstream"`
+	To        string                 `json:"to"`
+	Redirects []activeRouterRedirect `json:"redirects"`
+	Cache     activeRouterCache      `json:"cache"`
+	SSI       bool                   `json:"ssi"`
+	Route     def.Route              `json:"route"`
+}
+
 type activeRouterData struct {
-	Host   string `json:"host"`
-	Routes []struct {
-		Path     string    `json:"path"`
-		Type     string    `json:"type"`
-		Upstream string    `json:"upstream"`
-		To       string    `json:"to"`
-		Route    def.Route `json:"route"`
-	} `json:"routes"`
+	Host   string              `json:"host"`
+	Routes []activeRouterRoute `json:"routes"`
 }
 
 // ListActiveRoutes returns list of routes currently active in the router.
@@ -89,25 +114,15 @@ func ListActiveRoutes() ([]def.Route, error) {
 	if err != nil {
 		return []def.Route{}, errors.WithStack(err)
 	}
-	// itterate project ids and get route data from container
-	ch, err := getContainerHandler()
-	if err != nil {
-		return []def.Route{}, errors.WithStack(err)
-	}
-	containerConf := GetContainerConfig()
+	// itterate project ids and get route data
 	out := make([]def.Route, 0)
 	for _, pid := range projectIDs {
-		var buf bytes.Buffer
-		if _, err := ch.ContainerCommand(
-			containerConf.GetContainerName(),
-			"root",
-			[]string{"cat", fmt.Sprintf("/www/%s.json", pid)},
-			&buf,
-		); err != nil {
+		routeJSON, err := getProjectRouteJSON(pid)
+		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 		data := make([]activeRouterData, 0)
-		if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		if err := json.Unmarshal(routeJSON, &data); err != nil {
 			output.LogError(err)
 			return []def.Route{}, nil
 		}
@@ -125,3 +140,25 @@ func ListActiveRoutes() ([]def.Route, error) {
 	}
 	return out, nil
 }
+
+// getProjectRouteJSON returns the route list JSON currently loaded for given project.
+func getProjectRouteJSON(pid string) ([]byte, error) {
+	if IsNative() {
+		return ioutil.ReadFile(nativeRoutePath(pid))
+	}
+	ch, err := getContainerHandler()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	containerConf := GetContainerConfig()
+	var buf bytes.Buffer
+	if _, err := ch.ContainerCommand(
+		containerConf.GetContainerName(),
+		"root",
+		[]string{"cat", fmt.Sprintf("/www/%s.json", pid)},
+		&buf,
+	); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
@@ -22,7 +22,10 @@ import (
 	"archive/tar"
 	"bytes"
 	"fmt"
-	"strings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
@@ -38,26 +41,22 @@ var HTTPPort = uint16(80)
 // HTTPSPort is the port to accept HTTPS requests on.
 var HTTPSPort = uint16(443)
 
-// sslDomains is a list of domains to certain SSL certificates for (using minica https://github.com/jsha/minica).
-var sslDomains = []string{"localhost", "*." + strings.TrimLeft(project.OptionDomainSuffix.DefaultValue(), ".")}
+// caCreateCmd is the command that creates the CA and the default localhost certificate in the router container.
+const caCreateCmd = "cd /var/ssl && ~/go/bin/minica -domains localhost"
 
 // GetContainerConfig gets container configuration for the router.
 func GetContainerConfig() container.Config {
-	// add global domain suffix to list of ssl certifs
-	globalConfig, _ := config.Load()
-	if globalConfig.Options[string(project.OptionDomainSuffix)] != "" {
-		sslDomains = append(sslDomains, "*."+strings.TrimLeft(globalConfig.Options[string(project.OptionDomainSuffix)], "."))
-	}
+	// minica creates the CA and the default localhost certificate, host certificates are issued when routes are added
 	routerCmd := fmt.Sprintf(`
 mkdir /www
-mkdir /var/pcc_global/ssl
+mkdir -p %[1]s/%[2]s
 ln -s /var/pcc_global/ssl /var/ssl
 if [ ! -f /var/ssl/minica.pem ]; then
-	cd /var/ssl
-	~/go/bin/minica -domains "%s"
+	%[3]s
 fi
+%[4]s
 nginx -g "daemon off;"
-`, strings.Join(sslDomains, ","))
+`, nginxSSLPath, hostCertDir, caCreateCmd, accessLogRotateCmd)
 	return container.Config{
 		ProjectID:  "_",
 		ObjectName: "router",
@@ -76,12 +75,15 @@ nginx -g "daemon off;"
 }
 
 func getContainerHandler() (container.Interface, error) {
-	// TODO make container handler configurable
-	return container.NewDocker()
+	return project.NewGlobalContainerHandler()
 }
 
 // Start starts the router.
 func Start() error {
+	if IsNative() {
+		checkNativeRunning()
+		return nil
+	}
 	done := output.Duration("Start main router.")
 	ch, err := getContainerHandler()
 	if err != nil {
@@ -150,6 +152,13 @@ func Start() error {
 // Stop stops the router.
 func Stop() error {
 	done := output.Duration("Stop main router.")
+	if IsNative() {
+		if err := clearNativeRoutes(); err != nil {
+			return errors.WithStack(err)
+		}
+		done()
+		return nil
+	}
 	ch, err := getContainerHandler()
 	if err != nil {
 		return errors.WithStack(err)
@@ -163,6 +172,9 @@ func Stop() error {
 
 // Reload issues reload command to nginx in router container.
 func Reload() error {
+	if IsNative() {
+		return nil
+	}
 	ch, err := getContainerHandler()
 	if err != nil {
 		return errors.WithStack(err)
@@ -180,25 +192,54 @@ func Reload() error {
 }
 
 // ClearCertificates deletes all certificates files generates by minica.
+// A new CA is created right away and the certificates of all hosts are reissued so nginx can be reloaded.
 func ClearCertificates() error {
+	if IsNative() {
+		return errors.WithStack(os.RemoveAll(nativeSSLPath()))
+	}
 	ch, err := getContainerHandler()
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	containerConf := GetContainerConfig()
+	hosts := listHostCertificates(ch, time.Time{})
 	if _, err := ch.ContainerCommand(
 		containerConf.GetContainerName(),
 		"root",
-		[]string{"sh", "-c", "rm -rf /var/ssl/*"},
+		[]string{"sh", "-c", fmt.Sprintf(
+			"rm -rf /var/ssl/* && mkdir -p %s/%s && %s", nginxSSLPath, hostCertDir, caCreateCmd,
+		)},
 		nil,
 	); err != nil {
 		return errors.WithStack(err)
 	}
-	return nil
+	var buf bytes.Buffer
+	tarball := tar.NewWriter(&buf)
+	if err := writeHostCertificates(hosts, tarball); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tarball.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ch.ContainerUpload(containerConf.GetContainerName(), "/", &buf); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(Reload())
 }
 
 // DumpCertificateCA returns the CA certificate.
 func DumpCertificateCA() ([]byte, error) {
+	if IsNative() {
+		out := make([]byte, 0)
+		for _, name := range []string{caCertFile, caKeyFile} {
+			data, err := ioutil.ReadFile(filepath.Join(nativeSSLPath(), name))
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			out = append(out, data...)
+		}
+		return out, nil
+	}
 	ch, err := getContainerHandler()
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -208,7 +249,7 @@ func DumpCertificateCA() ([]byte, error) {
 	if _, err := ch.ContainerCommand(
 		containerConf.GetContainerName(),
 		"root",
-		[]string{"sh", "-c", "cat /var/ssl/minica.pem && cat /var/ssl/minica-key.pem"},
+		[]string{"sh", "-c", fmt.Sprintf("cat %[1]s/%[2]s && cat %[1]s/%[3]s", nginxSSLPath, caCertFile, caKeyFile)},
 		&buf,
 	); err != nil {
 		return nil, errors.WithStack(err)
@@ -221,6 +262,13 @@ func AddProjectRoutes(p *project.Project) error {
 	done := output.Duration(
 		fmt.Sprintf("Add routes for project '%s.'", p.ID),
 	)
+	if IsNative() {
+		if err := addNativeProjectRoutes(p); err != nil {
+			return errors.WithStack(err)
+		}
+		done()
+		return nil
+	}
 	ch, err := getContainerHandler()
 	if err != nil {
 		return errors.WithStack(err)
@@ -256,6 +304,10 @@ func AddProjectRoutes(p *project.Project) error {
 	if _, err := tarball.Write(routeJSON); err != nil {
 		return errors.WithStack(err)
 	}
+	// add certificates for new hosts
+	if err := addHostCertificates(ch, p, tarball); err != nil {
+		return errors.WithStack(err)
+	}
 	if err := tarball.Close(); err != nil {
 		return errors.WithStack(err)
 	}
@@ -287,6 +339,13 @@ func DeleteProjectRoutes(p *project.Project) error {
 	done := output.Duration(
 		fmt.Sprintf("Delete routes for project '%s.'", p.ID),
 	)
+	if IsNative() {
+		if err := deleteNativeProjectRoutes(p.ID); err != nil {
+			return errors.WithStack(err)
+		}
+		done()
+		return nil
+	}
 	ch, err := getContainerHandler()
 	if err != nil {
 		return errors.WithStack(err)
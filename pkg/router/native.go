@@ -0,0 +1,240 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
+)
+
+// ModeNginx runs the router as an nginx container.
+const ModeNginx = "nginx"
+
+// ModeNative runs the router in-process with 'router:serve'.
+const ModeNative = "native"
+
+// nativeRouteDir is the config sub directory containing route list JSON files read by the native router.
+const nativeRouteDir = "routes"
+
+// nativePollInterval is how often the native router checks for route changes.
+const nativePollInterval = time.Second
+
+// IsNative returns true if the router is configured to run in-process.
+func IsNative() bool {
+	gc, err := config.Load()
+	if err != nil {
+		return false
+	}
+	return gc.Router.Mode == ModeNative
+}
+
+// nativeRoutePath returns the path to the route list JSON of given project.
+func nativeRoutePath(pid string) string {
+	return filepath.Join(config.Path(), nativeRouteDir, pid+".json")
+}
+
+// listNativeProjects returns ids of all projects with routes in the native route directory.
+func listNativeProjects() ([]string, error) {
+	files, err := ioutil.ReadDir(filepath.Join(config.Path(), nativeRouteDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	out := make([]string, 0)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		out = append(out, strings.TrimSuffix(f.Name(), ".json"))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// loadNativeRoutes reads the route list JSON of all projects and a stamp that changes when any of them change.
+func loadNativeRoutes() (map[string][]byte, string, error) {
+	pids, err := listNativeProjects()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	out := make(map[string][]byte)
+	stamp := ""
+	for _, pid := range pids {
+		stat, err := os.Stat(nativeRoutePath(pid))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, "", errors.WithStack(err)
+		}
+		routeJSON, err := ioutil.ReadFile(nativeRoutePath(pid))
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		out[pid] = routeJSON
+		stamp += fmt.Sprintf("%s:%d:%d;", pid, stat.ModTime().UnixNano(), stat.Size())
+	}
+	return out, stamp, nil
+}
+
+// addNativeProjectRoutes writes given project's route list for the native router to pick up.
+func addNativeProjectRoutes(p *project.Project) error {
+	routeJSON, err := GenerateRouteListJSON(p)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Join(config.Path(), nativeRouteDir), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	// write then rename so the router never reads a partial file
+	tmpPath := nativeRoutePath(p.ID) + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, routeJSON, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmpPath, nativeRoutePath(p.ID)))
+}
+
+// deleteNativeProjectRoutes removes given project's route list from the native router.
+func deleteNativeProjectRoutes(pid string) error {
+	if err := os.Remove(nativeRoutePath(pid)); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// clearNativeRoutes removes all routes from the native router.
+func clearNativeRoutes() error {
+	pids, err := listNativeProjects()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, pid := range pids {
+		if err := deleteNativeProjectRoutes(pid); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// checkNativeRunning warns if nothing is listening on the router HTTP port.
+func checkNativeRunning() {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", HTTPPort), time.Second)
+	if err != nil {
+		output.Warn("Native router is not running, start it with 'router:serve'.")
+		return
+	}
+	conn.Close()
+}
+
+// Serve runs the native router until stop is closed.
+func Serve(stop <-chan struct{}) error {
+	gc, err := config.Load()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	HTTPPort = gc.Router.PortHTTP
+	HTTPSPort = gc.Router.PortHTTPS
+	proxy := NewProxy(resolveUpstream)
+	proxy.AccessLog = (&fileAccessLog{path: nativeAccessLogPath(), maxSize: accessLogMaxSize}).Log
+	routes, stamp, err := loadNativeRoutes()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := proxy.SetAllProjectRoutes(routes); err != nil {
+		return errors.WithStack(err)
+	}
+	ca, err := loadCertificateAuthority()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	certs := newCertificateStore(ca, func(host string) bool {
+		return host == "localhost" || proxy.HasHost(host)
+	})
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", HTTPPort),
+		Handler: proxy,
+	}
+	tlsServer := &http.Server{
+		Addr:      fmt.Sprintf(":%d", HTTPSPort),
+		Handler:   proxy,
+		TLSConfig: &tls.Config{GetCertificate: certs.GetCertificate},
+	}
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	go func() {
+		serveErr <- tlsServer.ListenAndServeTLS("", "")
+	}()
+	output.Info(fmt.Sprintf("Native router listening on ports %d and %d.", HTTPPort, HTTPSPort))
+	ticker := time.NewTicker(nativePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-serveErr:
+			{
+				return errors.WithStack(err)
+			}
+		case <-stop:
+			{
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := tlsServer.Shutdown(ctx); err != nil {
+					return errors.WithStack(err)
+				}
+				return errors.WithStack(server.Shutdown(ctx))
+			}
+		case <-ticker.C:
+			{
+				if err := processNativeCacheClears(proxy); err != nil {
+					output.LogError(err)
+				}
+				newRoutes, newStamp, err := loadNativeRoutes()
+				if err != nil {
+					output.LogError(err)
+					continue
+				}
+				if newStamp == stamp {
+					continue
+				}
+				if err := proxy.SetAllProjectRoutes(newRoutes); err != nil {
+					output.LogError(err)
+					continue
+				}
+				stamp = newStamp
+				output.Info(fmt.Sprintf("Reloaded routes for %d project(s).", len(newRoutes)))
+			}
+		}
+	}
+}
@@ -0,0 +1,468 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// proxyRedirect is a compiled route redirect path.
+type proxyRedirect struct {
+	activeRouterRedirect
+	re *regexp.Regexp
+}
+
+// match returns the redirect location if the request path matches.
+func (r proxyRedirect) match(path string) (string, bool) {
+	if r.re != nil {
+		idx := r.re.FindStringSubmatchIndex(path)
+		if idx == nil {
+			return "", false
+		}
+		return string(r.re.ExpandString(nil, r.To, path, idx)), true
+	}
+	if path == r.Path {
+		return r.To, true
+	}
+	prefix := strings.TrimRight(r.Path, "/") + "/"
+	if r.Prefix && strings.HasPrefix(path, prefix) {
+		if r.AppendSuffix {
+			return strings.TrimRight(r.To, "/") + "/" + strings.TrimPrefix(path, prefix), true
+		}
+		return r.To, true
+	}
+	return "", false
+}
+
+// proxyRoute is a single location of a host.
+type proxyRoute struct {
+	activeRouterRoute
+	redirects []proxyRedirect
+}
+
+// proxyHost contains the routes of a host sorted by longest path first.
+type proxyHost struct {
+	projectID string
+	routes    []proxyRoute
+}
+
+// routeTable is an immutable snapshot of all routes served by the proxy.
+type routeTable struct {
+	projects map[string][]byte
+	hosts    map[string]*proxyHost
+}
+
+// Proxy is an in-process HTTP router that replaces the nginx container.
+type Proxy struct {
+	// Resolve maps an upstream host (with optional port) to a dialable address.
+	// Results are cached until the routes change or the upstream fails.
+	Resolve func(upstream string) (string, error)
+	// AccessLog, if set, receives an entry for every request.
+	AccessLog     func(entry AccessLogEntry)
+	table         atomic.Value
+	lock          sync.Mutex
+	proxy         *httputil.ReverseProxy
+	cache         *responseCache
+	upstreams     map[string]string
+	upstreamsLock sync.RWMutex
+}
+
+// NewProxy creates a new proxy with an empty route table.
+func NewProxy(resolve func(upstream string) (string, error)) *Proxy {
+	p := &Proxy{Resolve: resolve, cache: newResponseCache(), upstreams: map[string]string{}}
+	p.table.Store(&routeTable{
+		projects: map[string][]byte{},
+		hosts:    map[string]*proxyHost{},
+	})
+	p.proxy = &httputil.ReverseProxy{
+		Director:       func(r *http.Request) {},
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			output.LogError(err)
+			// upstream may have been restarted with another address
+			p.forgetUpstreamAddr(r.URL.Host)
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		},
+	}
+	return p
+}
+
+// getTable returns the current route table.
+func (p *Proxy) getTable() *routeTable {
+	return p.table.Load().(*routeTable)
+}
+
+// ProjectIDs returns the ids of all projects loaded in to the proxy.
+func (p *Proxy) ProjectIDs() []string {
+	t := p.getTable()
+	out := make([]string, 0, len(t.projects))
+	for pid := range t.projects {
+		out = append(out, pid)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// HasHost returns true if given host is routed by the proxy.
+func (p *Proxy) HasHost(host string) bool {
+	_, ok := p.getTable().hosts[host]
+	return ok
+}
+
+// SetProjectRoutes replaces the routes of given project with the given route list JSON.
+func (p *Proxy) SetProjectRoutes(pid string, routeJSON []byte) error {
+	return p.swap(func(projects map[string][]byte) error {
+		projects[pid] = routeJSON
+		return nil
+	})
+}
+
+// DeleteProjectRoutes removes the routes of given project.
+func (p *Proxy) DeleteProjectRoutes(pid string) {
+	p.swap(func(projects map[string][]byte) error {
+		delete(projects, pid)
+		return nil
+	})
+}
+
+// SetAllProjectRoutes replaces the entire route table.
+func (p *Proxy) SetAllProjectRoutes(routes map[string][]byte) error {
+	return p.swap(func(projects map[string][]byte) error {
+		for pid := range projects {
+			delete(projects, pid)
+		}
+		for pid, routeJSON := range routes {
+			projects[pid] = routeJSON
+		}
+		return nil
+	})
+}
+
+// swap builds a new route table from a modified copy of the current one and stores it.
+func (p *Proxy) swap(modify func(projects map[string][]byte) error) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	projects := make(map[string][]byte)
+	for pid, routeJSON := range p.getTable().projects {
+		projects[pid] = routeJSON
+	}
+	if err := modify(projects); err != nil {
+		return errors.WithStack(err)
+	}
+	t, err := buildRouteTable(projects)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	p.table.Store(t)
+	p.clearUpstreams()
+	return nil
+}
+
+// resolve returns the cached address of given upstream, resolving it if it isn't cached.
+func (p *Proxy) resolve(upstream string) (string, error) {
+	p.upstreamsLock.RLock()
+	addr, ok := p.upstreams[upstream]
+	p.upstreamsLock.RUnlock()
+	if ok {
+		return addr, nil
+	}
+	addr, err := p.Resolve(upstream)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	p.upstreamsLock.Lock()
+	p.upstreams[upstream] = addr
+	p.upstreamsLock.Unlock()
+	return addr, nil
+}
+
+// forgetUpstreamAddr removes upstreams resolved to given address from the cache.
+func (p *Proxy) forgetUpstreamAddr(addr string) {
+	p.upstreamsLock.Lock()
+	defer p.upstreamsLock.Unlock()
+	for upstream, a := range p.upstreams {
+		if a == addr {
+			delete(p.upstreams, upstream)
+		}
+	}
+}
+
+// clearUpstreams empties the upstream address cache.
+func (p *Proxy) clearUpstreams() {
+	p.upstreamsLock.Lock()
+	p.upstreams = map[string]string{}
+	p.upstreamsLock.Unlock()
+}
+
+// buildRouteTable compiles route list JSON of all projects in to a route table.
+func buildRouteTable(projects map[string][]byte) (*routeTable, error) {
+	t := &routeTable{
+		projects: projects,
+		hosts:    map[string]*proxyHost{},
+	}
+	pids := make([]string, 0, len(projects))
+	for pid := range projects {
+		pids = append(pids, pid)
+	}
+	sort.Strings(pids)
+	for _, pid := range pids {
+		data := make([]activeRouterData, 0)
+		if err := json.Unmarshal(projects[pid], &data); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, hostData := range data {
+			host := &proxyHost{
+				projectID: pid,
+				routes:    make([]proxyRoute, 0),
+			}
+			for _, routeData := range hostData.Routes {
+				route := proxyRoute{
+					activeRouterRoute: routeData,
+					redirects:         make([]proxyRedirect, 0),
+				}
+				for _, redirectData := range routeData.Redirects {
+					redirect := proxyRedirect{activeRouterRedirect: redirectData}
+					if redirectData.Regexp {
+						re, err := regexp.Compile(redirectData.Path)
+						if err != nil {
+							return nil, errors.Wrapf(err, "invalid redirect path %s", redirectData.Path)
+						}
+						redirect.re = re
+					}
+					route.redirects = append(route.redirects, redirect)
+				}
+				sort.SliceStable(route.redirects, func(i, j int) bool {
+					return len(route.redirects[i].Path) > len(route.redirects[j].Path)
+				})
+				host.routes = append(host.routes, route)
+			}
+			sort.SliceStable(host.routes, func(i, j int) bool {
+				return len(host.routes[i].Path) > len(host.routes[j].Path)
+			})
+			if _, exists := t.hosts[hostData.Host]; exists {
+				output.Warn(fmt.Sprintf("Host '%s' of project '%s' is already routed.", hostData.Host, pid))
+				continue
+			}
+			t.hosts[hostData.Host] = host
+		}
+	}
+	return t, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := strings.ToLower(r.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	// sub requests of server side includes are not logged
+	if p.AccessLog == nil || r.Context().Value(ssiContextKey{}) != nil {
+		p.serve(host, w, r, &AccessLogEntry{})
+		return
+	}
+	entry := &AccessLogEntry{
+		Time:   time.Now(),
+		Host:   host,
+		Method: r.Method,
+		Path:   r.URL.RequestURI(),
+	}
+	if r.ContentLength > 0 {
+		entry.RequestSize = r.ContentLength
+	}
+	lw := &accessLogWriter{ResponseWriter: w}
+	p.serve(host, lw, r, entry)
+	entry.Status = lw.status
+	if entry.Status == 0 {
+		entry.Status = http.StatusOK
+	}
+	entry.ResponseSize = lw.size
+	entry.Latency = time.Since(entry.Time).Seconds()
+	entry.Cache = lw.Header().Get(cacheHeader)
+	p.AccessLog(*entry)
+}
+
+// serve routes the request, recording the matched route in entry.
+func (p *Proxy) serve(host string, w http.ResponseWriter, r *http.Request, entry *AccessLogEntry) {
+	t := p.getTable()
+	proxyHost := t.hosts[host]
+	if proxyHost == nil {
+		p.serveRouteList(t, w, r)
+		return
+	}
+	entry.ProjectID = proxyHost.projectID
+	for _, route := range proxyHost.routes {
+		if !strings.HasPrefix(r.URL.Path, route.Path) {
+			continue
+		}
+		entry.RouteType = route.Type
+		entry.RoutePath = route.Path
+		entry.Route = route.Route.OriginalURL
+		switch route.Type {
+		case "upstream":
+			{
+				for _, redirect := range route.redirects {
+					if to, ok := redirect.match(r.URL.Path); ok {
+						redirectTo(w, r, to, redirect.Code)
+						return
+					}
+				}
+				entry.Upstream = route.Upstream
+				if route.SSI {
+					p.serveSSI(w, r, func(w http.ResponseWriter, r *http.Request) {
+						p.serveUpstream(proxyHost.projectID, route, w, r)
+					})
+					return
+				}
+				p.serveUpstream(proxyHost.projectID, route, w, r)
+				return
+			}
+		case "redirect":
+			{
+				redirectTo(w, r, route.To, http.StatusMovedPermanently)
+				return
+			}
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// serveUpstream forwards the request to the route upstream.
+func (p *Proxy) serveUpstream(pid string, route proxyRoute, w http.ResponseWriter, r *http.Request) {
+	var cacheReq *cacheRequest
+	if route.Cache.Enabled {
+		cacheReq = &cacheRequest{defaultTTL: route.Cache.DefaultTTL}
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			cacheReq.key = cacheKey(pid, route.Cache, r)
+			if entry := p.cache.get(cacheReq.key); entry != nil {
+				entry.write(w)
+				return
+			}
+		}
+	}
+	addr, err := p.resolve(route.Upstream)
+	if err != nil {
+		output.LogError(err)
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	_, port, _ := net.SplitHostPort(r.Host)
+	if port == "" {
+		port = fmt.Sprintf("%d", HTTPPort)
+		if r.TLS != nil {
+			port = fmt.Sprintf("%d", HTTPSPort)
+		}
+	}
+	ctx := r.Context()
+	if cacheReq != nil {
+		ctx = context.WithValue(ctx, cacheContextKey{}, cacheReq)
+	}
+	outReq := r.Clone(ctx)
+	outReq.URL.Scheme = "http"
+	outReq.URL.Host = addr
+	if localAddr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		if ip, _, err := net.SplitHostPort(localAddr.String()); err == nil {
+			outReq.Header.Set("X-Client-IP", ip)
+		}
+	}
+	outReq.Header.Set("X-Forwarded-Host", r.Host)
+	outReq.Header.Set("X-Forwarded-Port", port)
+	outReq.Header.Set("X-Forwarded-Proto", scheme)
+	outReq.Header.Set("X-Forwarded-Server", r.Host)
+	if route.SSI {
+		outReq.Header.Del("Accept-Encoding")
+	}
+	p.proxy.ServeHTTP(w, outReq)
+}
+
+// serveRouteList serves the route list page, project list and project route JSON.
+func (p *Proxy) serveRouteList(t *routeTable, w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case name == "" || name == "index.html":
+		{
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(routeListHTML))
+			return
+		}
+	case name == "projects.txt":
+		{
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(strings.Join(p.ProjectIDs(), "\n") + "\n"))
+			return
+		}
+	case strings.HasSuffix(name, ".json"):
+		{
+			if routeJSON, ok := t.projects[strings.TrimSuffix(name, ".json")]; ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(routeJSON)
+				return
+			}
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// redirectTo sends a redirect response, keeping the query string if the target has none.
+func redirectTo(w http.ResponseWriter, r *http.Request, to string, code int) {
+	if code == 0 {
+		code = http.StatusFound
+	}
+	if r.URL.RawQuery != "" && !strings.Contains(to, "?") {
+		to += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, to, code)
+}
+
+// resolveUpstream resolves an upstream container host name to its IP address.
+func resolveUpstream(upstream string) (string, error) {
+	host, port, err := net.SplitHostPort(upstream)
+	if err != nil {
+		host = upstream
+		port = "80"
+	}
+	ch, err := getContainerHandler()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	status, err := ch.ContainerStatus(host)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if !status.Running || status.IPAddress == "" {
+		return "", errors.Wrapf(ErrUpstreamNotFound, "upstream %s is not running", host)
+	}
+	return net.JoinHostPort(status.IPAddress, port), nil
+}
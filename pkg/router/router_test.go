@@ -18,12 +18,27 @@ along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
 package router
 
 import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 // TestGenerateNginx tests the generation of nginx config.
@@ -52,4 +67,707 @@ func TestGenerateNginx(t *testing.T) {
 		"expected contextualcode-com route",
 		t,
 	)
+	def.AssertEqual(
+		strings.Contains(stringConf, "X-Platform-Cache"),
+		false,
+		"expected no cache for routes with cache disabled",
+		t,
+	)
+	// enable cache
+	for i := range proj.Routes {
+		if err := json.Unmarshal([]byte("true"), &proj.Routes[i].Cache.Enabled); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err = GenerateNginxConfig(proj)
+	if err != nil {
+		t.Error(err)
+	}
+	stringConf = string(out)
+	def.AssertEqual(
+		strings.Contains(stringConf, "proxy_cache "+nginxCacheZone(proj.ID)+";"),
+		true,
+		"expected cache zone",
+		t,
+	)
+	def.AssertEqual(
+		strings.Contains(stringConf, `proxy_cache_key "$scheme|$request_method|$host|$request_uri|$http_accept|$http_accept_language|$http_cookie"`),
+		true,
+		"expected cache key from default headers and cookies",
+		t,
+	)
+}
+
+// TestProxy tests the native router.
+func TestProxy(t *testing.T) {
+	proj, err := project.LoadFromPath(
+		filepath.Join("_test_data", "sample1"),
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routeJSON, err := GenerateRouteListJSON(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]activeRouterData, 0)
+	if err := json.Unmarshal(routeJSON, &data); err != nil {
+		t.Fatal(err)
+	}
+	upstreamHost := ""
+	redirectHost := ""
+	for _, hostData := range data {
+		for _, route := range hostData.Routes {
+			if route.Path != "/" {
+				continue
+			}
+			if route.Type == "upstream" && upstreamHost == "" {
+				upstreamHost = hostData.Host
+			} else if route.Type == "redirect" && route.Route.OriginalURL == "https://contextualcode.com/" {
+				redirectHost = hostData.Host
+			}
+		}
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.Host, r.URL.Path, r.Header.Get("X-Forwarded-Proto"))
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	resolveCount := 0
+	resolveAddr := upstreamURL.Host
+	proxy := NewProxy(func(string) (string, error) {
+		resolveCount++
+		return resolveAddr, nil
+	})
+	if err := proxy.SetProjectRoutes(proj.ID, routeJSON); err != nil {
+		t.Fatal(err)
+	}
+	request := func(host string, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://"+host+path, nil)
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		return rec
+	}
+	// upstream
+	rec := request(upstreamHost, "/hello")
+	def.AssertEqual(rec.Code, http.StatusOK, "expected upstream response", t)
+	def.AssertEqual(rec.Body.String(), upstreamHost+" /hello http", "expected request forwarded to upstream", t)
+	// upstream address is cached until the routes change or the upstream fails
+	request(upstreamHost, "/hello")
+	def.AssertEqual(resolveCount, 1, "expected upstream address to be cached", t)
+	proxy.SetProjectRoutes(proj.ID, routeJSON)
+	request(upstreamHost, "/hello")
+	def.AssertEqual(resolveCount, 2, "expected upstream address to be resolved after route change", t)
+	proxy.clearUpstreams()
+	resolveAddr = "127.0.0.1:1"
+	rec = request(upstreamHost, "/hello")
+	def.AssertEqual(rec.Code, http.StatusBadGateway, "expected unreachable upstream to fail", t)
+	resolveAddr = upstreamURL.Host
+	rec = request(upstreamHost, "/hello")
+	def.AssertEqual(rec.Code, http.StatusOK, "expected failed upstream to be resolved again", t)
+	// route redirects
+	rec = request(upstreamHost, "/test")
+	def.AssertEqual(rec.Code, http.StatusFound, "expected redirect path", t)
+	def.AssertEqual(rec.Header().Get("Location"), "/test2", "expected redirect to /test2", t)
+	rec = request(upstreamHost, "/test3/abc")
+	def.AssertEqual(rec.Header().Get("Location"), "/test4/abc", "expected regexp redirect to /test4/abc", t)
+	// redirect route
+	rec = request(redirectHost, "/")
+	def.AssertEqual(rec.Code, http.StatusMovedPermanently, "expected redirect route", t)
+	// route list
+	rec = request("localhost", "/")
+	def.AssertEqual(rec.Body.String(), routeListHTML, "expected route list page", t)
+	rec = request("localhost", "/projects.txt")
+	def.AssertEqual(rec.Body.String(), proj.ID+"\n", "expected project list", t)
+	rec = request("localhost", "/"+proj.ID+".json")
+	def.AssertEqual(rec.Body.String(), string(routeJSON), "expected project route json", t)
+	// hot swap
+	proxy.DeleteProjectRoutes(proj.ID)
+	rec = request(upstreamHost, "/hello")
+	def.AssertEqual(rec.Code, http.StatusNotFound, "expected upstream removed", t)
+}
+
+// TestProxyCache tests the native router cache.
+func TestProxyCache(t *testing.T) {
+	proj, err := project.LoadFromPath(
+		filepath.Join("_test_data", "sample1"),
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routeJSON, err := GenerateRouteListJSON(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]activeRouterData, 0)
+	if err := json.Unmarshal(routeJSON, &data); err != nil {
+		t.Fatal(err)
+	}
+	upstreamHost := ""
+	for i := range data {
+		for j := range data[i].Routes {
+			if data[i].Routes[j].Type == "upstream" {
+				data[i].Routes[j].Cache.Enabled = true
+				data[i].Routes[j].Cache.Cookies = []string{"SESS"}
+				upstreamHost = data[i].Host
+			}
+		}
+	}
+	routeJSON, _ = json.Marshal(data)
+	upstreamCount := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCount++
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private")
+		}
+		fmt.Fprintf(w, "%d", upstreamCount)
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	proxy := NewProxy(func(string) (string, error) {
+		return upstreamURL.Host, nil
+	})
+	if err := proxy.SetProjectRoutes(proj.ID, routeJSON); err != nil {
+		t.Fatal(err)
+	}
+	request := func(path string, accept string, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://"+upstreamHost+path, nil)
+		req.Header.Set("Accept", accept)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := request("/page", "text/html", "")
+	def.AssertEqual(rec.Header().Get(cacheHeader), "MISS", "expected cache miss", t)
+	rec = request("/page", "text/html", "other=1")
+	def.AssertEqual(rec.Header().Get(cacheHeader), "HIT", "expected cache hit", t)
+	def.AssertEqual(rec.Body.String(), "1", "expected cached body", t)
+	rec = request("/page", "application/json", "")
+	def.AssertEqual(rec.Header().Get(cacheHeader), "MISS", "expected cache miss for different header", t)
+	rec = request("/page", "text/html", "SESS=abc")
+	def.AssertEqual(rec.Header().Get(cacheHeader), "MISS", "expected cache miss for different cookie", t)
+	request("/private", "text/html", "")
+	rec = request("/private", "text/html", "")
+	def.AssertEqual(rec.Header().Get(cacheHeader), "MISS", "expected private response not cached", t)
+	req := httptest.NewRequest("GET", "https://"+upstreamHost+"/page", nil)
+	req.Header.Set("Accept", "text/html")
+	rec = httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+	def.AssertEqual(rec.Header().Get(cacheHeader), "MISS", "expected cache miss for different scheme", t)
+	proxy.ClearCache(proj.ID)
+	rec = request("/page", "text/html", "")
+	def.AssertEqual(rec.Header().Get(cacheHeader), "MISS", "expected cache miss after clear", t)
+	def.AssertEqual(upstreamCount, 7, "expected upstream request count", t)
+}
+
+// TestGenerateNginxSSI tests server side includes in the generated nginx config.
+func TestGenerateNginxSSI(t *testing.T) {
+	proj, err := project.LoadFromPath(
+		filepath.Join("_test_data", "sample1"),
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := GenerateNginxConfig(proj)
+	if err != nil {
+		t.Error(err)
+	}
+	def.AssertEqual(
+		strings.Contains(string(out), "ssi on;"),
+		true,
+		"expected ssi for routes with ssi enabled",
+		t,
+	)
+	// disable ssi
+	for i := range proj.Routes {
+		if err := json.Unmarshal([]byte("false"), &proj.Routes[i].SSI.Enabled); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err = GenerateNginxConfig(proj)
+	if err != nil {
+		t.Error(err)
+	}
+	def.AssertEqual(
+		strings.Contains(string(out), "ssi on;"),
+		false,
+		"expected no ssi for routes with ssi disabled",
+		t,
+	)
+}
+
+// TestProxySSI tests server side includes in the native router.
+func TestProxySSI(t *testing.T) {
+	proj, err := project.LoadFromPath(
+		filepath.Join("_test_data", "sample1"),
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/stream":
+			{
+				w.Header().Set("Content-Type", "text/plain")
+				fmt.Fprint(w, "first")
+				w.(http.Flusher).Flush()
+				select {
+				case <-release:
+				case <-time.After(5 * time.Second):
+				}
+				fmt.Fprint(w, "second")
+				break
+			}
+		case "/fragment":
+			{
+				fmt.Fprint(w, "fragment")
+				break
+			}
+		default:
+			{
+				fmt.Fprint(w, `<p><!--# include virtual="/fragment" --></p>`)
+				break
+			}
+		}
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	for _, enabled := range []bool{true, false} {
+		routeJSON, err := GenerateRouteListJSON(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]activeRouterData, 0)
+		if err := json.Unmarshal(routeJSON, &data); err != nil {
+			t.Fatal(err)
+		}
+		upstreamHost := ""
+		for i := range data {
+			for j := range data[i].Routes {
+				if data[i].Routes[j].Type == "upstream" {
+					data[i].Routes[j].SSI = enabled
+					upstreamHost = data[i].Host
+				}
+			}
+		}
+		routeJSON, _ = json.Marshal(data)
+		proxy := NewProxy(func(string) (string, error) {
+			return upstreamURL.Host, nil
+		})
+		if err := proxy.SetProjectRoutes(proj.ID, routeJSON); err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "http://"+upstreamHost+"/page", nil)
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		expected := `<p><!--# include virtual="/fragment" --></p>`
+		if enabled {
+			expected = "<p>fragment</p>"
+		}
+		def.AssertEqual(rec.Body.String(), expected, fmt.Sprintf("unexpected body with ssi enabled=%t", enabled), t)
+		if !enabled {
+			continue
+		}
+		// responses that are not html are streamed
+		server := httptest.NewServer(proxy)
+		req, _ = http.NewRequest("GET", server.URL+"/stream", nil)
+		req.Host = upstreamHost
+		var resp *http.Response
+		firstChunk := make(chan string, 1)
+		go func() {
+			resp, err = http.DefaultClient.Do(req)
+			if err != nil {
+				firstChunk <- ""
+				return
+			}
+			first := make([]byte, 5)
+			io.ReadFull(resp.Body, first)
+			firstChunk <- string(first)
+		}()
+		select {
+		case first := <-firstChunk:
+			{
+				def.AssertEqual(first, "first", "expected streamed first chunk", t)
+				break
+			}
+		case <-time.After(2 * time.Second):
+			{
+				t.Errorf("expected first chunk before upstream finished")
+				<-firstChunk
+			}
+		}
+		close(release)
+		if resp == nil {
+			t.Fatal(err)
+		}
+		rest, _ := ioutil.ReadAll(resp.Body)
+		def.AssertEqual(string(rest), "second", "expected streamed rest of body", t)
+		resp.Body.Close()
+		server.Close()
+	}
+}
+
+// TestCertificates tests issuing host certificates and exporting the CA trust bundle.
+func TestCertificates(t *testing.T) {
+	ca, keyPEM, err := newCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedCA, err := parseCertificateAuthority(append(ca.certPEM, keyPEM...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(parsedCA.cert.Equal(ca.cert), true, "expected parsed CA", t)
+	// issue leaf certificate
+	certPEM, leafKeyPEM, err := parsedCA.issue("www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, leafKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "www.example.com", Roots: roots})
+	def.AssertEqual(err, nil, "expected leaf certificate signed by CA", t)
+	// on demand certificates
+	store := newCertificateStore(ca, func(host string) bool {
+		return host == "www.example.com"
+	})
+	tlsCert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(tlsCert.Leaf.DNSNames[0], "www.example.com", "expected certificate for routed host", t)
+	tlsCert, err = store.GetCertificate(&tls.ClientHelloInfo{ServerName: "unknown.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(tlsCert.Leaf.DNSNames[0], "localhost", "expected localhost certificate for unknown host", t)
+	// expired host certificates are reissued
+	hostCerts := validHostCertificates(certPEM, time.Now())
+	def.AssertEqual(strings.Join(hostCerts, ","), "www.example.com", "expected valid host certificate", t)
+	hostCerts = validHostCertificates(certPEM, time.Now().AddDate(3, 0, 0))
+	def.AssertEqual(len(hostCerts), 0, "expected expired host certificate to be skipped", t)
+	// p12 trust store
+	p12, err := trustBundle(parsedCA, TrustBundleFormatP12, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted, err := pkcs12.DecodeTrustStore(p12, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(len(trusted), 1, "expected one certificate in trust store", t)
+	def.AssertEqual(string(trusted[0].Raw), string(ca.cert.Raw), "expected CA certificate in trust store", t)
+	// nginx config
+	proj, err := project.LoadFromPath(
+		filepath.Join("_test_data", "sample1"),
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := GenerateNginxConfig(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hostMap := range MapHostRoutes(proj.RoutesReplaceDefault(proj.Routes)) {
+		def.AssertEqual(
+			strings.Contains(string(out), "ssl_certificate /var/ssl/hosts/"+hostMap.Host+"/cert.pem;"),
+			true,
+			"expected host certificate for "+hostMap.Host,
+			t,
+		)
+	}
+}
+
+// TestProxyAccessLog tests the router access log.
+func TestProxyAccessLog(t *testing.T) {
+	proj, err := project.LoadFromPath(
+		filepath.Join("_test_data", "sample1"),
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routeJSON, err := GenerateRouteListJSON(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]activeRouterData, 0)
+	if err := json.Unmarshal(routeJSON, &data); err != nil {
+		t.Fatal(err)
+	}
+	upstreamHost := ""
+	for _, hostData := range data {
+		for _, route := range hostData.Routes {
+			if route.Type == "upstream" && upstreamHost == "" {
+				upstreamHost = hostData.Host
+			}
+		}
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		fmt.Fprint(w, "hello")
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	proxy := NewProxy(func(string) (string, error) {
+		return upstreamURL.Host, nil
+	})
+	entries := make([]AccessLogEntry, 0)
+	proxy.AccessLog = func(entry AccessLogEntry) {
+		entries = append(entries, entry)
+	}
+	if err := proxy.SetProjectRoutes(proj.ID, routeJSON); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/page?a=1", "/test"} {
+		req := httptest.NewRequest("POST", "http://"+upstreamHost+path, strings.NewReader("body"))
+		proxy.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	def.AssertEqual(len(entries), 2, "expected two log entries", t)
+	def.AssertEqual(entries[0].ProjectID, proj.ID, "expected project id", t)
+	def.AssertEqual(entries[0].Host, upstreamHost, "expected host", t)
+	def.AssertEqual(entries[0].Path, "/page?a=1", "expected path", t)
+	def.AssertEqual(entries[0].Status, http.StatusTeapot, "expected upstream status", t)
+	def.AssertEqual(entries[0].RequestSize, int64(4), "expected request size", t)
+	def.AssertEqual(entries[0].ResponseSize, int64(5), "expected response size", t)
+	def.AssertEqual(entries[0].RouteType, "upstream", "expected upstream route", t)
+	def.AssertEqual(entries[0].Route != "", true, "expected matched route", t)
+	def.AssertEqual(entries[0].Upstream != "", true, "expected upstream", t)
+	def.AssertEqual(entries[1].Status, http.StatusFound, "expected redirect status", t)
+	def.AssertEqual(entries[1].Upstream, "", "expected no upstream for redirect", t)
+	// parse log lines
+	parsed := make([]AccessLogEntry, 0)
+	parser := &accessLogParser{
+		filter: func(entry AccessLogEntry) bool {
+			return entry.Host == upstreamHost
+		},
+		callback: func(entry AccessLogEntry) error {
+			parsed = append(parsed, entry)
+			return nil
+		},
+	}
+	line1, _ := json.Marshal(entries[0])
+	line2, _ := json.Marshal(AccessLogEntry{Host: "other.example.com"})
+	lines := string(line1) + "\n" + string(line2) + "\n"
+	parser.Write([]byte(lines[:10]))
+	parser.Write([]byte(lines[10:]))
+	def.AssertEqual(len(parsed), 1, "expected filtered log entries", t)
+	def.AssertEqual(parsed[0].Route, entries[0].Route, "expected parsed route", t)
+	// nginx log variables
+	out, err := GenerateNginxConfig(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(
+		strings.Contains(string(out), `set $pcc_project "`+proj.ID+`";`),
+		true,
+		"expected project id log variable",
+		t,
+	)
+	def.AssertEqual(
+		strings.Contains(string(out), `set $pcc_route "`+entries[0].Route+`";`),
+		true,
+		"expected route log variable",
+		t,
+	)
+}
+
+// TestProxyAccessLogUpgrade tests that upgraded connections are proxied and logged.
+func TestProxyAccessLogUpgrade(t *testing.T) {
+	proj, err := project.LoadFromPath(
+		filepath.Join("_test_data", "sample1"),
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routeJSON, err := GenerateRouteListJSON(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]activeRouterData, 0)
+	if err := json.Unmarshal(routeJSON, &data); err != nil {
+		t.Fatal(err)
+	}
+	upstreamHost := ""
+	for _, hostData := range data {
+		for _, route := range hostData.Routes {
+			if route.Type == "upstream" && upstreamHost == "" {
+				upstreamHost = hostData.Host
+			}
+		}
+	}
+	// upstream switches protocols and echoes a single line
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString(line)
+		rw.Flush()
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	proxy := NewProxy(func(string) (string, error) {
+		return upstreamURL.Host, nil
+	})
+	entries := make(chan AccessLogEntry, 1)
+	proxy.AccessLog = func(entry AccessLogEntry) {
+		entries <- entry
+	}
+	if err := proxy.SetProjectRoutes(proj.ID, routeJSON); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: %s\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n", upstreamHost)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(resp.StatusCode, http.StatusSwitchingProtocols, "expected switching protocols", t)
+	fmt.Fprint(conn, "ping\n")
+	line, _ := reader.ReadString('\n')
+	def.AssertEqual(line, "ping\n", "expected echo over upgraded connection", t)
+	conn.Close()
+	select {
+	case entry := <-entries:
+		{
+			def.AssertEqual(entry.Status, http.StatusSwitchingProtocols, "expected switching protocols log status", t)
+			def.AssertEqual(entry.Path, "/ws", "expected upgrade path", t)
+		}
+	case <-time.After(5 * time.Second):
+		{
+			t.Error("expected log entry for upgraded connection")
+		}
+	}
+}
+
+// TestAccessLogRotate tests rotation of the native access log file.
+func TestAccessLogRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), nativeAccessLogFile)
+	log := &fileAccessLog{path: path, maxSize: 100}
+	for i := 0; i < 3; i++ {
+		log.Log(AccessLogEntry{Host: fmt.Sprintf("host%d.example.com", i)})
+	}
+	rotated, err := ioutil.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(strings.Contains(string(rotated), "host1.example.com"), true, "expected older entries in rotated log", t)
+	def.AssertEqual(strings.Contains(string(current), "host2.example.com"), true, "expected newest entry in current log", t)
+	def.AssertEqual(strings.Contains(string(current), "host1.example.com"), false, "expected older entries moved out of current log", t)
+}
+
+// TestShare tests sharing a host through the relay transport.
+func TestShare(t *testing.T) {
+	routerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "https://"+r.Host+"/target", http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "%s %s", r.Host, r.URL.Path)
+	}))
+	defer routerServer.Close()
+	routerURL, _ := url.Parse(routerServer.URL)
+	relay := NewRelayServer("127.0.0.1:0", "127.0.0.1:0", "", "")
+	if err := relay.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer relay.Close()
+	def.AssertEqual(len(relay.Secret), relayIDSize*2, "expected random relay secret", t)
+	// clients without the secret are refused
+	_, err := NewShare("www-example-com.platform.cc", routerURL.Host, NewRelayTransport(relay.ControlAddr, ""))
+	def.AssertEqual(errors.Is(err, ErrShareSecretRequired), true, "expected secret required error", t)
+	_, err = NewShare("www-example-com.platform.cc", routerURL.Host, NewRelayTransport(relay.ControlAddr, "wrong"))
+	def.AssertEqual(errors.Is(err, ErrShareRelayRefused), true, "expected relay to refuse invalid secret", t)
+	share, err := NewShare("www-example-com.platform.cc", routerURL.Host, NewRelayTransport(relay.ControlAddr, relay.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(share.PublicURL, relay.PublicURL, "expected relay public url", t)
+	// request through relay
+	resp, err := http.Get(share.PublicURL + "/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	def.AssertEqual(string(body), "www-example-com.platform.cc /hello", "expected host header rewrite", t)
+	// redirect to shared host points at public url
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = client.Get(share.PublicURL + "/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	def.AssertEqual(resp.Header.Get("Location"), share.PublicURL+"/target", "expected redirect to public url", t)
+	// connection ids are random
+	id1, _ := randomID()
+	id2, _ := randomID()
+	def.AssertEqual(len(id1), relayIDSize*2, "expected 128 bit connection id", t)
+	def.AssertEqual(id1 != id2, true, "expected unique connection ids", t)
+	// tear down
+	if err := share.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-share.Done():
+		{
+			break
+		}
+	case <-time.After(5 * time.Second):
+		{
+			t.Error("expected share to stop")
+		}
+	}
+}
+
+// TestShareSSHHostKey tests that SSH host keys are verified unless explicitly disabled.
+func TestShareSSHHostKey(t *testing.T) {
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	os.Setenv("HOME", t.TempDir())
+	_, err := sshHostKeyCallback(false)
+	def.AssertEqual(errors.Is(err, ErrKnownHostsNotFound), true, "expected known_hosts not found error", t)
+	callback, err := sshHostKeyCallback(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.AssertEqual(callback != nil, true, "expected insecure host key callback", t)
 }
@@ -0,0 +1,184 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/project"
+)
+
+// ShareTransportRelay shares through a TCP relay server.
+const ShareTransportRelay = "relay"
+
+// ShareTransportSSH shares through an SSH reverse tunnel.
+const ShareTransportSSH = "ssh"
+
+// ShareTransport exposes a local address to the public.
+type ShareTransport interface {
+	// Open starts forwarding public connections to localAddr and returns the public URL.
+	Open(localAddr string) (string, error)
+	// Done is closed when the transport stops forwarding.
+	Done() <-chan struct{}
+	// Close tears down the transport.
+	Close() error
+}
+
+// Share exposes a routed host through a share transport.
+type Share struct {
+	Host      string
+	PublicURL string
+	transport ShareTransport
+	listener  net.Listener
+	server    *http.Server
+}
+
+// NewShare starts sharing given host, served by the router at routerAddr, through transport.
+func NewShare(host string, routerAddr string, transport ShareTransport) (*Share, error) {
+	s := &Share{Host: host, transport: transport}
+	var err error
+	if s.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			// rewrite host so the router and PLATFORM_ROUTES aware apps see the routed host
+			r.Header.Set("X-Forwarded-Host", r.Host)
+			r.URL.Scheme = "http"
+			r.URL.Host = routerAddr
+			r.Host = host
+		},
+		ModifyResponse: s.rewriteLocation,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			output.LogError(err)
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		},
+	}
+	s.server = &http.Server{Handler: proxy}
+	go s.server.Serve(s.listener)
+	if s.PublicURL, err = transport.Open(s.listener.Addr().String()); err != nil {
+		s.server.Close()
+		return nil, errors.WithStack(err)
+	}
+	return s, nil
+}
+
+// rewriteLocation points redirects to the shared host at the public URL.
+func (s *Share) rewriteLocation(resp *http.Response) error {
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Hostname() != s.Host {
+		return nil
+	}
+	publicURL, err := url.Parse(s.PublicURL)
+	if err != nil {
+		return nil
+	}
+	location.Scheme = publicURL.Scheme
+	location.Host = publicURL.Host
+	resp.Header.Set("Location", location.String())
+	return nil
+}
+
+// Done is closed when the share stops.
+func (s *Share) Done() <-chan struct{} {
+	return s.transport.Done()
+}
+
+// Close stops sharing.
+func (s *Share) Close() error {
+	err := s.transport.Close()
+	s.server.Close()
+	return errors.WithStack(err)
+}
+
+// ShareProject shares one of given project's hosts, the first upstream host if host is empty.
+func ShareProject(p *project.Project, host string, transport ShareTransport) (*Share, error) {
+	templateVars, err := GenerateTemplateVars(p)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	shareHost := ""
+	for _, hostVars := range templateVars {
+		if host != "" {
+			if hostVars["host"].(string) == host {
+				shareHost = host
+				break
+			}
+			continue
+		}
+		for _, route := range hostVars["routes"].([]map[string]interface{}) {
+			if route["type"] == "upstream" {
+				shareHost = hostVars["host"].(string)
+				break
+			}
+		}
+		if shareHost != "" {
+			break
+		}
+	}
+	if shareHost == "" {
+		return nil, errors.Wrapf(ErrShareHostNotFound, "host '%s' not found in project routes", host)
+	}
+	return NewShare(shareHost, fmt.Sprintf("127.0.0.1:%d", HTTPPort), transport)
+}
+
+// NewShareTransport creates a share transport of given type.
+// The secret is used by the relay transport, remotePort, publicURL and insecureHostKey by the SSH transport.
+func NewShareTransport(
+	transportType string, server string, secret string, remotePort int, publicURL string, insecureHostKey bool,
+) (ShareTransport, error) {
+	switch transportType {
+	case ShareTransportRelay:
+		{
+			return NewRelayTransport(server, secret), nil
+		}
+	case ShareTransportSSH:
+		{
+			return NewSSHTransport(server, remotePort, publicURL, insecureHostKey), nil
+		}
+	}
+	return nil, errors.Wrapf(ErrInvalidShareTransport, "transport %s", transportType)
+}
+
+// splice copies data between two connections until either side closes.
+func splice(a net.Conn, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copyConn := func(dst net.Conn, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		dst.Close()
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	wg.Wait()
+}
+
+// trimLine removes the line ending from a protocol line.
+func trimLine(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
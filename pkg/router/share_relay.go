@@ -0,0 +1,318 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+// The relay protocol uses a single control connection per client and one data
+// connection per public connection.
+//
+//   client -> relay  REGISTER <secret>
+//   relay  -> client OK <public url>
+//   relay  -> client CONNECT <id>      (for every public connection)
+//   client -> relay  ACCEPT <id>       (on a new connection, then raw data)
+
+// relayAcceptTimeout is how long a public connection waits for the client to accept it.
+const relayAcceptTimeout = 10 * time.Second
+
+// relayIDSize is the number of random bytes in a connection id.
+const relayIDSize = 16
+
+// RelayServer is a minimal TCP relay exposing a registered client on a public address.
+// Only clients that know the secret can register.
+type RelayServer struct {
+	ControlAddr string
+	PublicAddr  string
+	PublicURL   string
+	Secret      string
+	control     net.Listener
+	public      net.Listener
+	lock        sync.Mutex
+	client      net.Conn
+	pending     map[string]chan net.Conn
+}
+
+// NewRelayServer creates a relay accepting clients on controlAddr and public connections on publicAddr.
+// A random secret is generated on start if secret is empty.
+func NewRelayServer(controlAddr string, publicAddr string, publicURL string, secret string) *RelayServer {
+	return &RelayServer{
+		ControlAddr: controlAddr,
+		PublicAddr:  publicAddr,
+		PublicURL:   publicURL,
+		Secret:      secret,
+		pending:     map[string]chan net.Conn{},
+	}
+}
+
+// randomID returns a random hex encoded 128 bit id.
+func randomID() (string, error) {
+	b := make([]byte, relayIDSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Start starts listening.
+func (s *RelayServer) Start() error {
+	var err error
+	if s.Secret == "" {
+		if s.Secret, err = randomID(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if s.control, err = net.Listen("tcp", s.ControlAddr); err != nil {
+		return errors.WithStack(err)
+	}
+	if s.public, err = net.Listen("tcp", s.PublicAddr); err != nil {
+		s.control.Close()
+		return errors.WithStack(err)
+	}
+	s.ControlAddr = s.control.Addr().String()
+	s.PublicAddr = s.public.Addr().String()
+	if s.PublicURL == "" {
+		s.PublicURL = "http://" + s.PublicAddr
+	}
+	go s.acceptLoop(s.control, s.handleControl)
+	go s.acceptLoop(s.public, s.handlePublic)
+	return nil
+}
+
+// Close stops the relay.
+func (s *RelayServer) Close() error {
+	s.lock.Lock()
+	if s.client != nil {
+		s.client.Close()
+	}
+	s.lock.Unlock()
+	s.public.Close()
+	return errors.WithStack(s.control.Close())
+}
+
+// acceptLoop accepts connections until the listener closes.
+func (s *RelayServer) acceptLoop(l net.Listener, handle func(net.Conn)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go handle(conn)
+	}
+}
+
+// handleControl handles client registrations and accepted data connections.
+func (s *RelayServer) handleControl(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return
+	}
+	fields := strings.Fields(trimLine(line))
+	switch {
+	case len(fields) == 2 && fields[0] == "REGISTER":
+		{
+			if subtle.ConstantTimeCompare([]byte(fields[1]), []byte(s.Secret)) != 1 {
+				output.Warn(fmt.Sprintf("Client '%s' refused, invalid secret.", conn.RemoteAddr()))
+				fmt.Fprint(conn, "ERROR invalid secret\n")
+				conn.Close()
+				return
+			}
+			s.lock.Lock()
+			if s.client != nil {
+				s.client.Close()
+			}
+			s.client = conn
+			s.lock.Unlock()
+			output.Info(fmt.Sprintf("Client '%s' registered.", conn.RemoteAddr()))
+			fmt.Fprintf(conn, "OK %s\n", s.PublicURL)
+			// block until the client goes away
+			reader.ReadString('\n')
+			s.lock.Lock()
+			if s.client == conn {
+				s.client = nil
+			}
+			s.lock.Unlock()
+			conn.Close()
+			return
+		}
+	case len(fields) == 2 && fields[0] == "ACCEPT":
+		{
+			s.lock.Lock()
+			pending := s.pending[fields[1]]
+			delete(s.pending, fields[1])
+			s.lock.Unlock()
+			if pending == nil {
+				conn.Close()
+				return
+			}
+			pending <- &bufferedConn{Conn: conn, reader: reader}
+			return
+		}
+	}
+	conn.Close()
+}
+
+// handlePublic asks the client for a data connection and splices it with the public connection.
+func (s *RelayServer) handlePublic(conn net.Conn) {
+	id, err := randomID()
+	if err != nil {
+		output.LogError(err)
+		conn.Close()
+		return
+	}
+	s.lock.Lock()
+	client := s.client
+	if client == nil {
+		s.lock.Unlock()
+		conn.Close()
+		return
+	}
+	pending := make(chan net.Conn, 1)
+	s.pending[id] = pending
+	_, err = fmt.Fprintf(client, "CONNECT %s\n", id)
+	s.lock.Unlock()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	select {
+	case data := <-pending:
+		{
+			splice(conn, data)
+		}
+	case <-time.After(relayAcceptTimeout):
+		{
+			s.lock.Lock()
+			delete(s.pending, id)
+			s.lock.Unlock()
+			conn.Close()
+		}
+	}
+}
+
+// bufferedConn is a connection with data already read in to a buffer.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read implements net.Conn.
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// relayTransport shares through a RelayServer.
+type relayTransport struct {
+	server  string
+	secret  string
+	control net.Conn
+	done    chan struct{}
+}
+
+// NewRelayTransport creates a transport connecting to the relay at given address with the relay's secret.
+func NewRelayTransport(server string, secret string) ShareTransport {
+	return &relayTransport{server: server, secret: secret, done: make(chan struct{})}
+}
+
+// Open implements ShareTransport.
+func (t *relayTransport) Open(localAddr string) (string, error) {
+	if t.secret == "" {
+		return "", errors.WithStack(ErrShareSecretRequired)
+	}
+	var err error
+	if t.control, err = net.Dial("tcp", t.server); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintf(t.control, "REGISTER %s\n", t.secret); err != nil {
+		t.control.Close()
+		return "", errors.WithStack(err)
+	}
+	reader := bufio.NewReader(t.control)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.control.Close()
+		return "", errors.WithStack(err)
+	}
+	fields := strings.Fields(trimLine(line))
+	if len(fields) != 2 || fields[0] != "OK" {
+		t.control.Close()
+		return "", errors.Wrapf(ErrShareRelayRefused, "%s", trimLine(line))
+	}
+	go func() {
+		defer close(t.done)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(trimLine(line))
+			if len(fields) != 2 || fields[0] != "CONNECT" {
+				continue
+			}
+			go t.accept(fields[1], localAddr)
+		}
+	}()
+	return fields[1], nil
+}
+
+// accept opens a data connection for a public connection and splices it with the local address.
+func (t *relayTransport) accept(id string, localAddr string) {
+	data, err := net.Dial("tcp", t.server)
+	if err != nil {
+		output.LogError(err)
+		return
+	}
+	if _, err := fmt.Fprintf(data, "ACCEPT %s\n", id); err != nil {
+		data.Close()
+		return
+	}
+	local, err := net.Dial("tcp", localAddr)
+	if err != nil {
+		output.LogError(err)
+		data.Close()
+		return
+	}
+	splice(data, local)
+}
+
+// Done implements ShareTransport.
+func (t *relayTransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// Close implements ShareTransport.
+func (t *relayTransport) Close() error {
+	if t.control == nil {
+		return nil
+	}
+	return errors.WithStack(t.control.Close())
+}
@@ -0,0 +1,190 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/config"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTransport shares through an SSH reverse tunnel (ssh -R).
+type sshTransport struct {
+	server          string
+	remotePort      int
+	publicURL       string
+	insecureHostKey bool
+	client          *ssh.Client
+	listener        net.Listener
+	done            chan struct{}
+}
+
+// NewSSHTransport creates a transport forwarding remotePort on the SSH server ([user@]host[:port]) to the share.
+// The host key is only left unverified if insecureHostKey is set.
+func NewSSHTransport(server string, remotePort int, publicURL string, insecureHostKey bool) ShareTransport {
+	return &sshTransport{
+		server:          server,
+		remotePort:      remotePort,
+		publicURL:       publicURL,
+		insecureHostKey: insecureHostKey,
+		done:            make(chan struct{}),
+	}
+}
+
+// sshAuthMethods returns the keys of the ssh agent, the pcc key and the user's default keys.
+// The returned agent connection, if any, must be closed once authentication is done.
+func sshAuthMethods() ([]ssh.AuthMethod, net.Conn) {
+	signers := make([]ssh.Signer, 0)
+	var agentConn net.Conn
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			agentConn = conn
+			if agentSigners, err := agent.NewClient(conn).Signers(); err == nil {
+				signers = append(signers, agentSigners...)
+			}
+		}
+	}
+	keyPaths := []string{config.PrivateKeyPath()}
+	if home, err := os.UserHomeDir(); err == nil {
+		keyPaths = append(
+			keyPaths,
+			filepath.Join(home, ".ssh", "id_ed25519"),
+			filepath.Join(home, ".ssh", "id_ecdsa"),
+			filepath.Join(home, ".ssh", "id_rsa"),
+		)
+	}
+	for _, path := range keyPaths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// passphrase protected keys are only usable through the agent
+		if signer, err := ssh.ParsePrivateKey(data); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, agentConn
+}
+
+// sshHostKeyCallback verifies host keys against the user's known_hosts file, host keys are only
+// ignored if insecure is set.
+func sshHostKeyCallback(insecure bool) (ssh.HostKeyCallback, error) {
+	if insecure {
+		output.Warn("SSH host key will not be verified.")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	path := filepath.Join(home, ".ssh", "known_hosts")
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, errors.Wrapf(ErrKnownHostsNotFound, "%s", path)
+		}
+		return nil, errors.WithStack(err)
+	}
+	return callback, nil
+}
+
+// Open implements ShareTransport.
+func (t *sshTransport) Open(localAddr string) (string, error) {
+	username := ""
+	host := t.server
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		username = host[:i]
+		host = host[i+1:]
+	}
+	if username == "" {
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+	hostKeyCallback, err := sshHostKeyCallback(t.insecureHostKey)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	auth, agentConn := sshAuthMethods()
+	t.client, err = ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if agentConn != nil {
+		agentConn.Close()
+	}
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if t.listener, err = t.client.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", t.remotePort)); err != nil {
+		t.client.Close()
+		return "", errors.WithStack(err)
+	}
+	go func() {
+		defer close(t.done)
+		for {
+			conn, err := t.listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				local, err := net.Dial("tcp", localAddr)
+				if err != nil {
+					output.LogError(err)
+					conn.Close()
+					return
+				}
+				splice(conn, local)
+			}()
+		}
+	}()
+	if t.publicURL != "" {
+		return t.publicURL, nil
+	}
+	hostname, _, _ := net.SplitHostPort(host)
+	return fmt.Sprintf("http://%s:%d", hostname, t.remotePort), nil
+}
+
+// Done implements ShareTransport.
+func (t *sshTransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// Close implements ShareTransport.
+func (t *sshTransport) Close() error {
+	if t.client == nil {
+		return nil
+	}
+	t.listener.Close()
+	return errors.WithStack(t.client.Close())
+}
@@ -0,0 +1,164 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxSSIDepth is the maximum nesting of server side includes.
+const maxSSIDepth = 10
+
+// ssiInclude matches server side include directives.
+var ssiInclude = regexp.MustCompile(`<!--#\s*include\s+(?:virtual|file)="([^"]*)"\s*-->`)
+
+// ssiContextKey is the request context key holding the include depth.
+type ssiContextKey struct{}
+
+// ssiWriter buffers HTML responses so server side includes can be processed before they
+// are sent, other responses are streamed to the underlying writer.
+type ssiWriter struct {
+	w           http.ResponseWriter // nil to buffer every response
+	depth       int
+	header      http.Header
+	status      int
+	wroteHeader bool
+	buffer      bool
+	body        bytes.Buffer
+}
+
+// newSSIWriter creates a response writer for given include depth, w may be nil to buffer every response.
+func newSSIWriter(w http.ResponseWriter, depth int) *ssiWriter {
+	return &ssiWriter{w: w, depth: depth, header: http.Header{}, status: http.StatusOK}
+}
+
+// Header implements http.ResponseWriter.
+func (s *ssiWriter) Header() http.Header {
+	return s.header
+}
+
+// Write implements http.ResponseWriter.
+func (s *ssiWriter) Write(b []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+	if s.buffer {
+		return s.body.Write(b)
+	}
+	return s.w.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter, only HTML responses are buffered.
+func (s *ssiWriter) WriteHeader(status int) {
+	if s.wroteHeader {
+		return
+	}
+	s.wroteHeader = true
+	s.status = status
+	s.buffer = s.w == nil ||
+		(s.depth < maxSSIDepth && strings.HasPrefix(s.header.Get("Content-Type"), "text/html"))
+	if s.buffer {
+		return
+	}
+	for k, v := range s.header {
+		s.w.Header()[k] = v
+	}
+	s.w.WriteHeader(status)
+}
+
+// Flush implements http.Flusher for streamed responses.
+func (s *ssiWriter) Flush() {
+	if s.buffer {
+		return
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, upgraded connections bypass include processing.
+func (s *ssiWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.WithStack(ErrHijackNotSupported)
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	s.wroteHeader = true
+	s.status = http.StatusSwitchingProtocols
+	s.buffer = false
+	return conn, rw, nil
+}
+
+// serveSSI serves the request with handler and processes server side includes in HTML responses.
+func (p *Proxy) serveSSI(w http.ResponseWriter, r *http.Request, handler func(http.ResponseWriter, *http.Request)) {
+	depth, _ := r.Context().Value(ssiContextKey{}).(int)
+	sw := newSSIWriter(w, depth)
+	handler(sw, r)
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+	if !sw.buffer {
+		return
+	}
+	body := p.processSSI(r, sw.body.Bytes(), depth)
+	sw.header.Set("Content-Length", strconv.Itoa(len(body)))
+	for k, v := range sw.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(sw.status)
+	w.Write(body)
+}
+
+// processSSI replaces include directives in body with the response of a sub request.
+func (p *Proxy) processSSI(r *http.Request, body []byte, depth int) []byte {
+	return ssiInclude.ReplaceAllFunc(body, func(directive []byte) []byte {
+		path := string(ssiInclude.FindSubmatch(directive)[1])
+		ref, err := url.Parse(path)
+		if err != nil {
+			return []byte{}
+		}
+		subURL := r.URL.ResolveReference(ref)
+		subReq := r.Clone(context.WithValue(r.Context(), ssiContextKey{}, depth+1))
+		subReq.Method = http.MethodGet
+		subReq.URL = subURL
+		subReq.RequestURI = subURL.RequestURI()
+		subReq.Body = http.NoBody
+		subReq.ContentLength = 0
+		subReq.Header.Del("Accept-Encoding")
+		subReq.Header.Del("Range")
+		buf := newSSIWriter(nil, 0)
+		p.ServeHTTP(buf, subReq)
+		if buf.status >= http.StatusBadRequest {
+			return []byte{}
+		}
+		return buf.body.Bytes()
+	})
+}
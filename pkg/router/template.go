@@ -38,6 +38,10 @@ http {
         application/vnd.apple.pkpass pkpass;
     }
     default_type application/octet-stream;
+    map $upstream_cache_status $pcc_cache_status {
+        HIT HIT;
+        default MISS;
+    }
     absolute_redirect off;
     proxy_request_buffering off;
     fastcgi_request_buffering off;
@@ -59,12 +63,23 @@ http {
     fastcgi_read_timeout      86400s;
     proxy_send_timeout        86400s;
     fastcgi_send_timeout      86400s;
+    log_format pcc_json escape=json '{"time":"$time_iso8601","project_id":"$pcc_project","host":"$host",'
+        '"method":"$request_method","path":"$request_uri","status":$status,"latency":$request_time,'
+        '"request_size":$request_length,"response_size":$bytes_sent,"upstream":"$pcc_upstream",'
+        '"route_type":"$pcc_route_type","route_path":"$pcc_route_path","route":"$pcc_route",'
+        '"cache":"$upstream_cache_status"}';
+    access_log /var/log/nginx/pcc_access.log pcc_json;
     server {
         server_name default;
         listen 80 default;
         listen 443 ssl;
         ssl_certificate /var/ssl/localhost/cert.pem;
         ssl_certificate_key /var/ssl/localhost/key.pem;
+        set $pcc_project "";
+        set $pcc_route_type "";
+        set $pcc_route_path "";
+        set $pcc_route "";
+        set $pcc_upstream "";
         root /www;
         location / {
             index index.html;
@@ -75,24 +90,50 @@ http {
 `
 
 const nginxServerTemplate = `
-{{ range . }}
+{{ define "routeVars" }}
+			set $pcc_route_type "{{ .type }}";
+			set $pcc_route_path "{{ .path }}";
+			set $pcc_route "{{ with .route }}{{ .OriginalURL }}{{ end }}";
+			set $pcc_upstream "{{ .upstream }}";
+{{ end }}
+proxy_cache_path {{ .cachePath }}/{{ .pid }} levels=1:2 keys_zone={{ .cacheZone }}:10m inactive=1h max_size=256m;
+{{ range .hosts }}
 server {
     resolver 127.0.0.11;
     server_name {{ .host }};
     listen 80;
     listen 443 ssl;
-    ssl_certificate /var/ssl/localhost/cert.pem;
-    ssl_certificate_key /var/ssl/localhost/key.pem;
+    ssl_certificate /var/ssl/hosts/{{ .host }}/cert.pem;
+    ssl_certificate_key /var/ssl/hosts/{{ .host }}/key.pem;
     client_max_body_size 200M;
+    set $pcc_project "{{ $.pid }}";
+    set $pcc_route_type "";
+    set $pcc_route_path "";
+    set $pcc_route "";
+    set $pcc_upstream "";
     {{ range .routes }}
+	{{ $route := . }}
 	{{ if eq .type "upstream" }}
 	location "{{ .path }}" {
 		{{ range .redirects }}
 		location ~ "{{ .path }}" {
+			{{ template "routeVars" $route }}
 			return {{ .code }} {{ .to }};
 		}
 		{{ end }}
 		location ~* {
+			{{ template "routeVars" $route }}
+			{{ if .cache.Enabled }}
+			proxy_cache {{ $.cacheZone }};
+			proxy_cache_key "{{ cacheKey .cache }}";
+			proxy_cache_methods GET HEAD;
+			proxy_cache_valid 200 301 302 {{ .cache.DefaultTTL }}s;
+			add_header X-Platform-Cache $pcc_cache_status always;
+			{{ end }}
+			{{ if .ssi }}
+			ssi on;
+			proxy_set_header Accept-Encoding "";
+			{{ end }}
 			proxy_pass http://{{ .upstream }};
 			proxy_set_header X-Client-IP $server_addr;
 			proxy_set_header X-Forwarded-Host $host;
@@ -105,6 +146,7 @@ server {
 	}
     {{ else if eq .type "redirect" }}
     location "{{ .path }}" {
+        {{ template "routeVars" $route }}
         return 301 {{ .to }};
     }
     {{ end }}
@@ -0,0 +1,134 @@
+/*
+This file is part of Platform.CC.
+
+Platform.CC is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Platform.CC is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with Platform.CC.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package router
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// TrustBundleFormatPEM is a PEM encoded CA certificate.
+const TrustBundleFormatPEM = "pem"
+
+// TrustBundleFormatP12 is a PKCS#12 trust store containing the CA certificate.
+const TrustBundleFormatP12 = "p12"
+
+// TrustBundleFormatNSS imports the CA certificate in to the NSS databases used by Chrome and Firefox.
+const TrustBundleFormatNSS = "nss"
+
+// caNickname is the name given to the CA certificate in trust stores.
+const caNickname = "Platform.CC CA"
+
+// caCertificatePEM returns the PEM encoded CA certificate without its private key.
+func caCertificatePEM() ([]byte, error) {
+	ca, err := loadCertificateAuthority()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ca.certPEM, nil
+}
+
+// TrustBundle returns the CA certificate in the given format for import in to a trust store.
+func TrustBundle(format string, password string) ([]byte, error) {
+	ca, err := loadCertificateAuthority()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return trustBundle(ca, format, password)
+}
+
+// trustBundle returns the certificate of given CA in the given format.
+func trustBundle(ca *certificateAuthority, format string, password string) ([]byte, error) {
+	switch format {
+	case TrustBundleFormatPEM:
+		{
+			return ca.certPEM, nil
+		}
+	case TrustBundleFormatP12:
+		{
+			p12, err := pkcs12.EncodeTrustStore(rand.Reader, []*x509.Certificate{ca.cert}, password)
+			return p12, errors.WithStack(err)
+		}
+	}
+	return nil, errors.Wrapf(ErrInvalidTrustBundleFormat, "format %s", format)
+}
+
+// nssDatabases returns the NSS certificate databases of the current user.
+func nssDatabases() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return []string{}
+	}
+	out := make([]string, 0)
+	for _, pattern := range []string{
+		filepath.Join(home, ".pki", "nssdb"),
+		filepath.Join(home, ".mozilla", "firefox", "*"),
+		filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox", "*"),
+		filepath.Join(home, "Library", "Application Support", "Firefox", "Profiles", "*"),
+	} {
+		matches, _ := filepath.Glob(filepath.Join(pattern, "cert9.db"))
+		for _, match := range matches {
+			out = append(out, filepath.Dir(match))
+		}
+	}
+	return out
+}
+
+// ImportTrustBundleNSS imports the CA certificate in to all NSS databases of the current user with certutil.
+func ImportTrustBundleNSS() ([]string, error) {
+	certutil, err := exec.LookPath("certutil")
+	if err != nil {
+		return nil, errors.WithStack(ErrCertutilNotFound)
+	}
+	databases := nssDatabases()
+	if len(databases) == 0 {
+		return nil, errors.WithStack(ErrNoNSSDatabase)
+	}
+	certPEM, err := caCertificatePEM()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f, err := ioutil.TempFile("", "pcc-ca-*.pem")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(certPEM); err != nil {
+		f.Close()
+		return nil, errors.WithStack(err)
+	}
+	f.Close()
+	for _, db := range databases {
+		done := output.Duration(fmt.Sprintf("Import CA in to '%s.'", db))
+		cmd := exec.Command(certutil, "-d", "sql:"+db, "-A", "-t", "C,,", "-n", caNickname, "-i", f.Name())
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, errors.Wrapf(err, "certutil: %s", out)
+		}
+		done()
+	}
+	return databases, nil
+}